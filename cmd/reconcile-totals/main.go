@@ -0,0 +1,130 @@
+// Command reconcile-totals recomputes event vote totals from the votes table
+// and overwrites the Redis tally and the events.totals_json snapshot. Run it
+// after a Redis outage or flush; without -events every live event and every
+// event whose tally missed a vote is fixed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/config"
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/pkg/cache"
+	dbpkg "github.com/funpot/funpot-go-core/pkg/database"
+)
+
+func main() {
+	eventIDs := flag.String("events", "", "comma-separated event IDs to reconcile (default: all live and stale events)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync() //nolint:errcheck
+
+	if cfg.Database.DSN() == "" {
+		logger.Fatal("database connection parameters are required to reconcile totals")
+	}
+	db, err := dbpkg.OpenPostgres(dbpkg.PostgresSettings{
+		DSN:             cfg.Database.DSN(),
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		logger.Fatal("failed to connect to postgres", zap.Error(err))
+	}
+	defer db.Close() //nolint:errcheck
+
+	ctx := context.Background()
+	eventsService := events.NewService(nil)
+	eventsService.WithRepository(events.NewPostgresRepository(db))
+	if cfg.Redis.Enabled {
+		redisCtx, cancel := context.WithTimeout(ctx, cfg.Redis.HealthcheckPing)
+		redisClient, err := cache.OpenRedis(redisCtx, cfg.Redis)
+		cancel()
+		if err != nil {
+			logger.Fatal("failed to connect to redis", zap.Error(err))
+		}
+		defer redisClient.Close() //nolint:errcheck
+		tally, err := events.NewRedisTally(redisClient, "funpot:tally")
+		if err != nil {
+			logger.Fatal("failed to configure vote tally", zap.Error(err))
+		}
+		eventsService.WithTally(tally)
+	}
+	votesService := votes.NewService(votes.NewPostgresRepository(db), eventsService)
+
+	ids := splitIDs(*eventIDs)
+	if len(ids) == 0 {
+		live, err := eventsService.ListByState(ctx, events.StateLive)
+		if err != nil {
+			logger.Fatal("failed to list live events", zap.Error(err))
+		}
+		for _, event := range live {
+			ids = append(ids, event.ID)
+		}
+		stale, err := eventsService.ListTotalsStale(ctx)
+		if err != nil {
+			logger.Fatal("failed to list stale event totals", zap.Error(err))
+		}
+		ids = appendMissing(ids, stale)
+	}
+
+	started := time.Now()
+	failed := 0
+	for _, id := range ids {
+		totals, err := votesService.ReconcileTotals(ctx, eventsService, id)
+		if err != nil {
+			failed++
+			logger.Error("failed to reconcile event totals", zap.String("event_id", id), zap.Error(err))
+			continue
+		}
+		logger.Info("reconciled event totals", zap.String("event_id", id), zap.Any("totals", totals))
+	}
+	logger.Info("reconcile finished", zap.Int("events", len(ids)), zap.Int("failed", failed), zap.Duration("took", time.Since(started)))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func splitIDs(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// appendMissing appends the IDs of extra that ids does not contain yet.
+func appendMissing(ids, extra []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		seen[id] = struct{}{}
+	}
+	for _, id := range extra {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
@@ -17,11 +19,23 @@ import (
 	"github.com/funpot/funpot-go-core/internal/config"
 	"github.com/funpot/funpot-go-core/internal/events"
 	"github.com/funpot/funpot-go-core/internal/games"
+	"github.com/funpot/funpot-go-core/internal/idempotency"
+	"github.com/funpot/funpot-go-core/internal/llm"
+	"github.com/funpot/funpot-go-core/internal/media"
+	"github.com/funpot/funpot-go-core/internal/payments"
+	"github.com/funpot/funpot-go-core/internal/pipeline"
 	"github.com/funpot/funpot-go-core/internal/prompts"
+	"github.com/funpot/funpot-go-core/internal/realtime"
+	"github.com/funpot/funpot-go-core/internal/referrals"
+	"github.com/funpot/funpot-go-core/internal/settlement"
 	"github.com/funpot/funpot-go-core/internal/streamers"
 	"github.com/funpot/funpot-go-core/internal/users"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/internal/withdrawals"
 	"github.com/funpot/funpot-go-core/pkg/cache"
 	dbpkg "github.com/funpot/funpot-go-core/pkg/database"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
 	"github.com/funpot/funpot-go-core/pkg/telemetry"
 )
 
@@ -107,6 +121,62 @@ func main() {
 	gamesService := games.NewService()
 	promptsService := prompts.NewService()
 	eventsService := events.NewService(nil)
+	eventsService.WithDefaultCostPerVote(cfg.Events.DefaultCostPerVote)
+	memoryLedger := wallet.NewInMemoryRepository()
+	memoryVotes := votes.NewInMemoryRepository()
+	memoryVotes.WithLedger(memoryLedger)
+	var votesRepo votes.Repository = memoryVotes
+	var walletRepo wallet.Repository = memoryLedger
+	var withdrawalsRepo withdrawals.Repository = withdrawals.NewInMemoryRepository()
+	var referralsRepo referrals.Repository = referrals.NewInMemoryRepository()
+	if db != nil {
+		eventsService.WithRepository(events.NewPostgresRepository(db))
+		votesRepo = votes.NewPostgresRepository(db)
+		walletRepo = wallet.NewPostgresRepository(db)
+		withdrawalsRepo = withdrawals.NewPostgresRepository(db)
+		referralsRepo = referrals.NewPostgresRepository(db)
+	}
+	walletService := wallet.NewService(walletRepo)
+	withdrawalsService := withdrawals.NewService(withdrawalsRepo, walletService)
+	referralsService := referrals.NewService(referralsRepo, userService, walletService, cfg.Referrals.Percent)
+	referralsService.WithLinkBase(cfg.Referrals.LinkBase)
+	referralsService.WithRiskPolicy(referrals.RiskPolicy{
+		HoldScore:   cfg.Referrals.HoldScore,
+		BurstWindow: cfg.Referrals.BurstWindow,
+		BurstSize:   cfg.Referrals.BurstSize,
+	}, logger)
+	votesService := votes.NewService(votesRepo, eventsService)
+	votesService.WithWallet(votes.NewLedgerWallet(walletService))
+	eventsService.WithUserVotes(votesService)
+	var voteTally events.Tally = events.NewInMemoryTally()
+	if redisClient != nil {
+		voteTally, err = events.NewRedisTally(redisClient, "funpot:tally")
+		if err != nil {
+			logger.Fatal("failed to configure vote tally", zap.Error(err))
+		}
+	}
+	eventsService.WithTally(voteTally)
+	votesService.WithTally(voteTally, cfg.Votes.PaidRequiresTally, logger)
+	votesService.WithStaleTotals(eventsService)
+	var limiter ratelimit.Limiter = ratelimit.NewInMemoryLimiter()
+	if redisClient != nil {
+		limiter, err = ratelimit.NewRedisLimiter(redisClient, "funpot:ratelimit")
+		if err != nil {
+			logger.Fatal("failed to configure rate limiter", zap.Error(err))
+		}
+		streamersService.WithRateLimiter(limiter, logger)
+	}
+	votesService.WithRateLimiter(limiter, ratelimit.PerMinute(cfg.Client.VotePerMin))
+	withdrawalsService.WithRateLimiter(limiter, logger)
+	pipelineService := pipeline.NewService()
+	if cfg.Events.AutoEnabled {
+		streamersService.WithDecisionObserver(events.NewAutomator(eventsService, logger, events.AutomationConfig{
+			VoteWindow:      cfg.Events.AutoVoteWindow,
+			CostPerVote:     cfg.Events.AutoCostPerVote,
+			UndecidedPolicy: cfg.Events.AutoUndecidedPolicy,
+			MinConfidence:   cfg.Events.AutoMinConfidence,
+		}))
+	}
 
 	authService, err := auth.NewService(logger, cfg.Auth, userService)
 	if err != nil {
@@ -123,6 +193,154 @@ func main() {
 		authService.WithRefreshSessionStore(refreshStore)
 	}
 
+	var eventsLease events.Lease = media.NewInMemoryLocker()
+	if redisClient != nil {
+		publisher, err := realtime.NewRedisPublisher(redisClient, "funpot:realtime")
+		if err != nil {
+			logger.Fatal("failed to configure realtime publisher", zap.Error(err))
+		}
+		eventsService.WithPublisher(publisher, logger)
+		votesService.WithPublisher(publisher)
+		walletService.WithPublisher(publisher, logger)
+		eventsLease = cache.NewRedisLocker(redisClient, "funpot:lock")
+	}
+	if cfg.Events.LiveCacheTTL > 0 {
+		var liveCacheClient redis.UniversalClient
+		if redisClient != nil {
+			liveCacheClient = redisClient
+		}
+		eventsService.WithLiveCache(liveCacheClient, events.LiveCacheConfig{TTL: cfg.Events.LiveCacheTTL})
+	}
+	eventsCloser := events.NewCloser(eventsService, eventsLease, logger, events.CloserConfig{
+		Interval: cfg.Events.CloserInterval,
+		LeaseTTL: cfg.Events.CloserLeaseTTL,
+	})
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	go func() {
+		if err := eventsCloser.Run(jobsCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("event closer stopped", zap.Error(err))
+		}
+	}()
+	totalsSnapshotter := events.NewSnapshotter(eventsService, logger, cfg.Events.TotalsSnapshotInterval)
+	go func() {
+		if err := totalsSnapshotter.Run(jobsCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("vote totals snapshotter stopped", zap.Error(err))
+		}
+	}()
+
+	settler := settlement.NewSettler(eventsService, votesService, walletService, eventsLease, logger, settlement.SettlerConfig{
+		Interval: cfg.Payouts.Interval,
+		LeaseTTL: cfg.Events.CloserLeaseTTL,
+		Payouts: settlement.PayoutConfig{
+			Model:     cfg.Payouts.Model,
+			RakeBPS:   int64(cfg.Payouts.RakeBPS),
+			FixedOdds: cfg.Payouts.FixedOdds,
+		},
+	})
+	go func() {
+		if err := settler.Run(jobsCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("event settler stopped", zap.Error(err))
+		}
+	}()
+
+	if cfg.Media.Enabled {
+		llmRegistry, err := llm.NewRegistryFromConfig(&http.Client{Timeout: 60 * time.Second}, cfg.LLM.Providers)
+		if err != nil {
+			logger.Fatal("failed to configure llm providers", zap.Error(err))
+		}
+		ffmpeg := media.ExecFFmpegRunner{Binary: cfg.Media.FFmpegBinary}
+		capture := media.NewFFmpegCapture(ffmpeg, streamersService, media.CaptureConfig{
+			URLTemplate: cfg.Media.StreamURLTemplate,
+			Duration:    cfg.Media.ChunkDuration,
+			WorkDir:     cfg.Media.WorkDir,
+		})
+		llmBudget := media.NewBudget(cfg.LLM.Budget)
+		if redisClient != nil {
+			budgetCounters, err := media.NewRedisBudgetCounters(redisClient, "funpot:llm-budget")
+			if err != nil {
+				logger.Fatal("failed to configure llm budget", zap.Error(err))
+			}
+			llmBudget.WithCounters(budgetCounters, logger)
+		}
+		if err := llmBudget.RegisterMetrics(telemetryProvider.Meter("funpot/media")); err != nil {
+			logger.Fatal("failed to register llm budget metrics", zap.Error(err))
+		}
+		classifier := media.NewLLMClassifier(llmRegistry)
+		classifier.WithBudget(llmBudget)
+		mediaWorker := media.NewWorker(capture, classifier, &media.InMemoryRunStore{}, streamersService, eventsLease, media.WorkerConfig{
+			LockTTL:       cfg.Media.ChunkDuration + time.Minute,
+			MinConfidence: cfg.Media.MinConfidence,
+			GameID:        cfg.Media.GameID,
+		})
+		mediaWorker.WithPrompts(promptsService)
+		mediaWorker.WithSwitches(pipelineService)
+		mediaWorker.WithPreprocessor(media.NewPreprocessor(ffmpeg, media.PreprocessConfig{
+			Mode:          cfg.Media.PreprocessMode,
+			FrameCount:    cfg.Media.FrameCount,
+			Width:         cfg.Media.FrameWidth,
+			HashThreshold: cfg.Media.HashThreshold,
+			WorkDir:       cfg.Media.WorkDir,
+		}))
+		mediaScheduler := media.NewScheduler(mediaWorker, streamersService, logger, media.SchedulerConfig{
+			Interval: cfg.Media.Interval,
+			GameID:   cfg.Media.GameID,
+		})
+		mediaScheduler.WithSwitches(pipelineService)
+		mediaScheduler.WithSampling(llmBudget)
+		go func() {
+			if err := mediaScheduler.Run(jobsCtx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("stream analysis scheduler stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	var paymentsService *payments.Service
+	if cfg.Auth.BotToken != "" {
+		botAPI, err := payments.NewBotAPI(&http.Client{Timeout: 10 * time.Second}, cfg.Payments.TelegramAPIURL, cfg.Auth.BotToken)
+		if err != nil {
+			logger.Fatal("failed to configure telegram bot api", zap.Error(err))
+		}
+		var paymentsRepo payments.Repository = payments.NewInMemoryRepository()
+		if db != nil {
+			paymentsRepo = payments.NewPostgresRepository(db)
+		}
+		paymentsService = payments.NewService(paymentsRepo, botAPI, cfg.Client.StarsRate)
+		paymentsService.WithRateLimiter(limiter, logger)
+		paymentsService.WithLedger(walletService)
+		paymentsService.WithWebhookSecret(cfg.Payments.WebhookSecret)
+		paymentsService.WithRefunder(botAPI)
+		paymentsService.WithTopupObserver(referralsService)
+		paymentsService.WithTopupReversalObserver(referralsService)
+		referralsService.WithTopupHistory(paymentsService)
+
+		reconciler := payments.NewReconciler(paymentsService, botAPI, eventsLease, logger, payments.ReconcilerConfig{
+			Interval: cfg.Payments.ReconcileInterval,
+			Window:   cfg.Payments.ReconcileWindow,
+		})
+		go func() {
+			if err := reconciler.Run(jobsCtx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("payments reconciler stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	var workerVerifier *auth.WorkerVerifier
+	if cfg.Worker.HMACSecret != "" {
+		workerVerifier, err = auth.NewWorkerVerifier(cfg.Worker.HMACSecret)
+		if err != nil {
+			logger.Fatal("failed to configure worker signature verification", zap.Error(err))
+		}
+	}
+
+	var idempotencyStore idempotency.Store = idempotency.NewInMemoryStore()
+	if redisClient != nil {
+		idempotencyStore, err = idempotency.NewRedisStore(redisClient, "funpot:idempotency")
+		if err != nil {
+			logger.Fatal("failed to configure idempotency store", zap.Error(err))
+		}
+	}
+
 	cleanupRefreshStore, err := setupRefreshSessionStore(ctx, logger, cfg, authService)
 	if err != nil {
 		logger.Fatal("failed to configure refresh sessions", zap.Error(err))
@@ -162,6 +380,15 @@ func main() {
 		gamesService,
 		promptsService,
 		eventsService,
+		pipelineService,
+		workerVerifier,
+		idempotencyStore,
+		votesService,
+		walletService,
+		settler,
+		paymentsService,
+		withdrawalsService,
+		referralsService,
 		app.ConfigResponseFromConfig(cfg),
 	)
 
@@ -26,10 +26,14 @@ func New(cfg config.Config, logger *zap.Logger, handler http.Handler) (*App, err
 	if handler == nil {
 		return nil, errors.New("http handler is required")
 	}
+	proxies, err := ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.Server.Address,
-		Handler:      handler,
+		Handler:      WithClientIP(handler, proxies),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
@@ -1,9 +1,13 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -17,9 +21,17 @@ import (
 	"github.com/funpot/funpot-go-core/internal/config"
 	"github.com/funpot/funpot-go-core/internal/events"
 	"github.com/funpot/funpot-go-core/internal/games"
+	"github.com/funpot/funpot-go-core/internal/idempotency"
+	"github.com/funpot/funpot-go-core/internal/payments"
+	"github.com/funpot/funpot-go-core/internal/pipeline"
 	"github.com/funpot/funpot-go-core/internal/prompts"
+	"github.com/funpot/funpot-go-core/internal/referrals"
+	"github.com/funpot/funpot-go-core/internal/settlement"
 	"github.com/funpot/funpot-go-core/internal/streamers"
 	"github.com/funpot/funpot-go-core/internal/users"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/internal/withdrawals"
 )
 
 type readinessState struct {
@@ -29,6 +41,10 @@ type readinessState struct {
 
 type telegramAuthRequest struct {
 	InitData string `json:"initData"`
+	// DeviceID is an optional client-generated device identifier used for
+	// referral abuse detection. Clients can omit or change it, so only a
+	// supplied ID is recorded; the shared User-Agent does not stand in.
+	DeviceID string `json:"deviceId,omitempty"`
 }
 
 type refreshTokenRequest struct {
@@ -72,25 +88,106 @@ type gameUpsertRequest struct {
 }
 
 type promptCreateRequest struct {
-	Stage         string  `json:"stage"`
-	Template      string  `json:"template"`
-	Model         string  `json:"model"`
-	Temperature   float64 `json:"temperature"`
-	MaxTokens     int     `json:"maxTokens"`
-	TimeoutMS     int     `json:"timeoutMs"`
-	RetryCount    int     `json:"retryCount"`
-	BackoffMS     int     `json:"backoffMs"`
-	CooldownMS    int     `json:"cooldownMs"`
-	MinConfidence float64 `json:"minConfidence"`
+	Stage         string               `json:"stage"`
+	Template      string               `json:"template"`
+	Model         string               `json:"model"`
+	FallbackModel string               `json:"fallbackModel"`
+	Temperature   float64              `json:"temperature"`
+	MaxTokens     int                  `json:"maxTokens"`
+	TimeoutMS     int                  `json:"timeoutMs"`
+	RetryCount    int                  `json:"retryCount"`
+	BackoffMS     int                  `json:"backoffMs"`
+	CooldownMS    int                  `json:"cooldownMs"`
+	MinConfidence float64              `json:"minConfidence"`
+	OutputSchema  prompts.OutputSchema `json:"outputSchema"`
 }
 
 type llmDecisionRecordRequest struct {
-	RunID      string  `json:"runId"`
-	Stage      string  `json:"stage"`
-	Label      string  `json:"label"`
-	Confidence float64 `json:"confidence"`
+	RunID        string  `json:"runId"`
+	Stage        string  `json:"stage"`
+	Label        string  `json:"label"`
+	Confidence   float64 `json:"confidence"`
+	Model        string  `json:"model"`
+	ErrorCode    string  `json:"errorCode"`
+	ErrorMessage string  `json:"errorMessage"`
 }
 
+type pipelineSwitchRequest struct {
+	Scope        string   `json:"scope"`
+	TargetID     string   `json:"targetId"`
+	Paused       bool     `json:"paused"`
+	PausedStages []string `json:"pausedStages"`
+}
+
+type streamerStatusResponse struct {
+	StreamerID string                           `json:"streamerId"`
+	Stages     map[string]streamers.LLMDecision `json:"stages"`
+	Pipeline   pipeline.State                   `json:"pipeline"`
+}
+
+type eventActionRequest struct {
+	Reason   string `json:"reason"`
+	OptionID string `json:"optionId"`
+	Outcome  string `json:"outcome"`
+}
+
+type workerEventsRequest struct {
+	StreamerID string  `json:"streamerId"`
+	GameID     *string `json:"gameId"`
+	Source     struct {
+		ClipID string `json:"clipId"`
+	} `json:"source"`
+	Events []workerEventItem `json:"events"`
+}
+
+type workerEventItem struct {
+	ExternalID     string                `json:"externalId"`
+	Title          string                `json:"title"`
+	Options        []events.Option       `json:"options"`
+	ValidForSec    int                   `json:"validForSec"`
+	Confidence     float64               `json:"confidence"`
+	PromptVersions events.PromptVersions `json:"promptVersions"`
+}
+
+type voteRequest struct {
+	EventID  string `json:"eventId"`
+	OptionID string `json:"optionId"`
+	Cost     *int64 `json:"cost"`
+}
+
+type invoiceRequest struct {
+	AmountINT int64 `json:"amountINT"`
+}
+
+type paymentRefundRequest struct {
+	Reason string `json:"reason"`
+}
+
+type withdrawRequest struct {
+	AmountINT int64 `json:"amountINT"`
+}
+
+type withdrawalActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type referralPayoutActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Idempotency TTLs follow docs/idempotency_rate_limits.md.
+const (
+	workerEventsIdempotencyTTL = 24 * time.Hour
+	votesIdempotencyTTL        = 24 * time.Hour
+	invoicesIdempotencyTTL     = time.Hour
+	withdrawIdempotencyTTL     = 24 * time.Hour
+
+	// idempotencyPendingTTL bounds how long a request that never finished,
+	// e.g. because the node crashed, keeps its key in progress. Completed
+	// responses are stored for the endpoint TTL.
+	idempotencyPendingTTL = time.Minute
+)
+
 type meResponse struct {
 	users.Profile
 	IsAdmin bool `json:"isAdmin"`
@@ -108,9 +205,21 @@ func NewHandler(
 	gamesService *games.Service,
 	promptsService *prompts.Service,
 	eventsService *events.Service,
+	pipelineService *pipeline.Service,
+	workerVerifier *auth.WorkerVerifier,
+	idempotencyStore idempotency.Store,
+	votesService *votes.Service,
+	walletService *wallet.Service,
+	settler *settlement.Settler,
+	paymentsService *payments.Service,
+	withdrawalsService *withdrawals.Service,
+	referralsService *referrals.Service,
 	clientConfig ClientConfigResponse,
 ) http.Handler {
 	mux := http.NewServeMux()
+	if idempotencyStore == nil {
+		idempotencyStore = idempotency.NewInMemoryStore()
+	}
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, readinessState{Status: "ok", Time: time.Now().UTC().Format(time.RFC3339Nano)})
@@ -185,6 +294,12 @@ func NewHandler(
 				return
 			}
 
+			if referralsService != nil {
+				if err := referralsService.RecordLogin(r.Context(), resp.User.ID, clientIP(r), req.DeviceID); err != nil {
+					logger.Warn("failed to record login fingerprint", zap.String("user_id", resp.User.ID), zap.Error(err))
+				}
+			}
+
 			writeJSON(w, http.StatusOK, resp)
 		})
 
@@ -386,6 +501,20 @@ func NewHandler(
 				}
 
 				switch action {
+				case "status":
+					if r.Method != http.MethodGet {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+					state := pipeline.State{PausedStages: []string{}, Sources: []string{}}
+					if pipelineService != nil {
+						state = pipelineService.Resolve(r.Context(), streamerID, r.URL.Query().Get("gameId"))
+					}
+					writeJSON(w, http.StatusOK, streamerStatusResponse{
+						StreamerID: streamerID,
+						Stages:     streamersService.LatestDecisionsByStage(r.Context(), streamerID),
+						Pipeline:   state,
+					})
 				case "llm-decisions":
 					switch r.Method {
 					case http.MethodGet:
@@ -416,11 +545,14 @@ func NewHandler(
 							return
 						}
 						item, err := streamersService.RecordLLMDecision(r.Context(), streamers.RecordDecisionRequest{
-							RunID:      req.RunID,
-							StreamerID: streamerID,
-							Stage:      req.Stage,
-							Label:      req.Label,
-							Confidence: req.Confidence,
+							RunID:        req.RunID,
+							StreamerID:   streamerID,
+							Stage:        req.Stage,
+							Label:        req.Label,
+							Confidence:   req.Confidence,
+							Model:        req.Model,
+							ErrorCode:    req.ErrorCode,
+							ErrorMessage: req.ErrorMessage,
 						})
 						if err != nil {
 							writeError(w, http.StatusBadRequest, err.Error())
@@ -579,6 +711,7 @@ func NewHandler(
 						Stage:         req.Stage,
 						Template:      req.Template,
 						Model:         req.Model,
+						FallbackModel: req.FallbackModel,
 						Temperature:   req.Temperature,
 						MaxTokens:     req.MaxTokens,
 						TimeoutMS:     req.TimeoutMS,
@@ -586,6 +719,7 @@ func NewHandler(
 						BackoffMS:     req.BackoffMS,
 						CooldownMS:    req.CooldownMS,
 						MinConfidence: req.MinConfidence,
+						OutputSchema:  req.OutputSchema,
 						ActorID:       claims.Subject,
 					})
 					if err != nil {
@@ -599,7 +733,9 @@ func NewHandler(
 							errors.Is(err, prompts.ErrInvalidRetryCount),
 							errors.Is(err, prompts.ErrInvalidBackoffMS),
 							errors.Is(err, prompts.ErrInvalidCooldownMS),
-							errors.Is(err, prompts.ErrInvalidMinConfidence):
+							errors.Is(err, prompts.ErrInvalidMinConfidence),
+							errors.Is(err, prompts.ErrInvalidOutputSchema),
+							errors.Is(err, prompts.ErrInvalidFallbackModel):
 							writeError(w, http.StatusBadRequest, err.Error())
 						default:
 							logger.Error("failed to create prompt", zap.Error(err))
@@ -655,6 +791,548 @@ func NewHandler(
 			})))
 		}
 
+		if votesService != nil {
+			mux.Handle("/api/votes", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+				if key == "" {
+					writeError(w, http.StatusBadRequest, "Idempotency-Key header is required")
+					return
+				}
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				var req voteRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					writeError(w, http.StatusBadRequest, "invalid request body")
+					return
+				}
+
+				serveIdempotent(w, r, idempotencyStore, "votes:"+claims.Subject+":"+key, body, votesIdempotencyTTL, func() (int, any) {
+					result, err := votesService.Cast(r.Context(), votes.CastRequest{
+						UserID:         claims.Subject,
+						EventID:        req.EventID,
+						OptionID:       req.OptionID,
+						Cost:           req.Cost,
+						IdempotencyKey: key,
+					})
+					if err != nil {
+						switch {
+						case errors.Is(err, events.ErrNotFound):
+							return http.StatusNotFound, errorBody(err.Error())
+						case errors.Is(err, votes.ErrEventIDRequired), errors.Is(err, votes.ErrOptionIDRequired), errors.Is(err, events.ErrUnknownOption):
+							return http.StatusBadRequest, errorBody(err.Error())
+						case errors.Is(err, votes.ErrEventNotLive), errors.Is(err, votes.ErrAlreadyVoted), errors.Is(err, votes.ErrCostMismatch):
+							return http.StatusConflict, errorBody(err.Error())
+						case errors.Is(err, votes.ErrRateLimited):
+							var limited *votes.RateLimitError
+							if errors.As(err, &limited) {
+								w.Header().Set("Retry-After", retryAfterSeconds(limited.RetryAfter))
+							}
+							return http.StatusTooManyRequests, errorBody(err.Error())
+						case errors.Is(err, votes.ErrInsufficientFunds):
+							return http.StatusPaymentRequired, errorBody(err.Error())
+						case errors.Is(err, votes.ErrWalletUnavailable), errors.Is(err, votes.ErrPaidVotingDisabled):
+							return http.StatusServiceUnavailable, errorBody(err.Error())
+						default:
+							logger.Error("failed to cast vote", zap.String("event_id", req.EventID), zap.Error(err))
+							return http.StatusInternalServerError, errorBody("failed to cast vote")
+						}
+					}
+					return http.StatusOK, result
+				})
+			})))
+		}
+
+		if walletService != nil {
+			mux.Handle("/api/wallet", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				page, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("page")))
+				if (err != nil && r.URL.Query().Get("page") != "") || page < 0 {
+					writeError(w, http.StatusBadRequest, "page must be a positive integer")
+					return
+				}
+				limit, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("limit")))
+				if (err != nil && r.URL.Query().Get("limit") != "") || limit < 0 {
+					writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+					return
+				}
+				item, err := walletService.Get(r.Context(), claims.Subject, page, limit)
+				if err != nil {
+					logger.Error("failed to load wallet", zap.String("user_id", claims.Subject), zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to load wallet")
+					return
+				}
+				writeJSON(w, http.StatusOK, item)
+			})))
+		}
+
+		if withdrawalsService != nil {
+			mux.Handle("/api/wallet/withdraw", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+				if key == "" {
+					writeError(w, http.StatusBadRequest, "Idempotency-Key header is required")
+					return
+				}
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				var req withdrawRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					writeError(w, http.StatusBadRequest, "invalid request body")
+					return
+				}
+
+				serveIdempotent(w, r, idempotencyStore, "withdraw:"+claims.Subject+":"+key, body, withdrawIdempotencyTTL, func() (int, any) {
+					withdrawal, err := withdrawalsService.Request(r.Context(), withdrawals.Request{
+						UserID:         claims.Subject,
+						AmountINT:      req.AmountINT,
+						IdempotencyKey: key,
+					})
+					if err != nil {
+						switch {
+						case errors.Is(err, withdrawals.ErrInvalidAmount):
+							return http.StatusBadRequest, errorBody(err.Error())
+						case errors.Is(err, withdrawals.ErrInsufficientFunds):
+							return http.StatusPaymentRequired, errorBody(err.Error())
+						case errors.Is(err, withdrawals.ErrIdempotencyConflict):
+							return http.StatusUnprocessableEntity, errorBody(err.Error())
+						case errors.Is(err, withdrawals.ErrRateLimited):
+							var limited *withdrawals.RateLimitError
+							if errors.As(err, &limited) {
+								w.Header().Set("Retry-After", retryAfterSeconds(limited.RetryAfter))
+							}
+							return http.StatusTooManyRequests, errorBody(err.Error())
+						default:
+							logger.Error("failed to request withdrawal", zap.String("user_id", claims.Subject), zap.Error(err))
+							return http.StatusInternalServerError, errorBody("failed to request withdrawal")
+						}
+					}
+					return http.StatusOK, withdrawal
+				})
+			})))
+
+			mux.Handle("/api/admin/withdrawals", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				status := strings.TrimSpace(r.URL.Query().Get("status"))
+				if status != "" && !withdrawals.IsValidStatus(status) {
+					writeError(w, http.StatusBadRequest, "unknown status")
+					return
+				}
+				items, err := withdrawalsService.List(r.Context(), status)
+				if err != nil {
+					logger.Error("failed to list withdrawals", zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to list withdrawals")
+					return
+				}
+				writeJSON(w, http.StatusOK, items)
+			})))
+
+			mux.Handle("/api/admin/withdrawals/", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/withdrawals/"), "/"), "/")
+				if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+					writeError(w, http.StatusNotFound, "withdrawal route not found")
+					return
+				}
+				withdrawalID, action := parts[0], parts[1]
+
+				var req withdrawalActionRequest
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				if len(bytes.TrimSpace(body)) > 0 {
+					if err := json.Unmarshal(body, &req); err != nil {
+						writeError(w, http.StatusBadRequest, "invalid request body")
+						return
+					}
+				}
+
+				var updated withdrawals.Withdrawal
+				switch action {
+				case "approve":
+					updated, err = withdrawalsService.Approve(r.Context(), withdrawalID, claims.Subject)
+				case "reject":
+					updated, err = withdrawalsService.Reject(r.Context(), withdrawalID, claims.Subject, req.Reason)
+				case "paid":
+					updated, err = withdrawalsService.MarkPaid(r.Context(), withdrawalID, claims.Subject)
+				default:
+					writeError(w, http.StatusNotFound, "withdrawal route not found")
+					return
+				}
+				if err != nil {
+					switch {
+					case errors.Is(err, withdrawals.ErrNotFound):
+						writeError(w, http.StatusNotFound, err.Error())
+					case errors.Is(err, withdrawals.ErrInvalidTransition):
+						writeError(w, http.StatusConflict, err.Error())
+					case errors.Is(err, withdrawals.ErrReasonRequired):
+						writeError(w, http.StatusBadRequest, err.Error())
+					default:
+						logger.Error("failed to update withdrawal", zap.String("withdrawal_id", withdrawalID), zap.Error(err))
+						writeError(w, http.StatusInternalServerError, "failed to update withdrawal")
+					}
+					return
+				}
+				writeJSON(w, http.StatusOK, updated)
+			})))
+		}
+
+		if referralsService != nil {
+			mux.Handle("/api/referrals/summary", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				summary, err := referralsService.Summary(r.Context(), claims.Subject)
+				if err != nil {
+					if errors.Is(err, users.ErrNotFound) {
+						writeError(w, http.StatusNotFound, "user not found")
+						return
+					}
+					logger.Error("failed to load referral summary", zap.String("user_id", claims.Subject), zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to load referral summary")
+					return
+				}
+				writeJSON(w, http.StatusOK, summary)
+			})))
+
+			mux.Handle("/api/referrals/payouts", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				payouts, err := referralsService.Payouts(r.Context(), claims.Subject)
+				if err != nil {
+					logger.Error("failed to load referral payouts", zap.String("user_id", claims.Subject), zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to load referral payouts")
+					return
+				}
+				writeJSON(w, http.StatusOK, payouts)
+			})))
+
+			mux.Handle("/api/admin/referrals/payouts", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				status := strings.TrimSpace(r.URL.Query().Get("status"))
+				if status != "" && !referrals.IsValidStatus(status) {
+					writeError(w, http.StatusBadRequest, "unknown status")
+					return
+				}
+				items, err := referralsService.List(r.Context(), status)
+				if err != nil {
+					logger.Error("failed to list referral payouts", zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to list referral payouts")
+					return
+				}
+				writeJSON(w, http.StatusOK, items)
+			})))
+
+			mux.Handle("/api/admin/referrals/payouts/", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/referrals/payouts/"), "/"), "/")
+				if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+					writeError(w, http.StatusNotFound, "referral payout route not found")
+					return
+				}
+				payoutID, action := parts[0], parts[1]
+
+				var req referralPayoutActionRequest
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				if len(bytes.TrimSpace(body)) > 0 {
+					if err := json.Unmarshal(body, &req); err != nil {
+						writeError(w, http.StatusBadRequest, "invalid request body")
+						return
+					}
+				}
+
+				var updated referrals.Payout
+				switch action {
+				case "approve":
+					updated, err = referralsService.Approve(r.Context(), payoutID, claims.Subject)
+				case "reject":
+					updated, err = referralsService.Reject(r.Context(), payoutID, claims.Subject, req.Reason)
+				default:
+					writeError(w, http.StatusNotFound, "referral payout route not found")
+					return
+				}
+				if err != nil {
+					switch {
+					case errors.Is(err, referrals.ErrNotFound):
+						writeError(w, http.StatusNotFound, err.Error())
+					case errors.Is(err, referrals.ErrInvalidTransition):
+						writeError(w, http.StatusConflict, err.Error())
+					case errors.Is(err, referrals.ErrReasonRequired):
+						writeError(w, http.StatusBadRequest, err.Error())
+					default:
+						logger.Error("failed to review referral payout", zap.String("payout_id", payoutID), zap.Error(err))
+						writeError(w, http.StatusInternalServerError, "failed to review referral payout")
+					}
+					return
+				}
+				writeJSON(w, http.StatusOK, updated)
+			})))
+		}
+
+		if paymentsService != nil {
+			mux.Handle("/api/payments/stars/createInvoice", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				var req invoiceRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					writeError(w, http.StatusBadRequest, "invalid request body")
+					return
+				}
+
+				key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+				create := func() (int, any) {
+					invoice, err := paymentsService.CreateInvoice(r.Context(), payments.InvoiceRequest{
+						UserID:         claims.Subject,
+						AmountINT:      req.AmountINT,
+						IdempotencyKey: key,
+					})
+					if err != nil {
+						switch {
+						case errors.Is(err, payments.ErrInvalidAmount):
+							return http.StatusBadRequest, errorBody(err.Error())
+						case errors.Is(err, payments.ErrIdempotencyConflict):
+							return http.StatusUnprocessableEntity, errorBody(err.Error())
+						case errors.Is(err, payments.ErrRateLimited):
+							var limited *payments.RateLimitError
+							if errors.As(err, &limited) {
+								w.Header().Set("Retry-After", retryAfterSeconds(limited.RetryAfter))
+							}
+							return http.StatusTooManyRequests, errorBody(err.Error())
+						case errors.Is(err, payments.ErrInvoiceUnavailable):
+							logger.Warn("failed to create stars invoice", zap.String("user_id", claims.Subject), zap.Error(err))
+							return http.StatusBadGateway, errorBody(payments.ErrInvoiceUnavailable.Error())
+						default:
+							logger.Error("failed to create stars invoice", zap.String("user_id", claims.Subject), zap.Error(err))
+							return http.StatusInternalServerError, errorBody("failed to create invoice")
+						}
+					}
+					return http.StatusOK, invoice
+				}
+				if key == "" {
+					status, payload := create()
+					writeJSON(w, status, payload)
+					return
+				}
+				serveIdempotent(w, r, idempotencyStore, "invoices:"+claims.Subject+":"+key, body, invoicesIdempotencyTTL, create)
+			})))
+
+			mux.Handle("/api/admin/payments/flagged", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				items, err := paymentsService.ListFlagged(r.Context())
+				if err != nil {
+					logger.Error("failed to list flagged payments", zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to list flagged payments")
+					return
+				}
+				writeJSON(w, http.StatusOK, items)
+			})))
+
+			mux.Handle("/api/admin/payments/", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/payments/"), "/"), "/")
+				if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || parts[1] != "refund" {
+					writeError(w, http.StatusNotFound, "payment route not found")
+					return
+				}
+				invoiceID := parts[0]
+
+				var req paymentRefundRequest
+				defer r.Body.Close() //nolint:errcheck
+				if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+					writeError(w, http.StatusBadRequest, "invalid request body")
+					return
+				}
+				payment, err := paymentsService.Refund(r.Context(), invoiceID, req.Reason)
+				if err != nil {
+					switch {
+					case errors.Is(err, payments.ErrReasonRequired):
+						writeError(w, http.StatusBadRequest, err.Error())
+					case errors.Is(err, payments.ErrNotFound):
+						writeError(w, http.StatusNotFound, err.Error())
+					case errors.Is(err, payments.ErrNotRefundable), errors.Is(err, payments.ErrPayerUnknown):
+						writeError(w, http.StatusConflict, err.Error())
+					case errors.Is(err, payments.ErrRefundUnavailable):
+						logger.Warn("failed to refund stars payment", zap.String("invoice_id", invoiceID), zap.Error(err))
+						writeError(w, http.StatusBadGateway, payments.ErrRefundUnavailable.Error())
+					default:
+						logger.Error("failed to refund stars payment", zap.String("invoice_id", invoiceID), zap.Error(err))
+						writeError(w, http.StatusInternalServerError, "failed to refund payment")
+					}
+					return
+				}
+				writeJSON(w, http.StatusOK, payment)
+			})))
+		}
+
+		if pipelineService != nil {
+			mux.Handle("/api/admin/pipeline/switches", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := auth.ClaimsFromContext(r.Context())
+				if !ok {
+					writeError(w, http.StatusUnauthorized, "missing auth claims")
+					return
+				}
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+
+				switch r.Method {
+				case http.MethodGet:
+					writeJSON(w, http.StatusOK, pipelineService.List(r.Context()))
+				case http.MethodPut:
+					defer r.Body.Close() //nolint:errcheck
+					body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+					if err != nil {
+						writeError(w, http.StatusBadRequest, "failed to read request body")
+						return
+					}
+					var req pipelineSwitchRequest
+					if err := json.Unmarshal(body, &req); err != nil {
+						writeError(w, http.StatusBadRequest, "invalid request body")
+						return
+					}
+					item, err := pipelineService.Set(r.Context(), pipeline.SetRequest{
+						Scope:        req.Scope,
+						TargetID:     req.TargetID,
+						Paused:       req.Paused,
+						PausedStages: req.PausedStages,
+						ActorID:      claims.Subject,
+					})
+					if err != nil {
+						switch {
+						case errors.Is(err, pipeline.ErrInvalidScope), errors.Is(err, pipeline.ErrTargetRequired), errors.Is(err, pipeline.ErrInvalidStage):
+							writeError(w, http.StatusBadRequest, err.Error())
+						default:
+							logger.Error("failed to update pipeline switch", zap.Error(err))
+							writeError(w, http.StatusInternalServerError, "failed to update pipeline switch")
+						}
+						return
+					}
+					writeJSON(w, http.StatusOK, item)
+				default:
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				}
+			})))
+		}
+
 		if eventsService != nil {
 			mux.Handle("/api/events/live", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				if r.Method != http.MethodGet {
@@ -666,14 +1344,350 @@ func NewHandler(
 					writeError(w, http.StatusBadRequest, "streamerId is required")
 					return
 				}
-				writeJSON(w, http.StatusOK, eventsService.ListLiveByStreamer(r.Context(), streamerID))
+				var userID string
+				if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+					userID = claims.Subject
+				}
+				items, err := eventsService.ListLiveByStreamer(r.Context(), streamerID, userID)
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, "failed to load events")
+					return
+				}
+				writeJSON(w, http.StatusOK, items)
+			})))
+
+			mux.Handle("/api/admin/events/settlement-flags", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodGet {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				items, err := eventsService.ListSettlementFlagged(r.Context())
+				if err != nil {
+					logger.Error("failed to list flagged settlements", zap.Error(err))
+					writeError(w, http.StatusInternalServerError, "failed to list flagged settlements")
+					return
+				}
+				writeJSON(w, http.StatusOK, items)
+			})))
+
+			mux.Handle("/api/admin/events/", authed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !requireAdmin(w, r, adminService) {
+					writeError(w, http.StatusForbidden, "admin role is required")
+					return
+				}
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+
+				parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/events/"), "/"), "/")
+				if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+					writeError(w, http.StatusNotFound, "event route not found")
+					return
+				}
+				eventID, action := parts[0], parts[1]
+
+				var req eventActionRequest
+				defer r.Body.Close() //nolint:errcheck
+				body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				if len(bytes.TrimSpace(body)) > 0 {
+					if err := json.Unmarshal(body, &req); err != nil {
+						writeError(w, http.StatusBadRequest, "invalid request body")
+						return
+					}
+				}
+
+				var updated events.LiveEvent
+				switch action {
+				case "close":
+					updated, err = eventsService.Close(r.Context(), eventID)
+				case "cancel":
+					updated, err = eventsService.Cancel(r.Context(), eventID, req.Reason)
+				case "refund":
+					if settler == nil {
+						writeError(w, http.StatusServiceUnavailable, "refunds are not available")
+						return
+					}
+					if strings.TrimSpace(req.Reason) == "" {
+						writeError(w, http.StatusBadRequest, "reason is required")
+						return
+					}
+					updated, err = settler.Refund(r.Context(), eventID, req.Reason)
+				case "settle":
+					if settler == nil {
+						writeError(w, http.StatusServiceUnavailable, "settlement is not available")
+						return
+					}
+					updated, err = settler.Retry(r.Context(), eventID)
+				case "result":
+					if strings.TrimSpace(req.OptionID) == "" {
+						writeError(w, http.StatusBadRequest, "optionId is required")
+						return
+					}
+					outcome := req.Outcome
+					if outcome == "" {
+						outcome = "manual"
+					}
+					updated, err = eventsService.RecordResult(r.Context(), eventID, events.Result{OptionID: req.OptionID, Outcome: outcome, Confidence: 1})
+				default:
+					writeError(w, http.StatusNotFound, "event route not found")
+					return
+				}
+				if err != nil {
+					switch {
+					case errors.Is(err, events.ErrNotFound):
+						writeError(w, http.StatusNotFound, err.Error())
+					case errors.Is(err, events.ErrInvalidTransition), errors.Is(err, events.ErrResultFinal), errors.Is(err, wallet.ErrIdempotencyConflict):
+						writeError(w, http.StatusConflict, err.Error())
+					case errors.Is(err, events.ErrUnknownOption):
+						writeError(w, http.StatusBadRequest, err.Error())
+					default:
+						logger.Error("failed to update event", zap.String("event_id", eventID), zap.Error(err))
+						writeError(w, http.StatusInternalServerError, "failed to update event")
+					}
+					return
+				}
+				writeJSON(w, http.StatusOK, updated)
 			})))
 		}
 	}
 
+	if paymentsService != nil {
+		mux.HandleFunc("/integrations/telegram/payments", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			defer r.Body.Close() //nolint:errcheck
+
+			if err := paymentsService.VerifyWebhook(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")); err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			var update payments.Update
+			if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&update); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+
+			answer, err := paymentsService.HandleUpdate(r.Context(), update)
+			switch {
+			case err == nil:
+			case errors.Is(err, payments.ErrNotFound), errors.Is(err, payments.ErrPaymentMismatch):
+				// Retrying cannot fix these; acknowledge so Telegram stops
+				// redelivering and leave the payment to manual review.
+				logger.Error("unprocessable telegram payment", zap.Int64("update_id", update.UpdateID), zap.Error(err))
+			default:
+				logger.Error("failed to process telegram payment", zap.Int64("update_id", update.UpdateID), zap.Error(err))
+				writeError(w, http.StatusInternalServerError, "failed to process update")
+				return
+			}
+			if answer != nil {
+				writeJSON(w, http.StatusOK, answer)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	if workerVerifier != nil && eventsService != nil {
+		mux.HandleFunc("/internal/worker/events", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			defer r.Body.Close() //nolint:errcheck
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			if err := workerVerifier.Verify(body, r.Header.Get("X-Worker-Signature")); err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			key := strings.TrimSpace(r.Header.Get("X-Idempotency-Key"))
+			if key == "" {
+				writeError(w, http.StatusBadRequest, "X-Idempotency-Key header is required")
+				return
+			}
+			var req workerEventsRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+
+			serveIdempotent(w, r, idempotencyStore, "worker-events:"+key, body, workerEventsIdempotencyTTL, func() (int, any) {
+				items := make([]events.IngestItem, 0, len(req.Events))
+				for _, item := range req.Events {
+					items = append(items, events.IngestItem{
+						ExternalID:     item.ExternalID,
+						Title:          item.Title,
+						Options:        item.Options,
+						ValidForSec:    item.ValidForSec,
+						Confidence:     item.Confidence,
+						PromptVersions: item.PromptVersions,
+					})
+				}
+				result, err := eventsService.Ingest(r.Context(), events.IngestRequest{
+					StreamerID:   req.StreamerID,
+					GameID:       req.GameID,
+					SourceClipID: req.Source.ClipID,
+					Events:       items,
+				})
+				if err != nil {
+					return http.StatusBadRequest, errorBody(err.Error())
+				}
+				return http.StatusOK, result
+			})
+		})
+	}
+
 	return mux
 }
 
+// serveIdempotent replays the stored response of a repeated idempotency key or
+// runs handle and stores its response. Only 2xx responses are stored so failed
+// requests can be retried with the same key.
+func serveIdempotent(w http.ResponseWriter, r *http.Request, store idempotency.Store, key string, body []byte, ttl time.Duration, handle func() (int, any)) {
+	fingerprint := idempotency.Fingerprint(body)
+	stored, err := store.Begin(r.Context(), key, fingerprint, min(idempotencyPendingTTL, ttl))
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, idempotency.ErrFingerprintMismatch):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, "idempotency store unavailable")
+		return
+	case stored != nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		_, _ = w.Write(stored.Body)
+		return
+	}
+
+	status, payload := handle()
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		_ = store.Release(r.Context(), key)
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	if status >= 200 && status < 300 {
+		_ = store.Complete(r.Context(), key, idempotency.Response{Fingerprint: fingerprint, Status: status, Body: encoded}, ttl)
+	} else {
+		_ = store.Release(r.Context(), key)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(encoded)
+}
+
+// retryAfterSeconds formats a Retry-After header value, rounding up to whole
+// seconds so clients never retry early.
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+type clientIPKey struct{}
+
+// TrustedProxies holds the networks of proxies whose X-Forwarded-For hops
+// are believed.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies accepts IPs and CIDRs.
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			proxies = append(proxies, network)
+			continue
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("trusted proxy %q is not an IP or CIDR", value)
+		}
+		bits := 8 * len(ip.To16())
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return proxies, nil
+}
+
+func (p TrustedProxies) contains(raw string) bool {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithClientIP resolves the client address of each request. The
+// X-Forwarded-For chain is only read when the connection comes from a
+// trusted proxy, and then from the right: the first hop not added by a
+// trusted proxy is the client, since anything left of it is client-supplied.
+func WithClientIP(next http.Handler, proxies TrustedProxies) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		if proxies.contains(ip) {
+			hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := strings.TrimSpace(hops[i])
+				if hop == "" {
+					continue
+				}
+				ip = hop
+				if !proxies.contains(hop) {
+					break
+				}
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// clientIP returns the address resolved by WithClientIP, or the connection
+// address when the handler is served without it.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func requireAdmin(w http.ResponseWriter, r *http.Request, adminService *admin.Service) bool {
 	claims, ok := auth.ClaimsFromContext(r.Context())
 	if !ok {
@@ -698,8 +1712,12 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 }
 
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]any{
+	writeJSON(w, status, errorBody(message))
+}
+
+func errorBody(message string) map[string]any {
+	return map[string]any{
 		"error":     message,
 		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
-	})
+	}
 }
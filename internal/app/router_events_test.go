@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/admin"
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/media"
+	"github.com/funpot/funpot-go-core/internal/settlement"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func TestAdminEventActions(t *testing.T) {
+	eventsService := events.NewService(nil)
+	created, err := eventsService.Create(context.Background(), events.CreateRequest{
+		StreamerID: "str-1",
+		Title:      "Ace this round?",
+		Options:    []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	votesService := votes.NewService(votes.NewInMemoryRepository(), eventsService)
+	settler := settlement.NewSettler(eventsService, votesService, wallet.NewService(wallet.NewInMemoryRepository()), media.NewInMemoryLocker(), nil, settlement.SettlerConfig{
+		Payouts: settlement.PayoutConfig{Model: settlement.ModelParimutuel},
+	})
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), admin.NewService([]string{"admin-1"}), nil, nil, nil, nil, eventsService, nil, nil, nil, votesService, nil, settler, nil, nil, nil, ClientConfigResponse{})
+
+	call := func(userID, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, userID))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := call("user-1", "/api/admin/events/"+created.ID+"/close", ""); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/close", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on close, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/close", ""); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on repeated close, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/result", `{"optionId":"maybe"}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown option, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/result", `{"optionId":"yes"}`); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on result, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/cancel", `{"reason":"stream ended"}`); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 cancelling a settled event, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/refund", ""); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 refunding without a reason, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/refund", `{"reason":"stream ended"}`); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 refunding an event with a final result, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/events/missing/cancel", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing event, got %d", res.Code)
+	}
+
+	if err := eventsService.FlagSettlement(context.Background(), created.ID, "payout key conflict"); err != nil {
+		t.Fatalf("FlagSettlement() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/events/settlement-flags", nil)
+	req.Header.Set("Authorization", "Bearer "+buildToken(t, "admin-1"))
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || !bytes.Contains(res.Body.Bytes(), []byte(`"settlementError":"payout key conflict"`)) {
+		t.Fatalf("expected flagged event in the admin list, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call("admin-1", "/api/admin/events/"+created.ID+"/settle", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on settle, got %d: %s", res.Code, res.Body.String())
+	}
+	if flagged, _ := eventsService.ListSettlementFlagged(context.Background()); len(flagged) != 0 {
+		t.Fatalf("expected settle to clear the flag, got %+v", flagged)
+	}
+}
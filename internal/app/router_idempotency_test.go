@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/idempotency"
+)
+
+type ttlRecordingStore struct {
+	idempotency.Store
+	beginTTL    time.Duration
+	completeTTL time.Duration
+}
+
+func (s *ttlRecordingStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*idempotency.Response, error) {
+	s.beginTTL = ttl
+	return s.Store.Begin(ctx, key, fingerprint, ttl)
+}
+
+func (s *ttlRecordingStore) Complete(ctx context.Context, key string, resp idempotency.Response, ttl time.Duration) error {
+	s.completeTTL = ttl
+	return s.Store.Complete(ctx, key, resp, ttl)
+}
+
+func TestServeIdempotentKeepsPendingMarkerShort(t *testing.T) {
+	store := &ttlRecordingStore{Store: idempotency.NewInMemoryStore()}
+	req := httptest.NewRequest(http.MethodPost, "/api/votes", nil)
+	res := httptest.NewRecorder()
+
+	serveIdempotent(res, req, store, "vote:key-1", []byte(`{}`), votesIdempotencyTTL, func() (int, any) {
+		return http.StatusOK, map[string]string{"status": "ok"}
+	})
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	if store.beginTTL != idempotencyPendingTTL {
+		t.Fatalf("expected pending marker TTL %s, got %s", idempotencyPendingTTL, store.beginTTL)
+	}
+	if store.completeTTL != votesIdempotencyTTL {
+		t.Fatalf("expected stored response TTL %s, got %s", votesIdempotencyTTL, store.completeTTL)
+	}
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/admin"
+	"github.com/funpot/funpot-go-core/internal/payments"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func TestCreateStarsInvoice(t *testing.T) {
+	botAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true,"result":"https://t.me/$invoice"}`))
+	}))
+	defer botAPI.Close()
+	linker, err := payments.NewBotAPI(botAPI.Client(), botAPI.URL, "test-token")
+	if err != nil {
+		t.Fatalf("NewBotAPI() error = %v", err)
+	}
+	paymentsService := payments.NewService(payments.NewInMemoryRepository(), linker, 2)
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, paymentsService, nil, nil, ClientConfigResponse{})
+
+	call := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/payments/stars/createInvoice", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := call("", `{"amountINT":0}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a zero amount, got %d", res.Code)
+	}
+	first := call("inv-1", `{"amountINT":5}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+	var invoice payments.Invoice
+	if err := json.Unmarshal(first.Body.Bytes(), &invoice); err != nil {
+		t.Fatalf("decode invoice: %v", err)
+	}
+	if invoice.Stars != 3 || invoice.InvoiceLink != "https://t.me/$invoice" || invoice.TgInvoicePayload != invoice.InvoiceID {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+	if retry := call("inv-1", `{"amountINT":5}`); retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Fatalf("expected the same invoice on retry, got %d %q", retry.Code, retry.Body.String())
+	}
+	if res := call("inv-1", `{"amountINT":9}`); res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a reused key, got %d", res.Code)
+	}
+
+	call("", `{"amountINT":5}`)
+	call("", `{"amountINT":5}`)
+	res := call("", `{"amountINT":5}`)
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", res.Code, res.Header())
+	}
+}
+
+func TestTelegramPaymentsWebhook(t *testing.T) {
+	ctx := context.Background()
+	botAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true,"result":"https://t.me/$invoice"}`))
+	}))
+	defer botAPI.Close()
+	linker, err := payments.NewBotAPI(botAPI.Client(), botAPI.URL, "test-token")
+	if err != nil {
+		t.Fatalf("NewBotAPI() error = %v", err)
+	}
+	walletService := wallet.NewService(wallet.NewInMemoryRepository())
+	paymentsService := payments.NewService(payments.NewInMemoryRepository(), linker, 1)
+	paymentsService.WithLedger(walletService)
+	paymentsService.WithWebhookSecret("hook-secret")
+	invoice, err := paymentsService.CreateInvoice(ctx, payments.InvoiceRequest{UserID: "user-1", AmountINT: 50})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, walletService, nil, paymentsService, nil, nil, ClientConfigResponse{})
+
+	call := func(secret, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/integrations/telegram/payments", bytes.NewBufferString(body))
+		if secret != "" {
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	preCheckout := `{"update_id":1,"pre_checkout_query":{"id":"q-1","from":{"id":42},"currency":"XTR","total_amount":50,"invoice_payload":"` + invoice.TgInvoicePayload + `"}}`
+	if res := call("wrong", preCheckout); res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad secret, got %d", res.Code)
+	}
+	res := call("hook-secret", preCheckout)
+	var answer payments.PreCheckoutAnswer
+	if res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &answer) != nil || !answer.OK || answer.Method != "answerPreCheckoutQuery" || answer.QueryID != "q-1" {
+		t.Fatalf("expected approved pre-checkout, got %d %q", res.Code, res.Body.String())
+	}
+
+	paid := `{"update_id":2,"message":{"message_id":9,"from":{"id":42},"successful_payment":{"currency":"XTR","total_amount":50,"invoice_payload":"` + invoice.TgInvoicePayload + `","telegram_payment_charge_id":"tg-1","provider_payment_charge_id":""}}}`
+	for i := 0; i < 2; i++ {
+		if res := call("hook-secret", paid); res.Code != http.StatusOK {
+			t.Fatalf("delivery %d: expected 200, got %d: %s", i+1, res.Code, res.Body.String())
+		}
+	}
+	if balance, err := walletService.Balance(ctx, "user-1"); err != nil || balance != 50 {
+		t.Fatalf("expected a single 50 INT credit, got %d (%v)", balance, err)
+	}
+}
+
+func TestAdminRefundStarsPayment(t *testing.T) {
+	ctx := context.Background()
+	botAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/refundStarPayment") {
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":"https://t.me/$invoice"}`))
+	}))
+	defer botAPI.Close()
+	bot, err := payments.NewBotAPI(botAPI.Client(), botAPI.URL, "test-token")
+	if err != nil {
+		t.Fatalf("NewBotAPI() error = %v", err)
+	}
+	walletService := wallet.NewService(wallet.NewInMemoryRepository())
+	paymentsService := payments.NewService(payments.NewInMemoryRepository(), bot, 1)
+	paymentsService.WithLedger(walletService)
+	paymentsService.WithRefunder(bot)
+	invoice, err := paymentsService.CreateInvoice(ctx, payments.InvoiceRequest{UserID: "user-1", AmountINT: 50})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	if err := paymentsService.CompletePayment(ctx, 42, payments.SuccessfulPayment{Currency: "XTR", TotalAmount: 50, InvoicePayload: invoice.TgInvoicePayload, TelegramPaymentChargeID: "tg-1"}); err != nil {
+		t.Fatalf("CompletePayment() error = %v", err)
+	}
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), admin.NewService([]string{"admin-1"}), nil, nil, nil, nil, nil, nil, nil, nil, nil, walletService, nil, paymentsService, nil, nil, ClientConfigResponse{})
+
+	call := func(userID, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, userID))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	path := "/api/admin/payments/" + invoice.InvoiceID + "/refund"
+	if res := call("user-1", path, `{"reason":"duplicate"}`); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin, got %d", res.Code)
+	}
+	if res := call("admin-1", path, `{}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a reason, got %d", res.Code)
+	}
+	if res := call("admin-1", "/api/admin/payments/inv_missing/refund", `{"reason":"duplicate"}`); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown invoice, got %d", res.Code)
+	}
+	res := call("admin-1", path, `{"reason":"duplicate"}`)
+	var payment payments.Payment
+	if res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &payment) != nil || payment.Status != payments.StatusRefunded {
+		t.Fatalf("expected refunded payment, got %d %q", res.Code, res.Body.String())
+	}
+	if balance, err := walletService.Balance(ctx, "user-1"); err != nil || balance != 0 {
+		t.Fatalf("expected the top-up reversed, got %d (%v)", balance, err)
+	}
+}
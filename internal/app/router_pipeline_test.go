@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/admin"
+	"github.com/funpot/funpot-go-core/internal/pipeline"
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+func TestAdminPipelineSwitchSurfacedOnStreamerStatus(t *testing.T) {
+	handler := NewHandler(
+		zap.NewNop(),
+		func() bool { return true },
+		nil,
+		buildAuthService(t),
+		admin.NewService([]string{"admin-1"}),
+		nil,
+		streamers.NewService(),
+		nil,
+		nil,
+		nil,
+		pipeline.NewService(),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		ClientConfigResponse{},
+	)
+
+	body, _ := json.Marshal(map[string]any{
+		"scope":        "streamer",
+		"targetId":     "str-1",
+		"pausedStages": []string{"stage_c"},
+	})
+	putReq := httptest.NewRequest(http.MethodPut, "/api/admin/pipeline/switches", bytes.NewReader(body))
+	putReq.Header.Set("Authorization", "Bearer "+buildToken(t, "admin-1"))
+	putRes := httptest.NewRecorder()
+	handler.ServeHTTP(putRes, putReq)
+	if putRes.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", putRes.Code)
+	}
+
+	statusReq := httptest.NewRequest(http.MethodGet, "/api/streamers/str-1/status", nil)
+	statusReq.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+	statusRes := httptest.NewRecorder()
+	handler.ServeHTTP(statusRes, statusReq)
+	if statusRes.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", statusRes.Code)
+	}
+
+	var status streamerStatusResponse
+	if err := json.Unmarshal(statusRes.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status response: %v", err)
+	}
+	if status.Pipeline.StageEnabled("stage_c") {
+		t.Fatalf("expected stage_c paused, got %+v", status.Pipeline)
+	}
+}
+
+func TestAdminPipelineSwitchForbiddenForNonAdmin(t *testing.T) {
+	handler := NewHandler(
+		zap.NewNop(),
+		func() bool { return true },
+		nil,
+		buildAuthService(t),
+		admin.NewService([]string{"admin-1"}),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		pipeline.NewService(),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		ClientConfigResponse{},
+	)
+
+	body, _ := json.Marshal(map[string]any{"scope": "global", "paused": true})
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/pipeline/switches", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", res.Code)
+	}
+}
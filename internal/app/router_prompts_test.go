@@ -25,6 +25,15 @@ func TestAdminPromptsCreateListAndActivate(t *testing.T) {
 		nil,
 		prompts.NewService(),
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		ClientConfigResponse{},
 	)
 	token := buildToken(t, "admin-1")
@@ -87,6 +96,15 @@ func TestAdminPromptsForbiddenForNonAdmin(t *testing.T) {
 		nil,
 		prompts.NewService(),
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		ClientConfigResponse{},
 	)
 
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/admin"
+	"github.com/funpot/funpot-go-core/internal/referrals"
+	"github.com/funpot/funpot-go-core/internal/users"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func TestReferralEndpoints(t *testing.T) {
+	ctx := context.Background()
+	userService := users.NewService(users.NewInMemoryRepository())
+	inviter, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 1})
+	if err != nil {
+		t.Fatalf("sync inviter: %v", err)
+	}
+	invitee, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 2, StartParam: "ref_" + inviter.ReferralCode})
+	if err != nil {
+		t.Fatalf("sync invitee: %v", err)
+	}
+	walletService := wallet.NewService(wallet.NewInMemoryRepository())
+	referralsService := referrals.NewService(referrals.NewInMemoryRepository(), userService, walletService, 10)
+	if err := referralsService.ObserveTopup(ctx, invitee.ID, "pay-1", 200); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, userService, nil, nil, nil, nil, nil, nil, nil, nil, walletService, nil, nil, nil, referralsService, ClientConfigResponse{})
+
+	call := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, inviter.ID))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	var summary referrals.Summary
+	if res := call("/api/referrals/summary"); res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &summary) != nil {
+		t.Fatalf("expected summary, got %d %q", res.Code, res.Body.String())
+	}
+	if summary.ReferralCode != inviter.ReferralCode || summary.InvitedCount != 1 || summary.EarningsINT != 20 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	var payouts []referrals.Payout
+	if res := call("/api/referrals/payouts"); res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &payouts) != nil {
+		t.Fatalf("expected payouts, got %d %q", res.Code, res.Body.String())
+	}
+	if len(payouts) != 1 || payouts[0].AmountINT != 20 || payouts[0].PaymentID != "pay-1" {
+		t.Fatalf("unexpected payouts %+v", payouts)
+	}
+}
+
+func TestReferralPayoutReviewQueue(t *testing.T) {
+	ctx := context.Background()
+	userService := users.NewService(users.NewInMemoryRepository())
+	inviter, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 1})
+	if err != nil {
+		t.Fatalf("sync inviter: %v", err)
+	}
+	invitee, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 2, StartParam: "ref_" + inviter.ReferralCode})
+	if err != nil {
+		t.Fatalf("sync invitee: %v", err)
+	}
+	walletService := wallet.NewService(wallet.NewInMemoryRepository())
+	referralsService := referrals.NewService(referrals.NewInMemoryRepository(), userService, walletService, 10)
+	for _, userID := range []string{inviter.ID, invitee.ID} {
+		if err := referralsService.RecordLogin(ctx, userID, "198.51.100.4", "same-phone"); err != nil {
+			t.Fatalf("RecordLogin() error = %v", err)
+		}
+	}
+	if err := referralsService.ObserveTopup(ctx, invitee.ID, "pay-1", 200); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), admin.NewService([]string{"admin-1"}), userService, nil, nil, nil, nil, nil, nil, nil, nil, walletService, nil, nil, nil, referralsService, ClientConfigResponse{})
+
+	call := func(method, userID, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, userID))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := call(http.MethodGet, inviter.ID, "/api/admin/referrals/payouts?status=held", ""); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "admin-1", "/api/admin/referrals/payouts?status=bogus", ""); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown status, got %d", res.Code)
+	}
+	var held []referrals.Payout
+	if res := call(http.MethodGet, "admin-1", "/api/admin/referrals/payouts?status=held", ""); res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &held) != nil || len(held) != 1 {
+		t.Fatalf("expected one held payout, got %d %q", res.Code, res.Body.String())
+	}
+
+	base := "/api/admin/referrals/payouts/" + held[0].ID + "/"
+	if res := call(http.MethodPost, "admin-1", base+"reject", `{}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a reason, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "admin-1", base+"approve", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on approve, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "admin-1", base+"approve", ""); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 approving twice, got %d", res.Code)
+	}
+	if balance, err := walletService.Balance(ctx, inviter.ID); err != nil || balance != 20 {
+		t.Fatalf("expected inviter balance 20, got %d (%v)", balance, err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	var got string
+	handler := WithClientIP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	}), proxies)
+	resolve := func(remoteAddr, forwarded string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/telegram", nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.9:5555", "", "203.0.113.9"},
+		{"untrusted peer forging the header", "203.0.113.9:5555", "198.51.100.1", "203.0.113.9"},
+		{"trusted proxy", "10.0.0.1:5555", "203.0.113.5", "203.0.113.5"},
+		{"client-supplied hops are skipped", "10.0.0.1:5555", "198.51.100.1, 203.0.113.5", "203.0.113.5"},
+		{"chained trusted proxies", "10.0.0.1:5555", "203.0.113.5, 192.168.1.10, 10.0.0.2", "203.0.113.5"},
+		{"trusted proxy without header", "10.0.0.1:5555", "", "10.0.0.1"},
+	} {
+		if ip := resolve(tc.remoteAddr, tc.forwarded); ip != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, ip)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/telegram", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	if ip := clientIP(req); ip != "10.0.0.1" {
+		t.Fatalf("expected the connection address without the middleware, got %q", ip)
+	}
+	if _, err := ParseTrustedProxies([]string{"ingress"}); err == nil {
+		t.Fatal("expected an error for a proxy that is not an IP or CIDR")
+	}
+}
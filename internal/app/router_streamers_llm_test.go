@@ -25,6 +25,15 @@ func TestStreamerLLMDecisionsCreateAndList(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		ClientConfigResponse{},
 	)
 
@@ -73,6 +82,15 @@ func TestStreamerLLMDecisionCreateForbiddenForNonAdmin(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		ClientConfigResponse{},
 	)
 
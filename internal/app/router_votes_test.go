@@ -0,0 +1,121 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
+)
+
+func TestCastVote(t *testing.T) {
+	eventsService := events.NewService(nil)
+	created, err := eventsService.Create(context.Background(), events.CreateRequest{
+		StreamerID: "str-1",
+		Title:      "Ace this round?",
+		Options:    []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	votesService := votes.NewService(votes.NewInMemoryRepository(), eventsService)
+	eventsService.WithUserVotes(votesService)
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, nil, nil, nil, nil, eventsService, nil, nil, nil, votesService, nil, nil, nil, nil, nil, ClientConfigResponse{})
+
+	call := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/votes", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	body := `{"eventId":"` + created.ID + `","optionId":"yes"}`
+	if res := call("", body); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without Idempotency-Key, got %d", res.Code)
+	}
+	first := call("vote-1", body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+	retry := call("vote-1", body)
+	if retry.Code != http.StatusOK || retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Fatalf("expected replayed response, got %d %q", retry.Code, retry.Body.String())
+	}
+	if res := call("vote-2", `{"eventId":"`+created.ID+`","optionId":"no"}`); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a second vote, got %d", res.Code)
+	}
+	if res := call("vote-3", `{"eventId":"missing","optionId":"yes"}`); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing event, got %d", res.Code)
+	}
+
+	live := func(userID string) []events.LiveEvent {
+		req := httptest.NewRequest(http.MethodGet, "/api/events/live?streamerId=str-1", nil)
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, userID))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		var items []events.LiveEvent
+		if err := json.Unmarshal(res.Body.Bytes(), &items); err != nil || len(items) != 1 {
+			t.Fatalf("unexpected live events %d %q", res.Code, res.Body.String())
+		}
+		return items
+	}
+	if items := live("user-1"); items[0].UserVote == nil || items[0].UserVote.OptionID != "yes" {
+		t.Fatalf("expected caller's vote, got %+v", items[0].UserVote)
+	}
+	if items := live("user-2"); items[0].UserVote != nil {
+		t.Fatalf("expected no vote for another user, got %+v", items[0].UserVote)
+	}
+}
+
+func TestCastVoteRateLimited(t *testing.T) {
+	eventsService := events.NewService(nil)
+	var ids []string
+	for _, title := range []string{"Ace this round?", "Clutch this round?"} {
+		created, err := eventsService.Create(context.Background(), events.CreateRequest{
+			StreamerID: "str-1",
+			Title:      title,
+			Options:    []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+			ClosesAt:   time.Now().Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, created.ID)
+	}
+	votesService := votes.NewService(votes.NewInMemoryRepository(), eventsService)
+	votesService.WithRateLimiter(ratelimit.NewInMemoryLimiter(), ratelimit.PerMinute(1))
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, nil, nil, nil, nil, eventsService, nil, nil, nil, votesService, nil, nil, nil, nil, nil, ClientConfigResponse{})
+
+	call := func(key, eventID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/votes", bytes.NewBufferString(`{"eventId":"`+eventID+`","optionId":"yes"}`))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+		req.Header.Set("Idempotency-Key", key)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := call("vote-1", ids[0]); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	res := call("vote-2", ids[1])
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", res.Code, res.Body.String())
+	}
+	if got := res.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("expected Retry-After 60, got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func TestWalletReflectsPaidVotes(t *testing.T) {
+	ctx := context.Background()
+	eventsService := events.NewService(nil)
+	created, err := eventsService.Create(ctx, events.CreateRequest{
+		StreamerID:  "str-1",
+		Title:       "Ace this round?",
+		Options:     []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:    time.Now().Add(time.Minute),
+		CostPerVote: 10,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	walletRepo := wallet.NewInMemoryRepository()
+	walletService := wallet.NewService(walletRepo)
+	if _, err := walletService.Credit(ctx, "user-1", 25, wallet.ReasonStarsTopup, "pay-1", "topup:pay-1"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	votesRepo := votes.NewInMemoryRepository()
+	votesRepo.WithLedger(walletRepo)
+	votesService := votes.NewService(votesRepo, eventsService)
+	votesService.WithWallet(votes.NewLedgerWallet(walletService))
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), nil, nil, nil, nil, nil, eventsService, nil, nil, nil, votesService, walletService, nil, nil, nil, nil, ClientConfigResponse{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/votes", bytes.NewBufferString(`{"eventId":"`+created.ID+`","optionId":"yes","cost":10}`))
+	req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+	req.Header.Set("Idempotency-Key", "vote-1")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on vote, got %d: %s", res.Code, res.Body.String())
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/wallet"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, "user-1"))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	res = get("?limit=1")
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var got wallet.Wallet
+	if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode wallet: %v", err)
+	}
+	if got.Balance != 15 || len(got.History) != 1 || got.History[0].Reason != wallet.ReasonVoteCost || !got.HasMore {
+		t.Fatalf("unexpected wallet %+v", got)
+	}
+	if res := get("?page=abc"); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid page, got %d", res.Code)
+	}
+}
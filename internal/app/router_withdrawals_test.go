@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/admin"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/internal/withdrawals"
+)
+
+func TestWithdrawalWorkflow(t *testing.T) {
+	ctx := context.Background()
+	walletService := wallet.NewService(wallet.NewInMemoryRepository())
+	if _, err := walletService.Credit(ctx, "user-1", 100, wallet.ReasonStarsTopup, "pay-1", "topup:pay-1"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	withdrawalsService := withdrawals.NewService(withdrawals.NewInMemoryRepository(), walletService)
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, buildAuthService(t), admin.NewService([]string{"admin-1"}), nil, nil, nil, nil, nil, nil, nil, nil, nil, walletService, nil, nil, withdrawalsService, nil, ClientConfigResponse{})
+
+	call := func(method, userID, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+buildToken(t, userID))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	balance := func() int64 {
+		got, err := walletService.Balance(ctx, "user-1")
+		if err != nil {
+			t.Fatalf("Balance() error = %v", err)
+		}
+		return got
+	}
+
+	if res := call(http.MethodPost, "user-1", "/api/wallet/withdraw", "", `{"amountINT":40}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without Idempotency-Key, got %d", res.Code)
+	}
+	res := call(http.MethodPost, "user-1", "/api/wallet/withdraw", "w-1", `{"amountINT":40}`)
+	var withdrawal withdrawals.Withdrawal
+	if res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &withdrawal) != nil || withdrawal.Status != withdrawals.StatusPending {
+		t.Fatalf("expected pending withdrawal, got %d %q", res.Code, res.Body.String())
+	}
+	if got := balance(); got != 60 {
+		t.Fatalf("expected 40 INT held, got balance %d", got)
+	}
+	if res := call(http.MethodPost, "user-1", "/api/wallet/withdraw", "w-2", `{"amountINT":500}`); res.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected 402 for an amount above the balance, got %d", res.Code)
+	}
+	limited := call(http.MethodPost, "user-1", "/api/wallet/withdraw", "w-3", `{"amountINT":10}`)
+	if limited.Code != http.StatusTooManyRequests || limited.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", limited.Code, limited.Header())
+	}
+
+	if res := call(http.MethodGet, "user-1", "/api/admin/withdrawals?status=pending", "", ""); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin, got %d", res.Code)
+	}
+	var pending []withdrawals.Withdrawal
+	if res := call(http.MethodGet, "admin-1", "/api/admin/withdrawals?status=pending", "", ""); res.Code != http.StatusOK || json.Unmarshal(res.Body.Bytes(), &pending) != nil || len(pending) != 1 {
+		t.Fatalf("expected one pending withdrawal, got %d %q", res.Code, res.Body.String())
+	}
+
+	base := "/api/admin/withdrawals/" + withdrawal.ID + "/"
+	if res := call(http.MethodPost, "admin-1", base+"paid", "", ""); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when paying a pending withdrawal, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "admin-1", base+"reject", "", `{}`); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a reason, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "admin-1", base+"approve", "", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on approve, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "admin-1", base+"reject", "", `{"reason":"payout bounced"}`); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on reject, got %d: %s", res.Code, res.Body.String())
+	}
+	if got := balance(); got != 100 {
+		t.Fatalf("expected the hold released, got balance %d", got)
+	}
+	if res := call(http.MethodPost, "admin-1", "/api/admin/withdrawals/missing/approve", "", ""); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown withdrawal, got %d", res.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/auth"
+	"github.com/funpot/funpot-go-core/internal/events"
+)
+
+func workerEventsRequestFor(t *testing.T, body []byte, signature, key string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/internal/worker/events", bytes.NewReader(body))
+	req.Header.Set("X-Worker-Signature", signature)
+	req.Header.Set("X-Idempotency-Key", key)
+	return req
+}
+
+func TestWorkerEventsIngestion(t *testing.T) {
+	verifier, err := auth.NewWorkerVerifier("worker-secret")
+	if err != nil {
+		t.Fatalf("NewWorkerVerifier() error = %v", err)
+	}
+	eventsService := events.NewService(nil)
+	handler := NewHandler(zap.NewNop(), func() bool { return true }, nil, nil, nil, nil, nil, nil, nil, eventsService, nil, verifier, nil, nil, nil, nil, nil, nil, nil, ClientConfigResponse{})
+
+	body, _ := json.Marshal(map[string]any{
+		"streamerId": "str-1",
+		"source":     map[string]any{"clipId": "clip-1", "llmModel": "gemini-2.0-flash"},
+		"events": []map[string]any{
+			{"externalId": "ext-1", "title": "First blood?", "options": []map[string]string{{"id": "a", "label": "A"}, {"id": "b", "label": "B"}}, "validForSec": 60, "confidence": 0.8},
+			{"externalId": "ext-1", "title": "Duplicate", "options": []map[string]string{{"id": "a", "label": "A"}, {"id": "b", "label": "B"}}, "validForSec": 60},
+			{"externalId": "ext-2", "title": "Bad", "options": []map[string]string{{"id": "a", "label": "A"}}, "validForSec": 60},
+		},
+	})
+
+	unsigned := httptest.NewRecorder()
+	handler.ServeHTTP(unsigned, workerEventsRequestFor(t, body, "deadbeef", "batch-1"))
+	if unsigned.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad signature, got %d", unsigned.Code)
+	}
+
+	signature := auth.SignWorkerPayload("worker-secret", body)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, workerEventsRequestFor(t, body, signature, "batch-1"))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var result events.IngestResult
+	if err := json.Unmarshal(res.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if result.Accepted != 1 || len(result.Rejected) != 2 || result.Rejected[0].ExternalID != "ext-1" {
+		t.Fatalf("unexpected ingest result %+v", result)
+	}
+
+	replay := httptest.NewRecorder()
+	handler.ServeHTTP(replay, workerEventsRequestFor(t, body, signature, "batch-1"))
+	if replay.Code != http.StatusOK || replay.Header().Get("Idempotent-Replayed") != "true" || replay.Body.String() != res.Body.String() {
+		t.Fatalf("expected replayed response, got %d %q", replay.Code, replay.Body.String())
+	}
+	if live, _ := eventsService.ListLiveByStreamer(context.Background(), "str-1", ""); len(live) != 1 {
+		t.Fatalf("expected a single stored event, got %d", len(live))
+	}
+
+	other := []byte(`{"streamerId":"str-1","events":[{"externalId":"ext-3"}]}`)
+	mismatch := httptest.NewRecorder()
+	handler.ServeHTTP(mismatch, workerEventsRequestFor(t, other, auth.SignWorkerPayload("worker-secret", other), "batch-1"))
+	if mismatch.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for reused key, got %d", mismatch.Code)
+	}
+}
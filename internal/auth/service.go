@@ -99,6 +99,7 @@ func (s *Service) Authenticate(ctx context.Context, initData string, now time.Ti
 		FirstName:    payload.User.FirstName,
 		LastName:     payload.User.LastName,
 		LanguageCode: payload.User.LanguageCode,
+		StartParam:   payload.StartParam,
 	})
 	if err != nil {
 		return TokenResponse{}, err
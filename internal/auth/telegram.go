@@ -35,9 +35,10 @@ type TelegramUser struct {
 
 // TelegramInitData represents the validated initData payload.
 type TelegramInitData struct {
-	User     TelegramUser
-	AuthDate time.Time
-	Raw      url.Values
+	User       TelegramUser
+	AuthDate   time.Time
+	StartParam string
+	Raw        url.Values
 }
 
 // VerifyInitData parses and validates Telegram init data according to the spec.
@@ -88,9 +89,10 @@ func VerifyInitData(raw string, botToken string, maxAge time.Duration, now time.
 	}
 
 	return TelegramInitData{
-		User:     user,
-		AuthDate: authTime,
-		Raw:      values,
+		User:       user,
+		AuthDate:   authTime,
+		StartParam: values.Get("start_param"),
+		Raw:        values,
 	}, nil
 }
 
@@ -18,6 +18,7 @@ func TestVerifyInitData_Success(t *testing.T) {
 	values := url.Values{}
 	values.Set("auth_date", "1700000000")
 	values.Set("query_id", "AAEWpqlDAAAAANz")
+	values.Set("start_param", "ref_ABC123")
 
 	userPayload := map[string]any{
 		"id":         float64(123456789),
@@ -50,6 +51,9 @@ func TestVerifyInitData_Success(t *testing.T) {
 	if result.AuthDate.Unix() != 1_700_000_000 {
 		t.Fatalf("unexpected auth date: %d", result.AuthDate.Unix())
 	}
+	if result.StartParam != "ref_ABC123" {
+		t.Fatalf("unexpected start param: %s", result.StartParam)
+	}
 }
 
 func TestVerifyInitData_InvalidHash(t *testing.T) {
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidWorkerSignature = errors.New("invalid worker signature")
+
+// WorkerVerifier checks X-Worker-Signature headers: the hex HMAC-SHA256 of the
+// raw request body keyed with the shared worker secret, optionally prefixed
+// with "sha256=".
+type WorkerVerifier struct {
+	secret []byte
+}
+
+func NewWorkerVerifier(secret string) (*WorkerVerifier, error) {
+	if strings.TrimSpace(secret) == "" {
+		return nil, errors.New("worker hmac secret is required")
+	}
+	return &WorkerVerifier{secret: []byte(secret)}, nil
+}
+
+func (v *WorkerVerifier) Verify(body []byte, signature string) error {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	provided, err := hex.DecodeString(signature)
+	if err != nil || len(provided) != sha256.Size {
+		return ErrInvalidWorkerSignature
+	}
+	if !hmac.Equal(provided, signWorkerPayload(v.secret, body)) {
+		return ErrInvalidWorkerSignature
+	}
+	return nil
+}
+
+// SignWorkerPayload returns the signature header value a worker sends for body.
+func SignWorkerPayload(secret string, body []byte) string {
+	return hex.EncodeToString(signWorkerPayload([]byte(secret), body))
+}
+
+func signWorkerPayload(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestWorkerVerifier(t *testing.T) {
+	verifier, err := NewWorkerVerifier("secret")
+	if err != nil {
+		t.Fatalf("NewWorkerVerifier() error = %v", err)
+	}
+	body := []byte(`{"streamerId":"s-1"}`)
+	signature := SignWorkerPayload("secret", body)
+
+	if err := verifier.Verify(body, signature); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := verifier.Verify(body, "sha256="+signature); err != nil {
+		t.Fatalf("Verify() with prefix error = %v", err)
+	}
+	if err := verifier.Verify([]byte(`{"streamerId":"s-2"}`), signature); err != ErrInvalidWorkerSignature {
+		t.Fatalf("expected ErrInvalidWorkerSignature for tampered body, got %v", err)
+	}
+	if err := verifier.Verify(body, SignWorkerPayload("other", body)); err != ErrInvalidWorkerSignature {
+		t.Fatalf("expected ErrInvalidWorkerSignature for wrong secret, got %v", err)
+	}
+	if _, err := NewWorkerVerifier(" "); err == nil {
+		t.Fatal("expected error for empty secret")
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
@@ -24,6 +25,65 @@ type Config struct {
 	Database    DatabaseConfig
 	Features    FeatureConfig
 	Client      ClientConfig
+	LLM         LLMConfig
+	Media       MediaConfig
+	Events      EventsConfig
+	Worker      WorkerConfig
+	Votes       VotesConfig
+	Payouts     PayoutsConfig
+	Payments    PaymentsConfig
+	Referrals   ReferralsConfig
+}
+
+// ReferralsConfig controls referral bonuses on Stars top-ups.
+type ReferralsConfig struct {
+	// Percent of each invitee top-up credited to the inviter.
+	Percent float64
+	// LinkBase is the Mini App link that "ref_<code>" is appended to.
+	LinkBase string
+	// HoldScore is the abuse score at which bonuses wait for admin review;
+	// 0 pays every bonus immediately.
+	HoldScore int
+	// BurstSize invitees joining within BurstWindow count as a signup burst.
+	BurstWindow time.Duration
+	BurstSize   int
+}
+
+// PaymentsConfig configures Telegram Stars top-ups. Invoices use the bot
+// token from AuthConfig and are disabled while it is empty.
+type PaymentsConfig struct {
+	TelegramAPIURL string
+	// WebhookSecret is the secret_token registered with setWebhook; the
+	// payments webhook is disabled while it is empty.
+	WebhookSecret string
+	// ReconcileInterval is how often Stars transactions are compared with
+	// the payments table; ReconcileWindow is how far back.
+	ReconcileInterval time.Duration
+	ReconcileWindow   time.Duration
+}
+
+// PayoutsConfig controls how winners of resolved events are paid.
+type PayoutsConfig struct {
+	// Model is parimutuel or fixed_odds.
+	Model string
+	// RakeBPS is the house share of a parimutuel pot in basis points.
+	RakeBPS int
+	// FixedOdds multiplies winning stakes under the fixed_odds model.
+	FixedOdds float64
+	// Interval is how often resolved events are checked for settlement.
+	Interval time.Duration
+}
+
+// VotesConfig controls the voting hot path.
+type VotesConfig struct {
+	// PaidRequiresTally refuses paid votes while the Redis vote tally is down.
+	PaidRequiresTally bool
+}
+
+// WorkerConfig holds the shared secret used to verify signed worker callbacks.
+// Internal worker endpoints are disabled while it is empty.
+type WorkerConfig struct {
+	HMACSecret string
 }
 
 // AdminConfig controls role-based admin access.
@@ -37,6 +97,10 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	// TrustedProxies lists the IPs or CIDRs of proxies whose
+	// X-Forwarded-For hops are believed; other requests use the connection
+	// address as the client IP.
+	TrustedProxies []string
 }
 
 // LoggingConfig controls logger behavior.
@@ -146,6 +210,90 @@ type ClientConfig struct {
 	VotePerMin int
 }
 
+// EventsConfig controls live events generated from pipeline decisions.
+type EventsConfig struct {
+	// DefaultCostPerVote applies to worker-ingested events.
+	DefaultCostPerVote int
+	AutoEnabled        bool
+	AutoVoteWindow     time.Duration
+	AutoCostPerVote    int
+	// AutoUndecidedPolicy handles draw/unknown results: "refund" or "review".
+	AutoUndecidedPolicy string
+	// AutoMinConfidence is the Stage D confidence a win/loss needs to settle
+	// the event; less confident results follow AutoUndecidedPolicy.
+	AutoMinConfidence float64
+	// CloserInterval is how often expired live events are closed.
+	CloserInterval time.Duration
+	CloserLeaseTTL time.Duration
+	// LiveCacheTTL bounds the staleness of /api/events/live; 0 disables caching.
+	LiveCacheTTL time.Duration
+	// TotalsSnapshotInterval is how often tallied vote totals are persisted.
+	TotalsSnapshotInterval time.Duration
+}
+
+// MediaConfig controls the stage A stream analysis worker.
+type MediaConfig struct {
+	Enabled bool
+	// StreamURLTemplate is the ffmpeg input of a live stream with
+	// "{username}" replaced by the streamer's username.
+	StreamURLTemplate string
+	// ChunkDuration is how much of the stream is recorded per cycle.
+	ChunkDuration time.Duration
+	// Interval is how often approved streamers are analysed.
+	Interval      time.Duration
+	MinConfidence float64
+	FFmpegBinary  string
+	// WorkDir holds captured chunks; empty uses the OS temp directory.
+	WorkDir string
+	// GameID binds the pipeline to a game so game-level switches apply.
+	GameID string
+	// PreprocessMode is keyframes or clip.
+	PreprocessMode string
+	FrameCount     int
+	FrameWidth     int
+	// HashThreshold is the per-frame dHash distance still treated as
+	// unchanged; a negative value classifies every chunk.
+	HashThreshold int
+}
+
+// LLMConfig controls LLM usage by the media pipeline.
+type LLMConfig struct {
+	Budget    LLMBudgetConfig
+	Providers LLMProvidersConfig
+}
+
+// LLMProvidersConfig configures the model providers. A provider is registered
+// only when its credentials (or, for the fake, its flag) are set.
+type LLMProvidersConfig struct {
+	GeminiAPIKey  string
+	GeminiBaseURL string
+	OpenAIAPIKey  string
+	OpenAIBaseURL string
+	// OpenAIPrefix routes models such as "openai/gpt-4o-mini" to the
+	// OpenAI-compatible endpoint; the prefix is stripped before the call.
+	OpenAIPrefix string
+	FakeEnabled  bool
+}
+
+// LLMBudgetConfig sets daily token and cost caps. Zero disables a cap.
+// Prices are USD per million tokens keyed by model.
+type LLMBudgetConfig struct {
+	GlobalDailyTokens      int
+	GlobalDailyCostUSD     float64
+	StreamerDailyTokens    int
+	StreamerDailyCostUSD   float64
+	ModelDailyTokens       map[string]int
+	ModelDailyCostUSD      map[string]float64
+	InputPricePerMTok      map[string]float64
+	OutputPricePerMTok     map[string]float64
+	SoftLimitRatio         float64
+	DegradedIntervalFactor int
+	// ImageTokens and ClipTokens estimate the input tokens of one keyframe
+	// and one stream clip when a call is reserved before it runs.
+	ImageTokens int
+	ClipTokens  int
+}
+
 // Load reads configuration from the environment, applying defaults and .env overrides.
 func Load() (Config, error) {
 	_ = godotenv.Load()
@@ -292,6 +440,201 @@ func Load() (Config, error) {
 
 	currencies := getCSVStrings("FUNPOT_CLIENT_CURRENCIES", []string{"INT"})
 
+	llmGlobalDailyTokens, err := getInt("FUNPOT_LLM_BUDGET_GLOBAL_DAILY_TOKENS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmGlobalDailyCost, err := getFloat("FUNPOT_LLM_BUDGET_GLOBAL_DAILY_COST_USD", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmStreamerDailyTokens, err := getInt("FUNPOT_LLM_BUDGET_STREAMER_DAILY_TOKENS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmStreamerDailyCost, err := getFloat("FUNPOT_LLM_BUDGET_STREAMER_DAILY_COST_USD", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmModelDailyTokens, err := getIntMap("FUNPOT_LLM_BUDGET_MODEL_DAILY_TOKENS")
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmModelDailyCost, err := getFloatMap("FUNPOT_LLM_BUDGET_MODEL_DAILY_COST_USD")
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmInputPrices, err := getFloatMap("FUNPOT_LLM_PRICE_INPUT_PER_MTOK")
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmOutputPrices, err := getFloatMap("FUNPOT_LLM_PRICE_OUTPUT_PER_MTOK")
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmSoftLimitRatio, err := getFloat("FUNPOT_LLM_BUDGET_SOFT_LIMIT_RATIO", 0.8)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmDegradedIntervalFactor, err := getInt("FUNPOT_LLM_BUDGET_DEGRADED_INTERVAL_FACTOR", 4)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmImageTokens, err := getInt("FUNPOT_LLM_BUDGET_IMAGE_TOKENS", 258)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmClipTokens, err := getInt("FUNPOT_LLM_BUDGET_CLIP_TOKENS", 3000)
+	if err != nil {
+		return Config{}, err
+	}
+
+	llmFakeEnabled, err := getBool("FUNPOT_LLM_FAKE_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaEnabled, err := getBool("FUNPOT_MEDIA_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaChunkDuration, err := getDuration("FUNPOT_MEDIA_CHUNK_DURATION", 10*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaInterval, err := getDuration("FUNPOT_MEDIA_INTERVAL", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaMinConfidence, err := getFloat("FUNPOT_MEDIA_MIN_CONFIDENCE", 0.5)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaFrameCount, err := getInt("FUNPOT_MEDIA_FRAME_COUNT", 4)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaFrameWidth, err := getInt("FUNPOT_MEDIA_FRAME_WIDTH", 512)
+	if err != nil {
+		return Config{}, err
+	}
+
+	mediaHashThreshold, err := getInt("FUNPOT_MEDIA_HASH_THRESHOLD", 5)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsAutoEnabled, err := getBool("FUNPOT_EVENTS_AUTO_ENABLED", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsAutoVoteWindow, err := getDuration("FUNPOT_EVENTS_AUTO_VOTE_WINDOW", 3*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsAutoCostPerVote, err := getInt("FUNPOT_EVENTS_AUTO_COST_PER_VOTE", 10)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsAutoMinConfidence, err := getFloat("FUNPOT_EVENTS_AUTO_MIN_CONFIDENCE", 0.8)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsDefaultCostPerVote, err := getInt("FUNPOT_EVENTS_DEFAULT_COST_PER_VOTE", 10)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsCloserInterval, err := getDuration("FUNPOT_EVENTS_CLOSER_INTERVAL", time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsCloserLeaseTTL, err := getDuration("FUNPOT_EVENTS_CLOSER_LEASE_TTL", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsLiveCacheTTL, err := getDuration("FUNPOT_EVENTS_LIVE_CACHE_TTL", 500*time.Millisecond)
+	if err != nil {
+		return Config{}, err
+	}
+
+	eventsTotalsSnapshotInterval, err := getDuration("FUNPOT_EVENTS_TOTALS_SNAPSHOT_INTERVAL", 2*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	votesPaidRequiresTally, err := getBool("FUNPOT_VOTES_PAID_REQUIRES_REDIS", true)
+	if err != nil {
+		return Config{}, err
+	}
+
+	payoutsRakeBPS, err := getInt("FUNPOT_PAYOUTS_RAKE_BPS", 500)
+	if err != nil {
+		return Config{}, err
+	}
+
+	payoutsFixedOdds, err := getFloat("FUNPOT_PAYOUTS_FIXED_ODDS", 2)
+	if err != nil {
+		return Config{}, err
+	}
+
+	payoutsInterval, err := getDuration("FUNPOT_PAYOUTS_INTERVAL", 2*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	paymentsReconcileInterval, err := getDuration("FUNPOT_PAYMENTS_RECONCILE_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	paymentsReconcileWindow, err := getDuration("FUNPOT_PAYMENTS_RECONCILE_WINDOW", 7*24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	referralsPercent, err := getFloat("FUNPOT_REFERRALS_PERCENT", 5)
+	if err != nil {
+		return Config{}, err
+	}
+
+	referralsHoldScore, err := getInt("FUNPOT_REFERRALS_HOLD_SCORE", 50)
+	if err != nil {
+		return Config{}, err
+	}
+
+	referralsBurstWindow, err := getDuration("FUNPOT_REFERRALS_BURST_WINDOW", time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	referralsBurstSize, err := getInt("FUNPOT_REFERRALS_BURST_SIZE", 5)
+	if err != nil {
+		return Config{}, err
+	}
+
 	maxIdleConns, err := getInt("FUNPOT_DATABASE_MAX_IDLE_CONNS", 5)
 	if err != nil {
 		return Config{}, err
@@ -319,6 +662,7 @@ func Load() (Config, error) {
 			ReadTimeout:     readTimeout,
 			WriteTimeout:    writeTimeout,
 			ShutdownTimeout: shutdownTimeout,
+			TrustedProxies:  getCSVStrings("FUNPOT_SERVER_TRUSTED_PROXIES", nil),
 		},
 		Logging: LoggingConfig{
 			Level: getString("FUNPOT_LOG_LEVEL", "info"),
@@ -388,6 +732,81 @@ func Load() (Config, error) {
 			Currencies: currencies,
 			VotePerMin: votePerMin,
 		},
+		LLM: LLMConfig{
+			Budget: LLMBudgetConfig{
+				GlobalDailyTokens:      llmGlobalDailyTokens,
+				GlobalDailyCostUSD:     llmGlobalDailyCost,
+				StreamerDailyTokens:    llmStreamerDailyTokens,
+				StreamerDailyCostUSD:   llmStreamerDailyCost,
+				ModelDailyTokens:       llmModelDailyTokens,
+				ModelDailyCostUSD:      llmModelDailyCost,
+				InputPricePerMTok:      llmInputPrices,
+				OutputPricePerMTok:     llmOutputPrices,
+				SoftLimitRatio:         llmSoftLimitRatio,
+				DegradedIntervalFactor: llmDegradedIntervalFactor,
+				ImageTokens:            llmImageTokens,
+				ClipTokens:             llmClipTokens,
+			},
+			Providers: LLMProvidersConfig{
+				GeminiAPIKey:  getString("FUNPOT_LLM_GEMINI_API_KEY", ""),
+				GeminiBaseURL: getString("FUNPOT_LLM_GEMINI_BASE_URL", "https://generativelanguage.googleapis.com"),
+				OpenAIAPIKey:  getString("FUNPOT_LLM_OPENAI_API_KEY", ""),
+				OpenAIBaseURL: getString("FUNPOT_LLM_OPENAI_BASE_URL", "https://api.openai.com/v1"),
+				OpenAIPrefix:  getString("FUNPOT_LLM_OPENAI_MODEL_PREFIX", "openai/"),
+				FakeEnabled:   llmFakeEnabled,
+			},
+		},
+		Media: MediaConfig{
+			Enabled:           mediaEnabled,
+			StreamURLTemplate: strings.TrimSpace(getString("FUNPOT_MEDIA_STREAM_URL_TEMPLATE", "")),
+			ChunkDuration:     mediaChunkDuration,
+			Interval:          mediaInterval,
+			MinConfidence:     mediaMinConfidence,
+			FFmpegBinary:      getString("FUNPOT_MEDIA_FFMPEG_BINARY", "ffmpeg"),
+			WorkDir:           getString("FUNPOT_MEDIA_WORK_DIR", ""),
+			GameID:            strings.TrimSpace(getString("FUNPOT_MEDIA_GAME_ID", "")),
+			PreprocessMode:    strings.ToLower(getString("FUNPOT_MEDIA_PREPROCESS_MODE", "keyframes")),
+			FrameCount:        mediaFrameCount,
+			FrameWidth:        mediaFrameWidth,
+			HashThreshold:     mediaHashThreshold,
+		},
+		Events: EventsConfig{
+			DefaultCostPerVote:     eventsDefaultCostPerVote,
+			AutoEnabled:            eventsAutoEnabled,
+			AutoVoteWindow:         eventsAutoVoteWindow,
+			AutoCostPerVote:        eventsAutoCostPerVote,
+			AutoUndecidedPolicy:    strings.ToLower(getString("FUNPOT_EVENTS_AUTO_UNDECIDED_POLICY", "refund")),
+			AutoMinConfidence:      eventsAutoMinConfidence,
+			CloserInterval:         eventsCloserInterval,
+			CloserLeaseTTL:         eventsCloserLeaseTTL,
+			LiveCacheTTL:           eventsLiveCacheTTL,
+			TotalsSnapshotInterval: eventsTotalsSnapshotInterval,
+		},
+		Worker: WorkerConfig{
+			HMACSecret: getString("FUNPOT_WORKER_HMAC_SECRET", ""),
+		},
+		Votes: VotesConfig{
+			PaidRequiresTally: votesPaidRequiresTally,
+		},
+		Payments: PaymentsConfig{
+			TelegramAPIURL:    getString("FUNPOT_PAYMENTS_TELEGRAM_API_URL", "https://api.telegram.org"),
+			WebhookSecret:     os.Getenv("FUNPOT_PAYMENTS_WEBHOOK_SECRET"),
+			ReconcileInterval: paymentsReconcileInterval,
+			ReconcileWindow:   paymentsReconcileWindow,
+		},
+		Referrals: ReferralsConfig{
+			Percent:     referralsPercent,
+			LinkBase:    os.Getenv("FUNPOT_REFERRALS_LINK_BASE"),
+			HoldScore:   referralsHoldScore,
+			BurstWindow: referralsBurstWindow,
+			BurstSize:   referralsBurstSize,
+		},
+		Payouts: PayoutsConfig{
+			Model:     strings.ToLower(getString("FUNPOT_PAYOUTS_MODEL", "parimutuel")),
+			RakeBPS:   payoutsRakeBPS,
+			FixedOdds: payoutsFixedOdds,
+			Interval:  payoutsInterval,
+		},
 	}
 
 	if cfg.Database.Enabled {
@@ -415,6 +834,108 @@ func Load() (Config, error) {
 		return Config{}, fmt.Errorf("invalid redis pool bounds: min_idle=%d pool_size=%d", cfg.Redis.MinIdleConns, cfg.Redis.PoolSize)
 	}
 
+	if cfg.LLM.Budget.SoftLimitRatio <= 0 || cfg.LLM.Budget.SoftLimitRatio > 1 {
+		return Config{}, fmt.Errorf("FUNPOT_LLM_BUDGET_SOFT_LIMIT_RATIO must be in (0, 1]")
+	}
+
+	if cfg.LLM.Budget.DegradedIntervalFactor < 1 {
+		return Config{}, fmt.Errorf("FUNPOT_LLM_BUDGET_DEGRADED_INTERVAL_FACTOR must be >= 1")
+	}
+
+	if cfg.LLM.Budget.ImageTokens < 1 || cfg.LLM.Budget.ClipTokens < 1 {
+		return Config{}, fmt.Errorf("FUNPOT_LLM_BUDGET_IMAGE_TOKENS and FUNPOT_LLM_BUDGET_CLIP_TOKENS must be >= 1")
+	}
+
+	if cfg.Media.Enabled && !strings.Contains(cfg.Media.StreamURLTemplate, "{username}") {
+		return Config{}, fmt.Errorf("FUNPOT_MEDIA_STREAM_URL_TEMPLATE must contain {username} when FUNPOT_MEDIA_ENABLED=true")
+	}
+
+	if cfg.Media.ChunkDuration <= 0 || cfg.Media.Interval <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_MEDIA_CHUNK_DURATION and FUNPOT_MEDIA_INTERVAL must be > 0")
+	}
+
+	if cfg.Media.MinConfidence < 0 || cfg.Media.MinConfidence > 1 {
+		return Config{}, fmt.Errorf("FUNPOT_MEDIA_MIN_CONFIDENCE must be between 0 and 1")
+	}
+
+	if cfg.Media.PreprocessMode != "keyframes" && cfg.Media.PreprocessMode != "clip" {
+		return Config{}, fmt.Errorf("FUNPOT_MEDIA_PREPROCESS_MODE must be keyframes or clip")
+	}
+
+	if cfg.Media.FrameCount < 1 || cfg.Media.FrameWidth < 1 {
+		return Config{}, fmt.Errorf("FUNPOT_MEDIA_FRAME_COUNT and FUNPOT_MEDIA_FRAME_WIDTH must be >= 1")
+	}
+
+	if cfg.Events.CloserInterval <= 0 || cfg.Events.CloserLeaseTTL <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_CLOSER_INTERVAL and FUNPOT_EVENTS_CLOSER_LEASE_TTL must be > 0")
+	}
+
+	if cfg.Events.TotalsSnapshotInterval <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_TOTALS_SNAPSHOT_INTERVAL must be > 0")
+	}
+
+	if cfg.Client.StarsRate <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_CLIENT_STARS_RATE must be > 0")
+	}
+
+	if cfg.Client.VotePerMin < 0 {
+		return Config{}, fmt.Errorf("FUNPOT_CLIENT_LIMIT_VOTE_PER_MIN must be >= 0")
+	}
+
+	if cfg.Events.LiveCacheTTL < 0 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_LIVE_CACHE_TTL must be >= 0")
+	}
+
+	if cfg.Events.DefaultCostPerVote < 0 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_DEFAULT_COST_PER_VOTE must be >= 0")
+	}
+
+	if cfg.Events.AutoVoteWindow <= 0 || cfg.Events.AutoCostPerVote < 0 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_AUTO_VOTE_WINDOW must be > 0 and FUNPOT_EVENTS_AUTO_COST_PER_VOTE must be >= 0")
+	}
+
+	if cfg.Events.AutoUndecidedPolicy != "refund" && cfg.Events.AutoUndecidedPolicy != "review" {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_AUTO_UNDECIDED_POLICY must be refund or review")
+	}
+
+	if cfg.Events.AutoMinConfidence < 0 || cfg.Events.AutoMinConfidence > 1 {
+		return Config{}, fmt.Errorf("FUNPOT_EVENTS_AUTO_MIN_CONFIDENCE must be between 0 and 1")
+	}
+
+	if cfg.Payouts.Model != "parimutuel" && cfg.Payouts.Model != "fixed_odds" {
+		return Config{}, fmt.Errorf("FUNPOT_PAYOUTS_MODEL must be parimutuel or fixed_odds")
+	}
+
+	if cfg.Payouts.RakeBPS < 0 || cfg.Payouts.RakeBPS > 10000 {
+		return Config{}, fmt.Errorf("FUNPOT_PAYOUTS_RAKE_BPS must be between 0 and 10000")
+	}
+
+	if cfg.Payouts.Model == "fixed_odds" && cfg.Payouts.FixedOdds < 1 {
+		return Config{}, fmt.Errorf("FUNPOT_PAYOUTS_FIXED_ODDS must be >= 1")
+	}
+
+	if cfg.Payouts.Interval <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_PAYOUTS_INTERVAL must be > 0")
+	}
+
+	if cfg.Payments.ReconcileInterval <= 0 || cfg.Payments.ReconcileWindow <= 0 {
+		return Config{}, fmt.Errorf("FUNPOT_PAYMENTS_RECONCILE_INTERVAL and FUNPOT_PAYMENTS_RECONCILE_WINDOW must be > 0")
+	}
+
+	if cfg.Referrals.Percent < 0 || cfg.Referrals.Percent > 100 {
+		return Config{}, fmt.Errorf("FUNPOT_REFERRALS_PERCENT must be between 0 and 100")
+	}
+
+	if cfg.Referrals.HoldScore < 0 || cfg.Referrals.BurstWindow < 0 || cfg.Referrals.BurstSize < 0 {
+		return Config{}, fmt.Errorf("FUNPOT_REFERRALS_HOLD_SCORE, FUNPOT_REFERRALS_BURST_WINDOW and FUNPOT_REFERRALS_BURST_SIZE must be >= 0")
+	}
+
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return Config{}, fmt.Errorf("FUNPOT_SERVER_TRUSTED_PROXIES entry %q is not an IP or CIDR", proxy)
+		}
+	}
+
 	return cfg, nil
 }
 func getString(key, fallback string) string {
@@ -498,6 +1019,55 @@ func getFeatureFlags(key string) (map[string]bool, error) {
 	return flags, nil
 }
 
+func getFloatMap(key string) (map[string]float64, error) {
+	pairs, err := getPairs(key)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(pairs))
+	for name, raw := range pairs {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float for %s[%s]: %w", key, name, err)
+		}
+		values[name] = parsed
+	}
+	return values, nil
+}
+
+func getIntMap(key string) (map[string]int, error) {
+	pairs, err := getPairs(key)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]int, len(pairs))
+	for name, raw := range pairs {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int for %s[%s]: %w", key, name, err)
+		}
+		values[name] = parsed
+	}
+	return values, nil
+}
+
+// getPairs parses comma separated name=value entries.
+func getPairs(key string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid pair for %s: %s", key, entry)
+		}
+		pairs[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return pairs, nil
+}
+
 func getCSVStrings(key string, fallback []string) []string {
 	value := strings.TrimSpace(os.Getenv(key))
 	if value == "" {
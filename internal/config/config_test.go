@@ -190,3 +190,80 @@ func TestLoadDatabaseValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadLLMBudgetConfig(t *testing.T) {
+	t.Setenv("FUNPOT_LLM_BUDGET_GLOBAL_DAILY_TOKENS", "5000000")
+	t.Setenv("FUNPOT_LLM_BUDGET_STREAMER_DAILY_COST_USD", "2.5")
+	t.Setenv("FUNPOT_LLM_BUDGET_MODEL_DAILY_TOKENS", "gemini-2.0-flash=1000000, gemini-1.5-pro=200000")
+	t.Setenv("FUNPOT_LLM_PRICE_INPUT_PER_MTOK", "gemini-2.0-flash=0.1")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	budget := cfg.LLM.Budget
+	if budget.GlobalDailyTokens != 5000000 {
+		t.Fatalf("expected global daily tokens 5000000, got %d", budget.GlobalDailyTokens)
+	}
+	if budget.StreamerDailyCostUSD != 2.5 {
+		t.Fatalf("expected streamer daily cost 2.5, got %v", budget.StreamerDailyCostUSD)
+	}
+	if budget.ModelDailyTokens["gemini-1.5-pro"] != 200000 {
+		t.Fatalf("expected model token cap 200000, got %v", budget.ModelDailyTokens)
+	}
+	if budget.InputPricePerMTok["gemini-2.0-flash"] != 0.1 {
+		t.Fatalf("expected input price 0.1, got %v", budget.InputPricePerMTok)
+	}
+	if budget.SoftLimitRatio != 0.8 || budget.DegradedIntervalFactor != 4 || budget.ImageTokens != 258 || budget.ClipTokens != 3000 {
+		t.Fatalf("unexpected defaults: %+v", budget)
+	}
+
+	t.Setenv("FUNPOT_LLM_BUDGET_MODEL_DAILY_TOKENS", "broken")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for malformed model budget pairs")
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("FUNPOT_SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1] != "192.168.1.10" {
+		t.Fatalf("unexpected trusted proxies %v", cfg.Server.TrustedProxies)
+	}
+
+	t.Setenv("FUNPOT_SERVER_TRUSTED_PROXIES", "ingress")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for a proxy that is not an IP or CIDR")
+	}
+}
+
+func TestLoadMediaConfig(t *testing.T) {
+	t.Setenv("FUNPOT_MEDIA_ENABLED", "true")
+	t.Setenv("FUNPOT_MEDIA_STREAM_URL_TEMPLATE", "https://relay.example/{username}/index.m3u8")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if !cfg.Media.Enabled || cfg.Media.ChunkDuration != 10*time.Second || cfg.Media.FFmpegBinary != "ffmpeg" {
+		t.Fatalf("unexpected media config %+v", cfg.Media)
+	}
+	if cfg.Media.PreprocessMode != "keyframes" || cfg.Media.FrameCount != 4 || cfg.Media.HashThreshold != 5 {
+		t.Fatalf("unexpected preprocess defaults %+v", cfg.Media)
+	}
+
+	t.Setenv("FUNPOT_MEDIA_STREAM_URL_TEMPLATE", "https://relay.example/live.m3u8")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for a stream URL template without {username}")
+	}
+
+	t.Setenv("FUNPOT_MEDIA_STREAM_URL_TEMPLATE", "https://relay.example/{username}/index.m3u8")
+	t.Setenv("FUNPOT_MEDIA_PREPROCESS_MODE", "gif")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for an unknown preprocess mode")
+	}
+}
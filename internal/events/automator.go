@@ -0,0 +1,216 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/prompts"
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+const (
+	// UndecidedRefund cancels the event so votes are refunded.
+	UndecidedRefund = "refund"
+	// UndecidedReview closes the event with a result flagged for manual review.
+	UndecidedReview = "review"
+
+	AutoMatchTitle = "Will the streamer win this match?"
+	OptionYes      = "yes"
+	OptionNo       = "no"
+)
+
+type AutomationConfig struct {
+	// VoteWindow is how long voting stays open after the match starts.
+	VoteWindow      time.Duration
+	CostPerVote     int
+	UndecidedPolicy string
+	// MinConfidence is the Stage D confidence a win or loss needs to settle
+	// the event; less confident results follow UndecidedPolicy.
+	MinConfidence float64
+}
+
+type autoMatch struct {
+	eventID string
+	stageC  string
+}
+
+// Automator opens match events from Stage C transitions and settles them from
+// Stage D once the match is reported finished.
+type Automator struct {
+	events  *Service
+	logger  *zap.Logger
+	cfg     AutomationConfig
+	mu      sync.Mutex
+	matches map[string]*autoMatch
+}
+
+func NewAutomator(events *Service, logger *zap.Logger, cfg AutomationConfig) *Automator {
+	if cfg.VoteWindow <= 0 {
+		cfg.VoteWindow = 3 * time.Minute
+	}
+	if cfg.UndecidedPolicy != UndecidedReview {
+		cfg.UndecidedPolicy = UndecidedRefund
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Automator{
+		events:  events,
+		logger:  logger,
+		cfg:     cfg,
+		matches: make(map[string]*autoMatch),
+	}
+}
+
+func (a *Automator) ObserveDecision(ctx context.Context, decision streamers.LLMDecision) {
+	if decision.ErrorCode != "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	switch decision.Stage {
+	case prompts.StageC:
+		err = a.onMatchState(ctx, decision)
+	case prompts.StageD:
+		err = a.onMatchResult(ctx, decision)
+	}
+	if err != nil {
+		a.logger.Warn("event automation failed",
+			zap.String("streamer_id", decision.StreamerID),
+			zap.String("stage", decision.Stage),
+			zap.String("label", decision.Label),
+			zap.Error(err))
+	}
+}
+
+// track returns the streamer's match, rebuilding it from the newest auto
+// event that still awaits a result when the automator restarted mid-match.
+func (a *Automator) track(ctx context.Context, streamerID string) (*autoMatch, error) {
+	if match, ok := a.matches[streamerID]; ok {
+		return match, nil
+	}
+	match := &autoMatch{}
+	var newest *LiveEvent
+	for _, state := range []string{StateLive, StateClosed} {
+		items, err := a.events.repo.ListByStreamer(ctx, streamerID, state)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if items[i].Title != AutoMatchTitle || items[i].Result != nil {
+				continue
+			}
+			if newest == nil || items[i].CreatedAt.After(newest.CreatedAt) {
+				newest = &items[i]
+			}
+		}
+	}
+	if newest != nil {
+		match.eventID = newest.ID
+		if newest.State == StateLive {
+			match.stageC = "in_progress"
+		}
+	}
+	a.matches[streamerID] = match
+	return match, nil
+}
+
+func (a *Automator) onMatchState(ctx context.Context, decision streamers.LLMDecision) error {
+	match, err := a.track(ctx, decision.StreamerID)
+	if err != nil {
+		return err
+	}
+	previous := match.stageC
+	match.stageC = decision.Label
+
+	switch decision.Label {
+	case "pregame":
+		// A new match is starting; the previous one's event is left to the
+		// closer and admins.
+		match.eventID = ""
+	case "in_progress":
+		if previous == "in_progress" {
+			return nil
+		}
+		if err := a.forgetSettled(ctx, match); err != nil {
+			return err
+		}
+		if match.eventID != "" {
+			return nil
+		}
+		event, err := a.events.Create(ctx, CreateRequest{
+			StreamerID:  decision.StreamerID,
+			Title:       AutoMatchTitle,
+			Options:     []Option{{ID: OptionYes, Label: "Yes"}, {ID: OptionNo, Label: "No"}},
+			ClosesAt:    a.events.nowFn().Add(a.cfg.VoteWindow),
+			CostPerVote: a.cfg.CostPerVote,
+		})
+		if err != nil {
+			return err
+		}
+		match.eventID = event.ID
+	case "finished":
+		if err := a.forgetSettled(ctx, match); err != nil || match.eventID == "" {
+			return err
+		}
+		if event, err := a.events.Get(ctx, match.eventID); err == nil && event.State == StateLive {
+			_, err = a.events.Close(ctx, match.eventID)
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetSettled stops tracking the match event once it was cancelled,
+// resolved or removed outside the automator, so the next match opens its own.
+func (a *Automator) forgetSettled(ctx context.Context, match *autoMatch) error {
+	if match.eventID == "" {
+		return nil
+	}
+	event, err := a.events.Get(ctx, match.eventID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return err
+	case event.State != StateCancelled && event.Result == nil:
+		return nil
+	}
+	match.eventID = ""
+	return nil
+}
+
+func (a *Automator) onMatchResult(ctx context.Context, decision streamers.LLMDecision) error {
+	match, err := a.track(ctx, decision.StreamerID)
+	if err != nil || match.eventID == "" || match.stageC != "finished" {
+		return err
+	}
+	eventID := match.eventID
+	// Reset rather than forget the streamer so the settled match is not
+	// rebuilt from older events.
+	*match = autoMatch{}
+
+	result := Result{Outcome: decision.Label, Confidence: decision.Confidence}
+	switch {
+	case decision.Confidence < a.cfg.MinConfidence:
+		if a.cfg.UndecidedPolicy == UndecidedRefund {
+			_, err := a.events.Cancel(ctx, eventID, "match outcome "+decision.Label+" below confidence threshold")
+			return err
+		}
+	case decision.Label == "win":
+		result.OptionID = OptionYes
+	case decision.Label == "loss":
+		result.OptionID = OptionNo
+	case a.cfg.UndecidedPolicy == UndecidedRefund:
+		_, err := a.events.Cancel(ctx, eventID, "match outcome "+decision.Label)
+		return err
+	}
+	_, err = a.events.RecordResult(ctx, eventID, result)
+	return err
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+func decision(stage, label string) streamers.LLMDecision {
+	return streamers.LLMDecision{StreamerID: "s-1", Stage: stage, Label: label, Confidence: 0.9}
+}
+
+func TestAutomatorOpensAndSettlesMatchEvent(t *testing.T) {
+	svc := NewService(nil)
+	automator := NewAutomator(svc, nil, AutomationConfig{VoteWindow: time.Minute, CostPerVote: 5})
+	ctx := context.Background()
+
+	automator.ObserveDecision(ctx, decision("stage_c", "pregame"))
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	live := liveEvents(t, svc, "s-1")
+	if len(live) != 1 || live[0].Title != AutoMatchTitle || live[0].CostPerVote != 5 {
+		t.Fatalf("expected a single auto event, got %+v", live)
+	}
+	eventID := live[0].ID
+
+	automator.ObserveDecision(ctx, decision("stage_d", "win"))
+	if event, _ := svc.Get(ctx, eventID); event.Result != nil {
+		t.Fatal("expected stage D to be ignored before the match finished")
+	}
+
+	automator.ObserveDecision(ctx, decision("stage_c", "finished"))
+	automator.ObserveDecision(ctx, decision("stage_d", "win"))
+	event, err := svc.Get(ctx, eventID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if event.State != StateClosed || event.Result == nil || event.Result.OptionID != OptionYes {
+		t.Fatalf("expected settled event, got %+v", event)
+	}
+}
+
+func TestAutomatorUndecidedPolicies(t *testing.T) {
+	tests := []struct {
+		policy string
+		state  string
+	}{
+		{policy: UndecidedRefund, state: StateCancelled},
+		{policy: UndecidedReview, state: StateClosed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			svc := NewService(nil)
+			automator := NewAutomator(svc, nil, AutomationConfig{UndecidedPolicy: tc.policy})
+			ctx := context.Background()
+
+			automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+			eventID := liveEvents(t, svc, "s-1")[0].ID
+			automator.ObserveDecision(ctx, decision("stage_c", "finished"))
+			automator.ObserveDecision(ctx, decision("stage_d", "draw"))
+
+			event, _ := svc.Get(ctx, eventID)
+			if event.State != tc.state {
+				t.Fatalf("state = %q, want %q", event.State, tc.state)
+			}
+			if tc.policy == UndecidedReview && (event.Result == nil || !event.Result.NeedsReview) {
+				t.Fatalf("expected result flagged for review, got %+v", event.Result)
+			}
+		})
+	}
+}
+
+func TestAutomatorOpensNewEventAfterPreviousIsSettled(t *testing.T) {
+	svc := NewService(nil)
+	automator := NewAutomator(svc, nil, AutomationConfig{VoteWindow: time.Minute})
+	ctx := context.Background()
+
+	// Stage C never reported the first match finished; a pregame starts over.
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	first := liveEvents(t, svc, "s-1")[0].ID
+	automator.ObserveDecision(ctx, decision("stage_c", "pregame"))
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	live := liveEvents(t, svc, "s-1")
+	if len(live) != 2 {
+		t.Fatalf("expected a second event after pregame, got %+v", live)
+	}
+
+	// An admin cancels the current event and Stage C flickers.
+	var second string
+	for _, event := range live {
+		if event.ID != first {
+			second = event.ID
+		}
+	}
+	if _, err := svc.Cancel(ctx, second, "stream glitch"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	automator.ObserveDecision(ctx, decision("stage_c", "unknown"))
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	if live := liveEvents(t, svc, "s-1"); len(live) != 2 {
+		t.Fatalf("expected a replacement for the cancelled event, got %+v", live)
+	}
+
+	// A flicker while the tracked event is still open reuses it.
+	automator.ObserveDecision(ctx, decision("stage_c", "unknown"))
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	if live := liveEvents(t, svc, "s-1"); len(live) != 2 {
+		t.Fatalf("expected the open event to be reused, got %+v", live)
+	}
+}
+
+func TestAutomatorSendsLowConfidenceResultsToUndecidedPolicy(t *testing.T) {
+	svc := NewService(nil)
+	automator := NewAutomator(svc, nil, AutomationConfig{UndecidedPolicy: UndecidedReview, MinConfidence: 0.7})
+	ctx := context.Background()
+
+	automator.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	eventID := liveEvents(t, svc, "s-1")[0].ID
+	automator.ObserveDecision(ctx, decision("stage_c", "finished"))
+	guess := decision("stage_d", "win")
+	guess.Confidence = 0.2
+	automator.ObserveDecision(ctx, guess)
+
+	event, _ := svc.Get(ctx, eventID)
+	if event.Result == nil || event.Result.OptionID != "" || !event.Result.NeedsReview || event.Result.Outcome != "win" {
+		t.Fatalf("expected a low-confidence win flagged for review, got %+v", event.Result)
+	}
+}
+
+func TestAutomatorResumesMatchAfterRestart(t *testing.T) {
+	svc := NewService(nil)
+	ctx := context.Background()
+	NewAutomator(svc, nil, AutomationConfig{}).ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	eventID := liveEvents(t, svc, "s-1")[0].ID
+	if _, err := svc.Close(ctx, eventID); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	restarted := NewAutomator(svc, nil, AutomationConfig{})
+	restarted.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	if live := liveEvents(t, svc, "s-1"); len(live) != 0 {
+		t.Fatalf("expected the running match's event to be reused, got %+v", live)
+	}
+	restarted.ObserveDecision(ctx, decision("stage_c", "finished"))
+	restarted.ObserveDecision(ctx, decision("stage_d", "loss"))
+	event, _ := svc.Get(ctx, eventID)
+	if event.Result == nil || event.Result.OptionID != OptionNo {
+		t.Fatalf("expected the event settled after the restart, got %+v", event)
+	}
+
+	restarted.ObserveDecision(ctx, decision("stage_c", "in_progress"))
+	if live := liveEvents(t, svc, "s-1"); len(live) != 1 {
+		t.Fatalf("expected the next match to open a new event, got %+v", live)
+	}
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Lease serializes work on a key across replicas.
+type Lease interface {
+	TryLock(key string, ttl time.Duration) bool
+	Unlock(key string)
+}
+
+type CloserConfig struct {
+	Interval time.Duration
+	LeaseTTL time.Duration
+}
+
+// Closer closes live events once their closesAt passes. Each event is closed
+// under a lease and through the live -> closed transition, so only one replica
+// closes it and publishes EVENT_CLOSED.
+type Closer struct {
+	events   *Service
+	lease    Lease
+	logger   *zap.Logger
+	interval time.Duration
+	leaseTTL time.Duration
+	nowFn    func() time.Time
+}
+
+func NewCloser(events *Service, lease Lease, logger *zap.Logger, cfg CloserConfig) *Closer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 30 * time.Second
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Closer{
+		events:   events,
+		lease:    lease,
+		logger:   logger,
+		interval: cfg.Interval,
+		leaseTTL: cfg.LeaseTTL,
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Run closes expired events every interval until ctx is cancelled.
+func (c *Closer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			c.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce closes every expired event this replica wins the lease for and
+// returns how many it closed.
+func (c *Closer) RunOnce(ctx context.Context) int {
+	expired, err := c.events.ListExpired(ctx, c.nowFn())
+	if err != nil {
+		c.logger.Warn("failed to list expired events", zap.Error(err))
+		return 0
+	}
+	closed := 0
+	for _, event := range expired {
+		key := "events:close:" + event.ID
+		if !c.lease.TryLock(key, c.leaseTTL) {
+			continue
+		}
+		_, err := c.events.Close(ctx, event.ID)
+		c.lease.Unlock(key)
+		switch {
+		case err == nil:
+			closed++
+		case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrNotFound):
+			// Closed or cancelled concurrently.
+		default:
+			c.logger.Warn("failed to close expired event", zap.String("event_id", event.ID), zap.Error(err))
+		}
+	}
+	return closed
+}
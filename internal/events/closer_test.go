@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/media"
+	"github.com/funpot/funpot-go-core/internal/realtime"
+)
+
+func TestCloserClosesExpiredEventsOnce(t *testing.T) {
+	ctx := context.Background()
+	publisher := realtime.NewInMemoryPublisher()
+	svc := NewService(nil)
+	gameID := "g-1"
+	svc.WithPublisher(publisher, nil)
+
+	event, err := svc.Create(ctx, CreateRequest{
+		StreamerID: "s-1",
+		GameID:     &gameID,
+		Title:      "Will it happen?",
+		Options:    []Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	lease := media.NewInMemoryLocker()
+	closer := NewCloser(svc, lease, nil, CloserConfig{})
+	if closed := closer.RunOnce(ctx); closed != 0 {
+		t.Fatalf("expected open event to stay live, closed %d", closed)
+	}
+
+	closer.nowFn = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	lease.TryLock("events:close:"+event.ID, time.Minute)
+	if closed := closer.RunOnce(ctx); closed != 0 {
+		t.Fatalf("expected leased event to be skipped, closed %d", closed)
+	}
+	lease.Unlock("events:close:" + event.ID)
+
+	if closed := closer.RunOnce(ctx); closed != 1 {
+		t.Fatalf("expected 1 closed event, got %d", closed)
+	}
+	if closed := closer.RunOnce(ctx); closed != 0 {
+		t.Fatalf("expected no events on second pass, got %d", closed)
+	}
+
+	messages := publisher.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected EVENT_CLOSED on streamer and game channels, got %+v", messages)
+	}
+	if messages[0].Channel != realtime.StreamerChannel("s-1") || messages[0].Message.Type != realtime.TypeEventClosed {
+		t.Fatalf("unexpected message %+v", messages[0])
+	}
+	if messages[1].Channel != realtime.GameChannel("g-1") {
+		t.Fatalf("unexpected message %+v", messages[1])
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxIngestBatch caps the number of events accepted in one worker batch.
+const MaxIngestBatch = 50
+
+// IngestRequest is a batch of worker-generated events for one streamer.
+type IngestRequest struct {
+	StreamerID   string
+	GameID       *string
+	SourceClipID string
+	Events       []IngestItem
+}
+
+type IngestItem struct {
+	ExternalID     string
+	Title          string
+	Options        []Option
+	ValidForSec    int
+	Confidence     float64
+	PromptVersions PromptVersions
+}
+
+type IngestRejection struct {
+	ExternalID string `json:"externalId"`
+	Reason     string `json:"reason"`
+}
+
+type IngestResult struct {
+	Accepted        int               `json:"accepted"`
+	CreatedEventIDs []string          `json:"createdEventIds"`
+	Rejected        []IngestRejection `json:"rejected"`
+}
+
+// Ingest validates and stores each item independently; invalid or duplicate
+// items are rejected without failing the rest of the batch.
+func (s *Service) Ingest(ctx context.Context, req IngestRequest) (IngestResult, error) {
+	if strings.TrimSpace(req.StreamerID) == "" {
+		return IngestResult{}, ErrStreamerIDRequired
+	}
+	if len(req.Events) == 0 || len(req.Events) > MaxIngestBatch {
+		return IngestResult{}, errors.New("events must contain between 1 and 50 items")
+	}
+
+	result := IngestResult{CreatedEventIDs: []string{}, Rejected: []IngestRejection{}}
+	now := s.nowFn()
+	for _, item := range req.Events {
+		externalID := strings.TrimSpace(item.ExternalID)
+		reject := func(reason string) {
+			result.Rejected = append(result.Rejected, IngestRejection{ExternalID: externalID, Reason: reason})
+		}
+		switch {
+		case externalID == "":
+			reject("externalId is required")
+			continue
+		case item.ValidForSec <= 0:
+			reject("validForSec must be greater than 0")
+			continue
+		case item.Confidence < 0 || item.Confidence > 1:
+			reject("confidence must be between 0 and 1")
+			continue
+		}
+
+		event, err := s.Create(ctx, CreateRequest{
+			StreamerID:     req.StreamerID,
+			GameID:         req.GameID,
+			Title:          item.Title,
+			Options:        item.Options,
+			ClosesAt:       now.Add(time.Duration(item.ValidForSec) * time.Second),
+			CostPerVote:    s.defaultCostPerVote,
+			ExternalID:     externalID,
+			SourceClipID:   strings.TrimSpace(req.SourceClipID),
+			Confidence:     item.Confidence,
+			PromptVersions: item.PromptVersions,
+		})
+		if err != nil {
+			reject(err.Error())
+			continue
+		}
+		result.Accepted++
+		result.CreatedEventIDs = append(result.CreatedEventIDs, event.ID)
+	}
+	return result, nil
+}
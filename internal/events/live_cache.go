@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+type LiveCacheConfig struct {
+	TTL       time.Duration
+	KeyPrefix string
+}
+
+// liveCache is a read-through cache of the live events per streamer.
+// Concurrent misses for a streamer share one load, and Redis failures fall
+// back to the repository.
+type liveCache struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	ttl       time.Duration
+	group     singleflight.Group
+	logger    *zap.Logger
+}
+
+// WithLiveCache caches ListLiveByStreamer results in Redis for cfg.TTL. A nil
+// client only coalesces concurrent loads.
+func (s *Service) WithLiveCache(client redis.UniversalClient, cfg LiveCacheConfig) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 500 * time.Millisecond
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "funpot:events:live"
+	}
+	s.liveCache = &liveCache{client: client, keyPrefix: cfg.KeyPrefix, ttl: cfg.TTL, logger: s.logger}
+}
+
+func (c *liveCache) key(streamerID string) string {
+	return c.keyPrefix + ":" + streamerID
+}
+
+func (c *liveCache) list(ctx context.Context, streamerID string, load func(context.Context, string) ([]LiveEvent, error)) ([]LiveEvent, error) {
+	value, err, _ := c.group.Do(streamerID, func() (any, error) {
+		// Waiters share this load, so it must not fail when the first caller goes away.
+		ctx := context.WithoutCancel(ctx)
+		if c.client != nil {
+			raw, err := c.client.Get(ctx, c.key(streamerID)).Bytes()
+			switch {
+			case err == nil:
+				var items []LiveEvent
+				if err := json.Unmarshal(raw, &items); err == nil {
+					// Only the public fields are cached; restore the owner.
+					for i := range items {
+						items[i].StreamerID = streamerID
+					}
+					return items, nil
+				}
+			case !errors.Is(err, redis.Nil):
+				c.logger.Warn("live events cache unavailable", zap.String("streamer_id", streamerID), zap.Error(err))
+				return load(ctx, streamerID)
+			}
+		}
+
+		items, err := load(ctx, streamerID)
+		if err != nil {
+			return nil, err
+		}
+		if c.client != nil {
+			if raw, err := json.Marshal(items); err == nil {
+				if err := c.client.Set(ctx, c.key(streamerID), raw, c.ttl).Err(); err != nil {
+					c.logger.Warn("failed to cache live events", zap.String("streamer_id", streamerID), zap.Error(err))
+				}
+			}
+		}
+		return items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	shared := value.([]LiveEvent)
+	items := make([]LiveEvent, len(shared))
+	copy(items, shared)
+	return items, nil
+}
+
+func (c *liveCache) invalidate(ctx context.Context, streamerID string) {
+	if c.client == nil {
+		return
+	}
+	if err := c.client.Del(ctx, c.key(streamerID)).Err(); err != nil {
+		c.logger.Warn("failed to invalidate live events cache", zap.String("streamer_id", streamerID), zap.Error(err))
+	}
+}
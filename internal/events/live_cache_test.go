@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingRepository counts live list loads that reach the repository.
+type countingRepository struct {
+	Repository
+	loads atomic.Int32
+}
+
+func (r *countingRepository) ListByStreamer(ctx context.Context, streamerID, state string) ([]LiveEvent, error) {
+	r.loads.Add(1)
+	return r.Repository.ListByStreamer(ctx, streamerID, state)
+}
+
+func TestLiveCacheReadThroughAndFallback(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("run miniredis: %v", err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	ctx := context.Background()
+	repo := &countingRepository{Repository: NewInMemoryRepository([]LiveEvent{{ID: "evt-1", StreamerID: "s-1", Title: "t"}})}
+	svc := NewService(nil)
+	svc.WithRepository(repo)
+	svc.WithLiveCache(client, LiveCacheConfig{TTL: time.Minute, KeyPrefix: "test"})
+
+	for i := 0; i < 3; i++ {
+		items := liveEvents(t, svc, "s-1")
+		if len(items) != 1 || items[0].ID != "evt-1" || items[0].StreamerID != "s-1" {
+			t.Fatalf("unexpected live events %+v", items)
+		}
+	}
+	if loads := repo.loads.Load(); loads != 1 {
+		t.Fatalf("expected cached reads after the first load, got %d loads", loads)
+	}
+	if !mr.Exists("test:s-1") {
+		t.Fatal("expected live events to be cached in redis")
+	}
+
+	if _, err := svc.Close(ctx, "evt-1"); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if items := liveEvents(t, svc, "s-1"); len(items) != 0 {
+		t.Fatalf("expected closing to invalidate the cache, got %+v", items)
+	}
+
+	mr.Close()
+	before := repo.loads.Load()
+	if items := liveEvents(t, svc, "s-1"); len(items) != 0 {
+		t.Fatalf("unexpected live events %+v", items)
+	}
+	if repo.loads.Load() != before+1 {
+		t.Fatal("expected repository fallback while redis is down")
+	}
+}
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InMemoryRepository stores events in memory for development and tests.
+type InMemoryRepository struct {
+	mu      sync.RWMutex
+	items   []LiveEvent
+	stale   map[string]struct{}
+	counter int64
+}
+
+// NewInMemoryRepository constructs a repository holding the seed events.
+func NewInMemoryRepository(seed []LiveEvent) *InMemoryRepository {
+	items := make([]LiveEvent, len(seed))
+	copy(items, seed)
+	for i := range items {
+		if items[i].State == "" {
+			items[i].State = StateLive
+		}
+	}
+	return &InMemoryRepository{items: items, stale: make(map[string]struct{})}
+}
+
+func (r *InMemoryRepository) Create(_ context.Context, event LiveEvent) (LiveEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.ExternalID != "" {
+		for _, existing := range r.items {
+			if existing.StreamerID == event.StreamerID && existing.ExternalID == event.ExternalID {
+				return LiveEvent{}, ErrDuplicateEvent
+			}
+		}
+	}
+	if event.ID == "" {
+		r.counter++
+		event.ID = fmt.Sprintf("evt_%d", r.counter)
+	}
+	r.items = append(r.items, event)
+	return event, nil
+}
+
+func (r *InMemoryRepository) Get(_ context.Context, id string) (LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idx := r.indexLocked(id)
+	if idx < 0 {
+		return LiveEvent{}, ErrNotFound
+	}
+	return r.items[idx], nil
+}
+
+func (r *InMemoryRepository) Update(_ context.Context, event LiveEvent, fromState string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.indexLocked(event.ID)
+	if idx < 0 {
+		return ErrNotFound
+	}
+	if r.items[idx].State != fromState || r.items[idx].Version != event.Version {
+		return ErrInvalidTransition
+	}
+	event.Version++
+	r.items[idx] = event
+	return nil
+}
+
+func (r *InMemoryRepository) ListByStreamer(_ context.Context, streamerID, state string) ([]LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]LiveEvent, 0)
+	for _, item := range r.items {
+		if item.StreamerID == streamerID && item.State == state {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) ListByState(_ context.Context, state string) ([]LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]LiveEvent, 0)
+	for _, item := range r.items {
+		if item.State == state {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) UpdateTotals(_ context.Context, id string, totals map[string]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.indexLocked(id)
+	if idx < 0 {
+		return ErrNotFound
+	}
+	copied := make(map[string]int, len(totals))
+	for optionID, count := range totals {
+		copied[optionID] = count
+	}
+	r.items[idx].Totals = copied
+	return nil
+}
+
+func (r *InMemoryRepository) MarkTotalsStale(_ context.Context, id string, stale bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.indexLocked(id) < 0 {
+		return ErrNotFound
+	}
+	if stale {
+		r.stale[id] = struct{}{}
+	} else {
+		delete(r.stale, id)
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) ListTotalsStale(_ context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.stale))
+	for _, item := range r.items {
+		if _, ok := r.stale[item.ID]; ok {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *InMemoryRepository) ListExpired(_ context.Context, now time.Time) ([]LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]LiveEvent, 0)
+	for _, item := range r.items {
+		if item.State == StateLive && !item.ClosesAt.IsZero() && !now.Before(item.ClosesAt) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) ListUnsettled(_ context.Context) ([]LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]LiveEvent, 0)
+	for _, item := range r.items {
+		if item.NeedsSettlement() {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) MarkSettled(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.indexLocked(id)
+	if idx < 0 {
+		return ErrNotFound
+	}
+	if r.items[idx].SettledAt == nil {
+		r.items[idx].SettledAt = &at
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) FlagSettlement(_ context.Context, id, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := r.indexLocked(id)
+	if idx < 0 {
+		return ErrNotFound
+	}
+	r.items[idx].SettlementError = reason
+	return nil
+}
+
+func (r *InMemoryRepository) ListSettlementFlagged(_ context.Context) ([]LiveEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]LiveEvent, 0)
+	for _, item := range r.items {
+		if item.SettledAt == nil && item.SettlementError != "" {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) indexLocked(id string) int {
+	id = strings.TrimSpace(id)
+	for i := range r.items {
+		if r.items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
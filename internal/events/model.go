@@ -1,5 +1,47 @@
 package events
 
+import (
+	"errors"
+	"time"
+)
+
+// Event states follow docs/erd.md. Voting is accepted only while an event is
+// live and before ClosesAt; closed events may still receive their result.
+const (
+	StateLive      = "live"
+	StateClosed    = "closed"
+	StateCancelled = "cancelled"
+)
+
+var transitions = map[string][]string{
+	StateLive:   {StateClosed, StateCancelled},
+	StateClosed: {StateCancelled},
+}
+
+// CanTransition reports whether an event may move from one state to another.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrNotFound           = errors.New("event not found")
+	ErrInvalidTransition  = errors.New("event state transition is not allowed")
+	ErrResultFinal        = errors.New("event already has a final result")
+	ErrEventExpired       = errors.New("event voting window has ended")
+	ErrStreamerIDRequired = errors.New("streamerId is required")
+	ErrTitleRequired      = errors.New("title is required")
+	ErrInvalidOptions     = errors.New("at least two options with unique ids are required")
+	ErrInvalidClosesAt    = errors.New("closesAt must be in the future")
+	ErrInvalidCostPerVote = errors.New("costPerVote must be greater than or equal to 0")
+	ErrUnknownOption      = errors.New("option does not belong to event")
+	ErrDuplicateEvent     = errors.New("event with this externalId already exists for streamer")
+)
+
 type Option struct {
 	ID    string `json:"id"`
 	Label string `json:"label"`
@@ -9,14 +51,92 @@ type UserVote struct {
 	OptionID string `json:"optionId"`
 }
 
+// Result is the outcome recorded for an event. OptionID is empty when the
+// outcome could not be mapped to an option and the event awaits manual review.
+type Result struct {
+	OptionID    string  `json:"optionId,omitempty"`
+	Outcome     string  `json:"outcome"`
+	Confidence  float64 `json:"confidence"`
+	NeedsReview bool    `json:"needsReview,omitempty"`
+}
+
 type LiveEvent struct {
-	ID          string         `json:"id"`
-	GameID      *string        `json:"gameId"`
-	StreamerID  string         `json:"-"`
-	Title       string         `json:"title"`
-	Options     []Option       `json:"options"`
-	ClosesAt    string         `json:"closesAt"`
-	Totals      map[string]int `json:"totals"`
-	UserVote    *UserVote      `json:"userVote,omitempty"`
-	CostPerVote int            `json:"costPerVote"`
+	ID         string         `json:"id"`
+	GameID     *string        `json:"gameId"`
+	StreamerID string         `json:"-"`
+	Title      string         `json:"title"`
+	Options    []Option       `json:"options"`
+	State      string         `json:"state"`
+	ClosesAt   time.Time      `json:"closesAt"`
+	Totals     map[string]int `json:"totals"`
+	// FinalTotals is the snapshot of Totals taken when voting stopped.
+	FinalTotals  map[string]int `json:"finalTotals,omitempty"`
+	UserVote     *UserVote      `json:"userVote,omitempty"`
+	CostPerVote  int            `json:"costPerVote"`
+	Result       *Result        `json:"result,omitempty"`
+	CancelReason string         `json:"cancelReason,omitempty"`
+	// SettledAt is set once payouts or refunds for the event were posted.
+	SettledAt *time.Time `json:"settledAt,omitempty"`
+	// SettlementError explains why settlement stopped and needs an admin.
+	SettlementError string    `json:"settlementError,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	// Version counts stored updates; Update only applies to the version that
+	// was read.
+	Version int64 `json:"-"`
+	// Provenance of worker-generated events.
+	ExternalID     string         `json:"-"`
+	SourceClipID   string         `json:"-"`
+	Confidence     float64        `json:"-"`
+	PromptVersions PromptVersions `json:"-"`
+}
+
+// PromptVersions records which prompt versions produced a worker event.
+type PromptVersions struct {
+	Session string `json:"session,omitempty"`
+	Game    string `json:"game,omitempty"`
+	PerClip string `json:"perClip,omitempty"`
+}
+
+// IsOpen reports whether the event accepts votes at now. A zero ClosesAt
+// means the event stays open until closed explicitly.
+func (e LiveEvent) IsOpen(now time.Time) bool {
+	return e.State == StateLive && (e.ClosesAt.IsZero() || now.Before(e.ClosesAt))
+}
+
+// NeedsSettlement reports whether the event was resolved or cancelled and its
+// payouts or refunds were not posted yet. Events flagged for an admin wait
+// until the flag is cleared.
+func (e LiveEvent) NeedsSettlement() bool {
+	if e.SettledAt != nil || e.SettlementError != "" {
+		return false
+	}
+	return e.State == StateCancelled || (e.State == StateClosed && e.HasFinalResult())
+}
+
+// HasFinalResult reports whether a result that does not need review is recorded.
+func (e LiveEvent) HasFinalResult() bool {
+	return e.Result != nil && !e.Result.NeedsReview
+}
+
+// UpdateRequest changes mutable fields of a live event; nil fields are kept.
+// Options are immutable because votes reference them.
+type UpdateRequest struct {
+	Title       *string
+	ClosesAt    *time.Time
+	CostPerVote *int
+}
+
+type CreateRequest struct {
+	StreamerID  string
+	GameID      *string
+	Title       string
+	Options     []Option
+	ClosesAt    time.Time
+	CostPerVote int
+	// ExternalID makes creation idempotent per streamer when set.
+	ExternalID     string
+	SourceClipID   string
+	Confidence     float64
+	PromptVersions PromptVersions
 }
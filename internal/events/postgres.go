@@ -0,0 +1,415 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const eventColumns = `id, streamer_id, game_id, external_id, title, options_json, state, closes_at, cost_per_vote, totals_json, final_totals_json, result_json, cancel_reason, source_clip_id, prompt_versions_json, confidence, settled_at, settlement_error, created_at, updated_at, version`
+
+// PostgresRepository persists events in PostgreSQL with options, totals,
+// results and prompt versions stored as JSONB.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository constructs a repository backed by PostgreSQL.
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Create inserts a new event. A conflicting (streamer_id, external_id) pair
+// is reported as ErrDuplicateEvent.
+func (r *PostgresRepository) Create(ctx context.Context, event LiveEvent) (LiveEvent, error) {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	encoded, err := encodeEvent(event)
+	if err != nil {
+		return LiveEvent{}, err
+	}
+
+	query := `INSERT INTO events (` + eventColumns + `)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+ON CONFLICT DO NOTHING`
+	result, err := r.db.ExecContext(ctx, query,
+		event.ID,
+		event.StreamerID,
+		nullString(event.GameID),
+		event.ExternalID,
+		event.Title,
+		encoded.options,
+		event.State,
+		encoded.closesAt,
+		event.CostPerVote,
+		encoded.totals,
+		encoded.finalTotals,
+		encoded.result,
+		event.CancelReason,
+		sql.NullString{String: event.SourceClipID, Valid: event.SourceClipID != ""},
+		encoded.promptVersions,
+		event.Confidence,
+		nullTime(event.SettledAt),
+		event.SettlementError,
+		event.CreatedAt,
+		event.UpdatedAt,
+		event.Version,
+	)
+	if err != nil {
+		return LiveEvent{}, fmt.Errorf("insert event: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return LiveEvent{}, err
+	}
+	if rowsAffected == 0 {
+		return LiveEvent{}, ErrDuplicateEvent
+	}
+	return event, nil
+}
+
+// Get returns an event by ID.
+func (r *PostgresRepository) Get(ctx context.Context, id string) (LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id = $1`
+	event, err := scanEvent(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return LiveEvent{}, ErrNotFound
+		}
+		return LiveEvent{}, fmt.Errorf("select event: %w", err)
+	}
+	return event, nil
+}
+
+// Update overwrites the mutable columns of an event still in fromState and
+// at event.Version, so concurrent writers cannot overwrite each other.
+func (r *PostgresRepository) Update(ctx context.Context, event LiveEvent, fromState string) error {
+	encoded, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		UPDATE events
+		SET title = $2,
+		    state = $3,
+		    closes_at = $4,
+		    cost_per_vote = $5,
+		    totals_json = $6,
+		    final_totals_json = $7,
+		    result_json = $8,
+		    cancel_reason = $9,
+		    updated_at = $10,
+		    version = version + 1
+		WHERE id = $1 AND state = $11 AND version = $12
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		event.ID,
+		event.Title,
+		event.State,
+		encoded.closesAt,
+		event.CostPerVote,
+		encoded.totals,
+		encoded.finalTotals,
+		encoded.result,
+		event.CancelReason,
+		event.UpdatedAt,
+		fromState,
+		event.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("update event: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.Get(ctx, event.ID); err != nil {
+			return err
+		}
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// ListByStreamer returns the streamer's events in state; served by the
+// (streamer_id, state) index.
+func (r *PostgresRepository) ListByStreamer(ctx context.Context, streamerID, state string) ([]LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE streamer_id = $1 AND state = $2 ORDER BY created_at, id`
+	return r.list(ctx, query, streamerID, state)
+}
+
+// ListByState returns every event in state.
+func (r *PostgresRepository) ListByState(ctx context.Context, state string) ([]LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE state = $1 ORDER BY created_at, id`
+	return r.list(ctx, query, state)
+}
+
+// UpdateTotals overwrites totals_json with a tally snapshot.
+func (r *PostgresRepository) UpdateTotals(ctx context.Context, id string, totals map[string]int) error {
+	encoded, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("encode totals: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE events SET totals_json = $2 WHERE id = $1`, id, encoded)
+	if err != nil {
+		return fmt.Errorf("update event totals: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkTotalsStale sets totals_stale, which reconcile-totals clears after
+// recounting the event.
+func (r *PostgresRepository) MarkTotalsStale(ctx context.Context, id string, stale bool) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE events SET totals_stale = $2 WHERE id = $1`, id, stale)
+	if err != nil {
+		return fmt.Errorf("mark event totals stale: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListTotalsStale returns the IDs of events whose totals must be recounted.
+func (r *PostgresRepository) ListTotalsStale(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM events WHERE totals_stale ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list stale event totals: %w", err)
+	}
+	defer rows.Close()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan stale event totals: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListUnsettled returns closed events whose result names an option and
+// cancelled events whose payouts or refunds were not posted yet.
+func (r *PostgresRepository) ListUnsettled(ctx context.Context) ([]LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE settled_at IS NULL AND settlement_error = '' AND (state = 'cancelled' OR (state = 'closed' AND result_json ->> 'optionId' <> '')) ORDER BY updated_at, id`
+	return r.list(ctx, query)
+}
+
+// MarkSettled records when the event's payouts or refunds were posted.
+func (r *PostgresRepository) MarkSettled(ctx context.Context, id string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE events SET settled_at = $2 WHERE id = $1 AND settled_at IS NULL`, id, at)
+	if err != nil {
+		return fmt.Errorf("mark event settled: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlagSettlement stores reason as the event's settlement error; an empty
+// reason clears it.
+func (r *PostgresRepository) FlagSettlement(ctx context.Context, id, reason string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE events SET settlement_error = $2 WHERE id = $1`, id, reason)
+	if err != nil {
+		return fmt.Errorf("flag event settlement: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListSettlementFlagged returns unsettled events whose settlement needs an
+// admin, oldest first.
+func (r *PostgresRepository) ListSettlementFlagged(ctx context.Context) ([]LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE settled_at IS NULL AND settlement_error <> '' ORDER BY updated_at, id`
+	return r.list(ctx, query)
+}
+
+// ListExpired returns live events whose closes_at has passed.
+func (r *PostgresRepository) ListExpired(ctx context.Context, now time.Time) ([]LiveEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE state = 'live' AND closes_at <= $1 ORDER BY closes_at, id`
+	return r.list(ctx, query, now)
+}
+
+func (r *PostgresRepository) list(ctx context.Context, query string, args ...any) ([]LiveEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select events: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]LiveEvent, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan event: %w", err)
+		}
+		result = append(result, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate events: %w", err)
+	}
+	return result, nil
+}
+
+// encodedEvent holds the JSONB and nullable column values of an event.
+type encodedEvent struct {
+	options        []byte
+	totals         []byte
+	promptVersions []byte
+	finalTotals    any
+	result         any
+	closesAt       sql.NullTime
+}
+
+func encodeEvent(event LiveEvent) (encodedEvent, error) {
+	var (
+		encoded encodedEvent
+		err     error
+	)
+	if encoded.options, err = json.Marshal(event.Options); err != nil {
+		return encodedEvent{}, fmt.Errorf("encode options: %w", err)
+	}
+	if encoded.totals, err = json.Marshal(event.Totals); err != nil {
+		return encodedEvent{}, fmt.Errorf("encode totals: %w", err)
+	}
+	if encoded.promptVersions, err = json.Marshal(event.PromptVersions); err != nil {
+		return encodedEvent{}, fmt.Errorf("encode prompt versions: %w", err)
+	}
+	if event.FinalTotals != nil {
+		if encoded.finalTotals, err = json.Marshal(event.FinalTotals); err != nil {
+			return encodedEvent{}, fmt.Errorf("encode final totals: %w", err)
+		}
+	}
+	if event.Result != nil {
+		if encoded.result, err = json.Marshal(event.Result); err != nil {
+			return encodedEvent{}, fmt.Errorf("encode result: %w", err)
+		}
+	}
+	if !event.ClosesAt.IsZero() {
+		encoded.closesAt = sql.NullTime{Time: event.ClosesAt, Valid: true}
+	}
+	return encoded, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row rowScanner) (LiveEvent, error) {
+	var (
+		event                                                LiveEvent
+		gameID, sourceClipID                                 sql.NullString
+		closesAt, settledAt                                  sql.NullTime
+		options, totals, finalTotals, result, promptVersions []byte
+	)
+	if err := row.Scan(
+		&event.ID,
+		&event.StreamerID,
+		&gameID,
+		&event.ExternalID,
+		&event.Title,
+		&options,
+		&event.State,
+		&closesAt,
+		&event.CostPerVote,
+		&totals,
+		&finalTotals,
+		&result,
+		&event.CancelReason,
+		&sourceClipID,
+		&promptVersions,
+		&event.Confidence,
+		&settledAt,
+		&event.SettlementError,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+		&event.Version,
+	); err != nil {
+		return LiveEvent{}, err
+	}
+
+	if gameID.Valid {
+		event.GameID = &gameID.String
+	}
+	event.SourceClipID = sourceClipID.String
+	if closesAt.Valid {
+		event.ClosesAt = closesAt.Time.UTC()
+	}
+	if settledAt.Valid {
+		at := settledAt.Time.UTC()
+		event.SettledAt = &at
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	event.UpdatedAt = event.UpdatedAt.UTC()
+
+	if err := json.Unmarshal(options, &event.Options); err != nil {
+		return LiveEvent{}, fmt.Errorf("decode options: %w", err)
+	}
+	if err := json.Unmarshal(totals, &event.Totals); err != nil {
+		return LiveEvent{}, fmt.Errorf("decode totals: %w", err)
+	}
+	if len(promptVersions) > 0 {
+		if err := json.Unmarshal(promptVersions, &event.PromptVersions); err != nil {
+			return LiveEvent{}, fmt.Errorf("decode prompt versions: %w", err)
+		}
+	}
+	if len(finalTotals) > 0 {
+		if err := json.Unmarshal(finalTotals, &event.FinalTotals); err != nil {
+			return LiveEvent{}, fmt.Errorf("decode final totals: %w", err)
+		}
+	}
+	if len(result) > 0 {
+		event.Result = &Result{}
+		if err := json.Unmarshal(result, event.Result); err != nil {
+			return LiveEvent{}, fmt.Errorf("decode result: %w", err)
+		}
+	}
+	return event, nil
+}
+
+func nullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
+
+func nullString(value *string) sql.NullString {
+	if value == nil || *value == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
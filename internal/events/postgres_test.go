@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var eventColumnNames = []string{"id", "streamer_id", "game_id", "external_id", "title", "options_json", "state", "closes_at", "cost_per_vote", "totals_json", "final_totals_json", "result_json", "cancel_reason", "source_clip_id", "prompt_versions_json", "confidence", "settled_at", "settlement_error", "created_at", "updated_at", "version"}
+
+func TestPostgresRepository_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	rows := sqlmock.NewRows(eventColumnNames).AddRow(
+		"evt-1", "s-1", "g-1", "ext-1", "Will it happen?",
+		[]byte(`[{"id":"yes","label":"Yes"},{"id":"no","label":"No"}]`),
+		StateClosed, now, int64(10),
+		[]byte(`{"yes":3,"no":1}`), []byte(`{"yes":3,"no":1}`), []byte(`{"optionId":"yes","outcome":"win","confidence":0.9}`),
+		"", "clip-1", []byte(`{"session":"v1","perClip":"v3"}`), 0.85, nil, "", now, now, int64(3),
+	)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + eventColumns + " FROM events WHERE id = $1")).
+		WithArgs("evt-1").
+		WillReturnRows(rows)
+
+	event, err := repo.Get(context.Background(), "evt-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.GameID == nil || *event.GameID != "g-1" || len(event.Options) != 2 || event.Totals["yes"] != 3 || event.Version != 3 {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if event.Result == nil || event.Result.OptionID != "yes" || event.PromptVersions.PerClip != "v3" || event.SourceClipID != "clip-1" {
+		t.Fatalf("unexpected JSON columns: %+v", event)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_CreateDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	args := make([]driver.Value, len(eventColumnNames))
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO events (" + eventColumns + ")")).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = repo.Create(context.Background(), LiveEvent{StreamerID: "s-1", ExternalID: "ext-1", State: StateLive})
+	if !errors.Is(err, ErrDuplicateEvent) {
+		t.Fatalf("expected ErrDuplicateEvent, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateStateChanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	event := LiveEvent{ID: "evt-1", StreamerID: "s-1", Title: "t", State: StateClosed, UpdatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE events")).
+		WithArgs("evt-1", "t", StateClosed, sqlmock.AnyArg(), 0, sqlmock.AnyArg(), nil, nil, "", now, StateLive, int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + eventColumns + " FROM events WHERE id = $1")).
+		WithArgs("evt-1").
+		WillReturnRows(sqlmock.NewRows(eventColumnNames).AddRow(
+			"evt-1", "s-1", nil, "", "t", []byte(`[]`), StateCancelled, nil, int64(0),
+			[]byte(`{}`), nil, nil, "", nil, []byte(`{}`), 0.0, nil, "", now, now, int64(1),
+		))
+
+	if err := repo.Update(context.Background(), event, StateLive); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_ListByStreamer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT "+eventColumns+" FROM events WHERE streamer_id = $1 AND state = $2")).
+		WithArgs("s-1", StateLive).
+		WillReturnRows(sqlmock.NewRows(eventColumnNames).AddRow(
+			"evt-1", "s-1", nil, "", "t", []byte(`[{"id":"yes","label":"Yes"},{"id":"no","label":"No"}]`), StateLive, now.Add(time.Minute), int64(10),
+			[]byte(`{"yes":0,"no":0}`), nil, nil, "", nil, []byte(`{}`), 0.0, nil, "", now, now, int64(0),
+		))
+
+	items, err := repo.ListByStreamer(context.Background(), "s-1", StateLive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].GameID != nil || items[0].Result != nil || items[0].CostPerVote != 10 {
+		t.Fatalf("unexpected events: %+v", items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_TotalsStale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE events SET totals_stale = $2 WHERE id = $1`)).
+		WithArgs("evt-1", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM events WHERE totals_stale ORDER BY created_at, id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("evt-1"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE events SET totals_stale = $2 WHERE id = $1`)).
+		WithArgs("missing", true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.MarkTotalsStale(context.Background(), "evt-1", true); err != nil {
+		t.Fatalf("MarkTotalsStale() error = %v", err)
+	}
+	ids, err := repo.ListTotalsStale(context.Background())
+	if err != nil || len(ids) != 1 || ids[0] != "evt-1" {
+		t.Fatalf("ListTotalsStale() = %v, %v", ids, err)
+	}
+	if err := repo.MarkTotalsStale(context.Background(), "missing", true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
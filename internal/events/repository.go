@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// Repository abstracts event persistence.
+type Repository interface {
+	// Create stores a new event, assigning its ID when empty. It returns
+	// ErrDuplicateEvent when the streamer already has the event's ExternalID.
+	Create(ctx context.Context, event LiveEvent) (LiveEvent, error)
+	Get(ctx context.Context, id string) (LiveEvent, error)
+	// Update persists event if it is still in fromState at event.Version,
+	// bumping the version, and returns ErrInvalidTransition when another
+	// writer changed it first.
+	Update(ctx context.Context, event LiveEvent, fromState string) error
+	// ListByStreamer returns the streamer's events in the given state, oldest first.
+	ListByStreamer(ctx context.Context, streamerID, state string) ([]LiveEvent, error)
+	// ListByState returns every event in state, oldest first.
+	ListByState(ctx context.Context, state string) ([]LiveEvent, error)
+	// ListExpired returns live events with a closesAt at or before now.
+	ListExpired(ctx context.Context, now time.Time) ([]LiveEvent, error)
+	// UpdateTotals stores a snapshot of the vote totals.
+	UpdateTotals(ctx context.Context, id string, totals map[string]int) error
+	// MarkTotalsStale records whether the tally missed votes of an event, so
+	// its totals must be recounted from the votes table.
+	MarkTotalsStale(ctx context.Context, id string, stale bool) error
+	// ListTotalsStale returns the IDs of events marked stale.
+	ListTotalsStale(ctx context.Context) ([]string, error)
+	// ListUnsettled returns closed events with a final result and cancelled
+	// events that have no SettledAt and no settlement error, oldest first.
+	ListUnsettled(ctx context.Context) ([]LiveEvent, error)
+	// MarkSettled sets SettledAt once; marking a settled event again is a no-op.
+	MarkSettled(ctx context.Context, id string, at time.Time) error
+	// FlagSettlement records why an event cannot be settled without an admin;
+	// flagged events are left out of ListUnsettled. An empty reason clears it.
+	FlagSettlement(ctx context.Context, id, reason string) error
+	// ListSettlementFlagged returns unsettled events with a settlement error.
+	ListSettlementFlagged(ctx context.Context) ([]LiveEvent, error)
+}
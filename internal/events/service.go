@@ -2,29 +2,513 @@ package events
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/realtime"
 )
 
 type Service struct {
-	mu    sync.RWMutex
-	items []LiveEvent
+	// mu serializes read-modify-write cycles within this process; the
+	// repository guards state changes across replicas.
+	mu                 sync.Mutex
+	repo               Repository
+	defaultCostPerVote int
+	publisher          realtime.Publisher
+	liveCache          *liveCache
+	tally              Tally
+	userVotes          UserVoteSource
+	logger             *zap.Logger
+	nowFn              func() time.Time
+}
+
+// UserVoteSource looks up a user's votes on several events at once.
+type UserVoteSource interface {
+	UserVotes(ctx context.Context, userID string, eventIDs []string) (map[string]UserVote, error)
+}
+
+type closedPayload struct {
+	EventID string       `json:"eventId"`
+	Result  closedResult `json:"result"`
+}
+
+type closedResult struct {
+	OptionID string         `json:"optionId,omitempty"`
+	Totals   map[string]int `json:"totals"`
 }
 
 func NewService(seed []LiveEvent) *Service {
-	items := make([]LiveEvent, len(seed))
-	copy(items, seed)
-	return &Service{items: items}
+	return &Service{
+		repo:   NewInMemoryRepository(seed),
+		logger: zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithRepository replaces the in-memory store, e.g. with PostgresRepository.
+func (s *Service) WithRepository(repo Repository) {
+	s.repo = repo
+}
+
+// WithTally reads live vote totals from tally instead of the repository snapshot.
+func (s *Service) WithTally(tally Tally) {
+	s.tally = tally
+}
+
+// WithUserVotes fills UserVote on live events listed for a user.
+func (s *Service) WithUserVotes(source UserVoteSource) {
+	s.userVotes = source
+}
+
+// WithDefaultCostPerVote sets the vote cost of worker-ingested events.
+func (s *Service) WithDefaultCostPerVote(cost int) {
+	s.defaultCostPerVote = cost
 }
 
-func (s *Service) ListLiveByStreamer(_ context.Context, streamerID string) []LiveEvent {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// WithPublisher broadcasts EVENT_CLOSED to the streamer and game channels
+// whenever a live event is closed.
+func (s *Service) WithPublisher(publisher realtime.Publisher, logger *zap.Logger) {
+	s.publisher = publisher
+	if logger != nil {
+		s.logger = logger
+	}
+}
 
-	result := make([]LiveEvent, 0)
-	for _, item := range s.items {
-		if item.StreamerID == streamerID {
+// ListExpired returns live events whose voting window ended at or before now.
+func (s *Service) ListExpired(ctx context.Context, now time.Time) ([]LiveEvent, error) {
+	return s.repo.ListExpired(ctx, now)
+}
+
+// ListLiveByStreamer returns the streamer's events that still accept votes,
+// served from the live cache when one is configured. When userID is set each
+// event carries that user's vote, looked up in one batch after the cache.
+func (s *Service) ListLiveByStreamer(ctx context.Context, streamerID, userID string) ([]LiveEvent, error) {
+	var (
+		items []LiveEvent
+		err   error
+	)
+	if s.liveCache != nil {
+		items, err = s.liveCache.list(ctx, streamerID, s.listByStreamer)
+	} else {
+		items, err = s.listByStreamer(ctx, streamerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Cached entries may outlive closesAt by up to the cache TTL.
+	now := s.nowFn()
+	result := make([]LiveEvent, 0, len(items))
+	for _, item := range items {
+		if item.IsOpen(now) {
 			result = append(result, item)
 		}
 	}
-	return result
+	s.fillUserVotes(ctx, userID, result)
+	return result, nil
+}
+
+// fillUserVotes sets UserVote on items; the list is still served without
+// them if the lookup fails.
+func (s *Service) fillUserVotes(ctx context.Context, userID string, items []LiveEvent) {
+	userID = strings.TrimSpace(userID)
+	if s.userVotes == nil || userID == "" || len(items) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	picks, err := s.userVotes.UserVotes(ctx, userID, ids)
+	if err != nil {
+		s.logger.Warn("failed to load user votes", zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+	for i := range items {
+		if pick, ok := picks[items[i].ID]; ok {
+			items[i].UserVote = &UserVote{OptionID: pick.OptionID}
+		}
+	}
+}
+
+func (s *Service) listByStreamer(ctx context.Context, streamerID string) ([]LiveEvent, error) {
+	items, err := s.repo.ListByStreamer(ctx, streamerID, StateLive)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		s.fillTotals(ctx, &items[i])
+	}
+	return items, nil
+}
+
+// ListByState returns every event in state with live totals filled in.
+func (s *Service) ListByState(ctx context.Context, state string) ([]LiveEvent, error) {
+	items, err := s.repo.ListByState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		s.fillTotals(ctx, &items[i])
+	}
+	return items, nil
+}
+
+// ListUnsettled returns resolved and cancelled events whose payouts or
+// refunds were not posted yet.
+func (s *Service) ListUnsettled(ctx context.Context) ([]LiveEvent, error) {
+	return s.repo.ListUnsettled(ctx)
+}
+
+// FlagSettlement parks an event's settlement for an admin with reason; an
+// empty reason clears the flag so the settler retries it.
+func (s *Service) FlagSettlement(ctx context.Context, id, reason string) error {
+	return s.repo.FlagSettlement(ctx, strings.TrimSpace(id), strings.TrimSpace(reason))
+}
+
+// ListSettlementFlagged returns unsettled events waiting for an admin.
+func (s *Service) ListSettlementFlagged(ctx context.Context) ([]LiveEvent, error) {
+	return s.repo.ListSettlementFlagged(ctx)
+}
+
+// MarkSettled records that the event's payouts or refunds were posted.
+func (s *Service) MarkSettled(ctx context.Context, id string) error {
+	return s.repo.MarkSettled(ctx, strings.TrimSpace(id), s.nowFn())
+}
+
+func (s *Service) Get(ctx context.Context, id string) (LiveEvent, error) {
+	item, err := s.repo.Get(ctx, strings.TrimSpace(id))
+	if err != nil {
+		return LiveEvent{}, err
+	}
+	s.fillTotals(ctx, &item)
+	return item, nil
+}
+
+// SnapshotTotals persists the tally counters of every event voted on since
+// the previous snapshot and returns how many events were written. Events
+// whose snapshot failed stay claimed and are retried on the next call.
+func (s *Service) SnapshotTotals(ctx context.Context) (int, error) {
+	if s.tally == nil {
+		return 0, nil
+	}
+	ids, err := s.tally.TakeDirty(ctx)
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	acked := make([]string, 0, len(ids))
+	var errs []error
+	for _, id := range ids {
+		totals, err := s.tally.Totals(ctx, id)
+		if err == nil {
+			err = s.repo.UpdateTotals(ctx, id, totals)
+		}
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// Nothing to snapshot into; stop retrying it.
+		case err != nil:
+			errs = append(errs, fmt.Errorf("snapshot totals of %s: %w", id, err))
+			continue
+		default:
+			written++
+		}
+		acked = append(acked, id)
+	}
+	if err := s.tally.AckDirty(ctx, acked...); err != nil {
+		errs = append(errs, fmt.Errorf("ack snapshotted totals: %w", err))
+	}
+	return written, errors.Join(errs...)
+}
+
+// ReconcileTotals replaces the stored and tallied totals of an event, e.g.
+// with counts recomputed from the votes table after a Redis outage. Options
+// without votes are stored as zero.
+func (s *Service) ReconcileTotals(ctx context.Context, id string, counts map[string]int) error {
+	item, err := s.repo.Get(ctx, strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	totals := make(map[string]int, len(item.Options))
+	for _, option := range item.Options {
+		totals[option.ID] = 0
+	}
+	for optionID, count := range counts {
+		totals[optionID] = count
+	}
+	if err := s.repo.UpdateTotals(ctx, item.ID, totals); err != nil {
+		return err
+	}
+	if s.tally != nil {
+		if err := s.tally.Set(ctx, item.ID, totals); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.MarkTotalsStale(ctx, item.ID, false); err != nil {
+		return err
+	}
+	s.invalidateLive(ctx, item.StreamerID)
+	return nil
+}
+
+// MarkTotalsStale records that the tally missed a vote of the event, so
+// reconcile-totals recounts it.
+func (s *Service) MarkTotalsStale(ctx context.Context, id string) error {
+	return s.repo.MarkTotalsStale(ctx, strings.TrimSpace(id), true)
+}
+
+// ListTotalsStale returns the IDs of events marked by MarkTotalsStale and not
+// reconciled since.
+func (s *Service) ListTotalsStale(ctx context.Context) ([]string, error) {
+	return s.repo.ListTotalsStale(ctx)
+}
+
+// fillTotals overlays the tally counters on a live event's stored totals.
+// The stored snapshot is kept when the tally is unreachable.
+func (s *Service) fillTotals(ctx context.Context, item *LiveEvent) {
+	if s.tally == nil || item.State != StateLive {
+		return
+	}
+	counts, err := s.tally.Totals(ctx, item.ID)
+	if err != nil {
+		s.logger.Warn("failed to read vote tally", zap.String("event_id", item.ID), zap.Error(err))
+		return
+	}
+	totals := make(map[string]int, len(item.Totals))
+	for optionID, count := range item.Totals {
+		totals[optionID] = count
+	}
+	for optionID, count := range counts {
+		totals[optionID] = count
+	}
+	item.Totals = totals
+}
+
+// Create opens a live event accepting votes until ClosesAt.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (LiveEvent, error) {
+	streamerID := strings.TrimSpace(req.StreamerID)
+	if streamerID == "" {
+		return LiveEvent{}, ErrStreamerIDRequired
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return LiveEvent{}, ErrTitleRequired
+	}
+	if !validOptions(req.Options) {
+		return LiveEvent{}, ErrInvalidOptions
+	}
+	now := s.nowFn()
+	if !req.ClosesAt.After(now) {
+		return LiveEvent{}, ErrInvalidClosesAt
+	}
+	if req.CostPerVote < 0 {
+		return LiveEvent{}, ErrInvalidCostPerVote
+	}
+
+	options := make([]Option, len(req.Options))
+	copy(options, req.Options)
+	totals := make(map[string]int, len(options))
+	for _, option := range options {
+		totals[option.ID] = 0
+	}
+
+	item, err := s.repo.Create(ctx, LiveEvent{
+		GameID:         req.GameID,
+		StreamerID:     streamerID,
+		Title:          title,
+		Options:        options,
+		State:          StateLive,
+		ClosesAt:       req.ClosesAt.UTC(),
+		Totals:         totals,
+		CostPerVote:    req.CostPerVote,
+		ExternalID:     strings.TrimSpace(req.ExternalID),
+		SourceClipID:   strings.TrimSpace(req.SourceClipID),
+		Confidence:     req.Confidence,
+		PromptVersions: req.PromptVersions,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		return LiveEvent{}, err
+	}
+	s.invalidateLive(ctx, streamerID)
+	return item, nil
+}
+
+// Update changes a live event whose voting window has not ended.
+func (s *Service) Update(ctx context.Context, id string, req UpdateRequest) (LiveEvent, error) {
+	return s.mutate(ctx, id, func(item *LiveEvent, now time.Time) error {
+		if !item.IsOpen(now) {
+			if item.State == StateLive {
+				return ErrEventExpired
+			}
+			return ErrInvalidTransition
+		}
+		if req.Title != nil {
+			title := strings.TrimSpace(*req.Title)
+			if title == "" {
+				return ErrTitleRequired
+			}
+			item.Title = title
+		}
+		if req.ClosesAt != nil {
+			if !req.ClosesAt.After(now) {
+				return ErrInvalidClosesAt
+			}
+			item.ClosesAt = req.ClosesAt.UTC()
+		}
+		if req.CostPerVote != nil {
+			if *req.CostPerVote < 0 {
+				return ErrInvalidCostPerVote
+			}
+			item.CostPerVote = *req.CostPerVote
+		}
+		return nil
+	})
+}
+
+// Close stops voting on a live event.
+func (s *Service) Close(ctx context.Context, id string) (LiveEvent, error) {
+	return s.mutate(ctx, id, func(item *LiveEvent, _ time.Time) error {
+		return transition(item, StateClosed)
+	})
+}
+
+// RecordResult stores the outcome of a live or closed event, closing it if
+// needed. A result without an option is kept as needing review and may be
+// replaced later.
+func (s *Service) RecordResult(ctx context.Context, id string, result Result) (LiveEvent, error) {
+	return s.mutate(ctx, id, func(item *LiveEvent, _ time.Time) error {
+		if item.HasFinalResult() {
+			return ErrResultFinal
+		}
+		if result.OptionID != "" && !hasOption(item.Options, result.OptionID) {
+			return ErrUnknownOption
+		}
+		if item.State != StateClosed {
+			if err := transition(item, StateClosed); err != nil {
+				return err
+			}
+		}
+		result.NeedsReview = result.OptionID == ""
+		item.Result = &result
+		return nil
+	})
+}
+
+// Cancel voids an event that has no final result so its votes can be refunded.
+func (s *Service) Cancel(ctx context.Context, id, reason string) (LiveEvent, error) {
+	return s.mutate(ctx, id, func(item *LiveEvent, _ time.Time) error {
+		if item.HasFinalResult() {
+			return ErrResultFinal
+		}
+		if err := transition(item, StateCancelled); err != nil {
+			return err
+		}
+		item.CancelReason = strings.TrimSpace(reason)
+		return nil
+	})
+}
+
+func (s *Service) mutate(ctx context.Context, id string, fn func(item *LiveEvent, now time.Time) error) (LiveEvent, error) {
+	s.mu.Lock()
+	item, err := s.repo.Get(ctx, strings.TrimSpace(id))
+	if err != nil {
+		s.mu.Unlock()
+		return LiveEvent{}, err
+	}
+	s.fillTotals(ctx, &item)
+	now := s.nowFn()
+	previous := item.State
+	if err := fn(&item, now); err != nil {
+		s.mu.Unlock()
+		return LiveEvent{}, err
+	}
+	item.UpdatedAt = now
+	err = s.repo.Update(ctx, item, previous)
+	s.mu.Unlock()
+	if err != nil {
+		return LiveEvent{}, err
+	}
+	item.Version++
+
+	s.invalidateLive(ctx, item.StreamerID)
+	if previous == StateLive && item.State == StateClosed {
+		s.publishClosed(ctx, item)
+	}
+	return item, nil
+}
+
+func (s *Service) invalidateLive(ctx context.Context, streamerID string) {
+	if s.liveCache != nil {
+		s.liveCache.invalidate(ctx, streamerID)
+	}
+}
+
+func (s *Service) publishClosed(ctx context.Context, item LiveEvent) {
+	if s.publisher == nil {
+		return
+	}
+	payload := closedPayload{EventID: item.ID, Result: closedResult{Totals: item.FinalTotals}}
+	if item.Result != nil {
+		payload.Result.OptionID = item.Result.OptionID
+	}
+	channels := []string{realtime.StreamerChannel(item.StreamerID)}
+	if item.GameID != nil && *item.GameID != "" {
+		channels = append(channels, realtime.GameChannel(*item.GameID))
+	}
+	for _, channel := range channels {
+		if err := s.publisher.Publish(ctx, channel, realtime.Message{Type: realtime.TypeEventClosed, Payload: payload}); err != nil {
+			s.logger.Warn("failed to publish event close", zap.String("event_id", item.ID), zap.String("channel", channel), zap.Error(err))
+		}
+	}
+}
+
+// transition moves the event to a new state, snapshotting the vote totals
+// when voting stops.
+func transition(item *LiveEvent, to string) error {
+	if !CanTransition(item.State, to) {
+		return ErrInvalidTransition
+	}
+	if item.State == StateLive {
+		item.FinalTotals = make(map[string]int, len(item.Totals))
+		for optionID, total := range item.Totals {
+			item.FinalTotals[optionID] = total
+		}
+	}
+	item.State = to
+	return nil
+}
+
+func validOptions(options []Option) bool {
+	if len(options) < 2 {
+		return false
+	}
+	seen := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		id := strings.TrimSpace(option.ID)
+		if id == "" || strings.TrimSpace(option.Label) == "" {
+			return false
+		}
+		if _, dup := seen[id]; dup {
+			return false
+		}
+		seen[id] = struct{}{}
+	}
+	return true
+}
+
+func hasOption(options []Option, id string) bool {
+	for _, option := range options {
+		if option.ID == id {
+			return true
+		}
+	}
+	return false
 }
@@ -3,8 +3,18 @@ package events
 import (
 	"context"
 	"testing"
+	"time"
 )
 
+func liveEvents(t *testing.T, svc *Service, streamerID string) []LiveEvent {
+	t.Helper()
+	items, err := svc.ListLiveByStreamer(context.Background(), streamerID, "")
+	if err != nil {
+		t.Fatalf("ListLiveByStreamer() error = %v", err)
+	}
+	return items
+}
+
 func TestListLiveByStreamer(t *testing.T) {
 	svc := NewService([]LiveEvent{
 		{ID: "evt-1", StreamerID: "s-1"},
@@ -12,8 +22,98 @@ func TestListLiveByStreamer(t *testing.T) {
 		{ID: "evt-3", StreamerID: "s-1"},
 	})
 
-	items := svc.ListLiveByStreamer(context.Background(), "s-1")
+	items := liveEvents(t, svc, "s-1")
 	if len(items) != 2 {
 		t.Fatalf("expected 2 events, got %d", len(items))
 	}
 }
+
+func TestCreateCloseAndRecordResult(t *testing.T) {
+	svc := NewService(nil)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, CreateRequest{StreamerID: "s-1", Title: "t", Options: []Option{{ID: "a", Label: "A"}}, ClosesAt: time.Now().Add(time.Minute)}); err != ErrInvalidOptions {
+		t.Fatalf("expected ErrInvalidOptions, got %v", err)
+	}
+	event, err := svc.Create(ctx, CreateRequest{
+		StreamerID: "s-1",
+		Title:      "Will it happen?",
+		Options:    []Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if event.State != StateLive || len(liveEvents(t, svc, "s-1")) != 1 {
+		t.Fatalf("expected live event, got %+v", event)
+	}
+
+	if _, err := svc.Close(ctx, event.ID); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(liveEvents(t, svc, "s-1")) != 0 {
+		t.Fatal("expected closed event to leave the live list")
+	}
+	if _, err := svc.RecordResult(ctx, event.ID, Result{OptionID: "maybe"}); err != ErrUnknownOption {
+		t.Fatalf("expected ErrUnknownOption, got %v", err)
+	}
+	settled, err := svc.RecordResult(ctx, event.ID, Result{OptionID: "yes", Outcome: "win"})
+	if err != nil || settled.Result == nil || settled.Result.OptionID != "yes" {
+		t.Fatalf("unexpected settle result %+v, %v", settled, err)
+	}
+	if _, err := svc.Cancel(ctx, event.ID, "late"); err != ErrResultFinal {
+		t.Fatalf("expected settled event to reject cancel, got %v", err)
+	}
+}
+
+func TestUpdateAndTransitions(t *testing.T) {
+	svc := NewService(nil)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+	ctx := context.Background()
+
+	event, err := svc.Create(ctx, CreateRequest{
+		StreamerID: "s-1",
+		Title:      "Clutch?",
+		Options:    []Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	title := "Clutch 1v3?"
+	later := now.Add(2 * time.Minute)
+	updated, err := svc.Update(ctx, event.ID, UpdateRequest{Title: &title, ClosesAt: &later})
+	if err != nil || updated.Title != title || !updated.ClosesAt.Equal(later) || updated.Version != 1 {
+		t.Fatalf("unexpected update %+v, %v", updated, err)
+	}
+	// A write based on the version read before that update must not land.
+	stale := event
+	stale.Title = "stale"
+	if err := svc.repo.Update(ctx, stale, StateLive); err != ErrInvalidTransition {
+		t.Fatalf("expected a stale write to be rejected, got %v", err)
+	}
+	past := now.Add(-time.Second)
+	if _, err := svc.Update(ctx, event.ID, UpdateRequest{ClosesAt: &past}); err != ErrInvalidClosesAt {
+		t.Fatalf("expected ErrInvalidClosesAt, got %v", err)
+	}
+
+	now = later
+	if len(liveEvents(t, svc, "s-1")) != 0 {
+		t.Fatal("expected expired event to stop accepting votes")
+	}
+	if _, err := svc.Update(ctx, event.ID, UpdateRequest{Title: &title}); err != ErrEventExpired {
+		t.Fatalf("expected ErrEventExpired, got %v", err)
+	}
+
+	if _, err := svc.Cancel(ctx, event.ID, "stream offline"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if _, err := svc.Close(ctx, event.ID); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if _, err := svc.RecordResult(ctx, event.ID, Result{OptionID: "yes"}); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition for cancelled event, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Snapshotter periodically copies tally counters into the repository so
+// totals survive a Redis loss.
+type Snapshotter struct {
+	events   *Service
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+func NewSnapshotter(events *Service, logger *zap.Logger, interval time.Duration) *Snapshotter {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Snapshotter{events: events, logger: logger, interval: interval}
+}
+
+// Run snapshots totals every interval until ctx is cancelled, then takes a
+// final snapshot.
+func (s *Snapshotter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.RunOnce(context.WithoutCancel(ctx))
+			return ctx.Err()
+		case <-ticker.C:
+			s.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce writes one snapshot and returns how many events were updated.
+func (s *Snapshotter) RunOnce(ctx context.Context) int {
+	written, err := s.events.SnapshotTotals(ctx)
+	if err != nil {
+		s.logger.Warn("failed to snapshot vote totals", zap.Error(err))
+	}
+	return written
+}
@@ -0,0 +1,205 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Tally counts votes per option while events are live. Counters are the
+// source of LiveEvent.Totals and are snapshotted into the repository.
+type Tally interface {
+	Increment(ctx context.Context, eventID, optionID string) error
+	Totals(ctx context.Context, eventID string) (map[string]int, error)
+	// Set overwrites an event's counters, e.g. after reconciling from votes.
+	Set(ctx context.Context, eventID string, totals map[string]int) error
+	// TakeDirty claims the events incremented since the last call together
+	// with earlier claims that were never acknowledged.
+	TakeDirty(ctx context.Context) ([]string, error)
+	// AckDirty forgets claimed events whose totals were persisted.
+	AckDirty(ctx context.Context, eventIDs ...string) error
+	Ping(ctx context.Context) error
+}
+
+// tallyRetention keeps counters of long-running events alive between votes.
+const tallyRetention = 72 * time.Hour
+
+// RedisTally keeps counters in one Redis hash per event.
+type RedisTally struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisTally(client redis.UniversalClient, keyPrefix string) (*RedisTally, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = "funpot:tally"
+	}
+	return &RedisTally{client: client, keyPrefix: keyPrefix}, nil
+}
+
+func (t *RedisTally) key(eventID string) string {
+	return t.keyPrefix + ":" + eventID
+}
+
+func (t *RedisTally) dirtyKey() string {
+	return t.keyPrefix + ":dirty"
+}
+
+func (t *RedisTally) Increment(ctx context.Context, eventID, optionID string) error {
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, t.key(eventID), optionID, 1)
+		pipe.Expire(ctx, t.key(eventID), tallyRetention)
+		pipe.SAdd(ctx, t.dirtyKey(), eventID)
+		return nil
+	})
+	return err
+}
+
+func (t *RedisTally) Totals(ctx context.Context, eventID string) (map[string]int, error) {
+	raw, err := t.client.HGetAll(ctx, t.key(eventID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]int, len(raw))
+	for optionID, value := range raw {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		totals[optionID] = count
+	}
+	return totals, nil
+}
+
+func (t *RedisTally) Set(ctx context.Context, eventID string, totals map[string]int) error {
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, t.key(eventID))
+		if len(totals) > 0 {
+			values := make(map[string]any, len(totals))
+			for optionID, count := range totals {
+				values[optionID] = count
+			}
+			pipe.HSet(ctx, t.key(eventID), values)
+			pipe.Expire(ctx, t.key(eventID), tallyRetention)
+		}
+		return nil
+	})
+	return err
+}
+
+func (t *RedisTally) claimedKey() string {
+	return t.keyPrefix + ":dirty:claimed"
+}
+
+// TakeDirty moves the dirty set into the claimed set, which keeps events
+// until AckDirty so a failed snapshot is retried on the next call.
+func (t *RedisTally) TakeDirty(ctx context.Context) ([]string, error) {
+	var members *redis.StringSliceCmd
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SUnionStore(ctx, t.claimedKey(), t.claimedKey(), t.dirtyKey())
+		pipe.Del(ctx, t.dirtyKey())
+		members = pipe.SMembers(ctx, t.claimedKey())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+func (t *RedisTally) AckDirty(ctx context.Context, eventIDs ...string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	members := make([]any, len(eventIDs))
+	for i, id := range eventIDs {
+		members[i] = id
+	}
+	return t.client.SRem(ctx, t.claimedKey(), members...).Err()
+}
+
+func (t *RedisTally) Ping(ctx context.Context) error {
+	return t.client.Ping(ctx).Err()
+}
+
+// InMemoryTally counts votes in process; used in tests and without Redis.
+type InMemoryTally struct {
+	mu      sync.Mutex
+	totals  map[string]map[string]int
+	dirty   map[string]struct{}
+	claimed map[string]struct{}
+}
+
+func NewInMemoryTally() *InMemoryTally {
+	return &InMemoryTally{
+		totals:  make(map[string]map[string]int),
+		dirty:   make(map[string]struct{}),
+		claimed: make(map[string]struct{}),
+	}
+}
+
+func (t *InMemoryTally) Increment(_ context.Context, eventID, optionID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.totals[eventID] == nil {
+		t.totals[eventID] = make(map[string]int)
+	}
+	t.totals[eventID][optionID]++
+	t.dirty[eventID] = struct{}{}
+	return nil
+}
+
+func (t *InMemoryTally) Totals(_ context.Context, eventID string) (map[string]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals := make(map[string]int, len(t.totals[eventID]))
+	for optionID, count := range t.totals[eventID] {
+		totals[optionID] = count
+	}
+	return totals, nil
+}
+
+func (t *InMemoryTally) Set(_ context.Context, eventID string, totals map[string]int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	copied := make(map[string]int, len(totals))
+	for optionID, count := range totals {
+		copied[optionID] = count
+	}
+	t.totals[eventID] = copied
+	return nil
+}
+
+func (t *InMemoryTally) TakeDirty(_ context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id := range t.dirty {
+		t.claimed[id] = struct{}{}
+	}
+	t.dirty = make(map[string]struct{})
+	ids := make([]string, 0, len(t.claimed))
+	for id := range t.claimed {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (t *InMemoryTally) AckDirty(_ context.Context, eventIDs ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range eventIDs {
+		delete(t.claimed, id)
+	}
+	return nil
+}
+
+func (t *InMemoryTally) Ping(context.Context) error {
+	return nil
+}
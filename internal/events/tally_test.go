@@ -0,0 +1,143 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisTallyCountsAndDrainsDirty(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("run miniredis: %v", err)
+	}
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	tally, err := NewRedisTally(client, "test")
+	if err != nil {
+		t.Fatalf("NewRedisTally() error = %v", err)
+	}
+	for _, optionID := range []string{"yes", "yes", "no"} {
+		if err := tally.Increment(ctx, "evt-1", optionID); err != nil {
+			t.Fatalf("Increment() error = %v", err)
+		}
+	}
+	totals, err := tally.Totals(ctx, "evt-1")
+	if err != nil || totals["yes"] != 2 || totals["no"] != 1 {
+		t.Fatalf("unexpected totals %v, %v", totals, err)
+	}
+
+	dirty, err := tally.TakeDirty(ctx)
+	if err != nil || len(dirty) != 1 || dirty[0] != "evt-1" {
+		t.Fatalf("unexpected dirty events %v, %v", dirty, err)
+	}
+	if dirty, _ := tally.TakeDirty(ctx); len(dirty) != 1 {
+		t.Fatalf("expected unacknowledged events to be claimed again, got %v", dirty)
+	}
+	if err := tally.AckDirty(ctx, "evt-1"); err != nil {
+		t.Fatalf("AckDirty() error = %v", err)
+	}
+	if dirty, _ := tally.TakeDirty(ctx); len(dirty) != 0 {
+		t.Fatalf("expected dirty set to be drained, got %v", dirty)
+	}
+
+	if err := tally.Set(ctx, "evt-1", map[string]int{"yes": 5, "no": 0}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if totals, _ := tally.Totals(ctx, "evt-1"); totals["yes"] != 5 || totals["no"] != 0 {
+		t.Fatalf("unexpected totals after Set %v", totals)
+	}
+}
+
+func TestServiceSnapshotsAndReconcilesTotals(t *testing.T) {
+	ctx := context.Background()
+	tally := NewInMemoryTally()
+	svc := NewService(nil)
+	svc.WithTally(tally)
+	event, err := svc.Create(ctx, CreateRequest{
+		StreamerID: "s-1",
+		Title:      "Will it happen?",
+		Options:    []Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_ = tally.Increment(ctx, event.ID, "yes")
+	_ = tally.Increment(ctx, event.ID, "yes")
+	if live := liveEvents(t, svc, "s-1"); live[0].Totals["yes"] != 2 || live[0].Totals["no"] != 0 {
+		t.Fatalf("expected tallied totals, got %v", live[0].Totals)
+	}
+
+	if written := NewSnapshotter(svc, nil, time.Second).RunOnce(ctx); written != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", written)
+	}
+	stored, _ := svc.repo.Get(ctx, event.ID)
+	if stored.Totals["yes"] != 2 {
+		t.Fatalf("expected snapshot in repository, got %v", stored.Totals)
+	}
+
+	if err := svc.ReconcileTotals(ctx, event.ID, map[string]int{"no": 1}); err != nil {
+		t.Fatalf("ReconcileTotals() error = %v", err)
+	}
+	closed, err := svc.Close(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if closed.FinalTotals["yes"] != 0 || closed.FinalTotals["no"] != 1 {
+		t.Fatalf("expected reconciled final totals, got %v", closed.FinalTotals)
+	}
+}
+
+// flakyTotalsRepository fails the first UpdateTotals calls.
+type flakyTotalsRepository struct {
+	Repository
+	failures int
+}
+
+func (r *flakyTotalsRepository) UpdateTotals(ctx context.Context, id string, totals map[string]int) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("database unavailable")
+	}
+	return r.Repository.UpdateTotals(ctx, id, totals)
+}
+
+func TestServiceRetriesFailedSnapshots(t *testing.T) {
+	ctx := context.Background()
+	repo := &flakyTotalsRepository{Repository: NewInMemoryRepository(nil), failures: 1}
+	svc := NewService(nil)
+	svc.WithRepository(repo)
+	svc.WithTally(NewInMemoryTally())
+	event, err := svc.Create(ctx, CreateRequest{
+		StreamerID: "s-1",
+		Title:      "Will it happen?",
+		Options:    []Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_ = svc.tally.Increment(ctx, event.ID, "yes")
+
+	if written, err := svc.SnapshotTotals(ctx); written != 0 || err == nil {
+		t.Fatalf("expected the snapshot to fail, got %d, %v", written, err)
+	}
+	if written, err := svc.SnapshotTotals(ctx); written != 1 || err != nil {
+		t.Fatalf("expected the failed snapshot to be retried, got %d, %v", written, err)
+	}
+	if stored, _ := repo.Get(ctx, event.ID); stored.Totals["yes"] != 1 {
+		t.Fatalf("expected snapshot in repository, got %v", stored.Totals)
+	}
+	if written, _ := svc.SnapshotTotals(ctx); written != 0 {
+		t.Fatalf("expected acknowledged events to be forgotten, got %d", written)
+	}
+}
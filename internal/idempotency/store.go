@@ -0,0 +1,40 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInProgress          = errors.New("request with this idempotency key is still in progress")
+	ErrFingerprintMismatch = errors.New("idempotency key was already used with a different payload")
+)
+
+// Response is the stored outcome replayed for retries of the same key.
+type Response struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	Body        []byte `json:"body"`
+}
+
+// Store reserves idempotency keys and remembers the response they produced.
+type Store interface {
+	// Begin reserves key for a request with the given payload fingerprint. It
+	// returns the stored response when the key already completed, ErrInProgress
+	// while another request holds it and ErrFingerprintMismatch when the key
+	// was used with a different payload.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Response, error)
+	// Complete stores the response for a reserved key.
+	Complete(ctx context.Context, key string, resp Response, ttl time.Duration) error
+	// Release drops a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Fingerprint hashes a request payload.
+func Fingerprint(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,57 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	record
+	expiresAt time.Time
+}
+
+// InMemoryStore is a single-process Store for tests and Redis-less setups.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	nowFn   func() time.Time
+}
+
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		entries: make(map[string]memoryEntry),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+func (s *InMemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFn()
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		return entry.replay(fingerprint)
+	}
+	s.entries[key] = memoryEntry{
+		record:    record{Pending: true, Response: Response{Fingerprint: fingerprint}},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+func (s *InMemoryStore) Complete(_ context.Context, key string, resp Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{record: record{Response: resp}, expiresAt: s.nowFn().Add(ttl)}
+	return nil
+}
+
+func (s *InMemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
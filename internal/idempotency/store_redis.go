@@ -0,0 +1,86 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type record struct {
+	Pending bool `json:"pending"`
+	Response
+}
+
+// RedisStore keeps idempotency records in Redis so retries dedupe across replicas.
+type RedisStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, keyPrefix string) (*RedisStore, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = "funpot:idempotency"
+	}
+	return &RedisStore{client: client, keyPrefix: keyPrefix}, nil
+}
+
+func (s *RedisStore) key(key string) string {
+	return fmt.Sprintf("%s:%s", s.keyPrefix, key)
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Response, error) {
+	pending, err := json.Marshal(record{Pending: true, Response: Response{Fingerprint: fingerprint}})
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.client.SetNX(ctx, s.key(key), pending, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	raw, err := s.client.Get(ctx, s.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return s.Begin(ctx, key, fingerprint, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var existing record
+	if err := json.Unmarshal(raw, &existing); err != nil {
+		return nil, err
+	}
+	return existing.replay(fingerprint)
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, resp Response, ttl time.Duration) error {
+	raw, err := json.Marshal(record{Response: resp})
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key(key), raw, ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.key(key)).Err()
+}
+
+func (r record) replay(fingerprint string) (*Response, error) {
+	if r.Fingerprint != fingerprint {
+		return nil, ErrFingerprintMismatch
+	}
+	if r.Pending {
+		return nil, ErrInProgress
+	}
+	resp := r.Response
+	return &resp, nil
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	redisStore, err := NewRedisStore(client, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	return map[string]Store{"memory": NewInMemoryStore(), "redis": redisStore}
+}
+
+func TestStoreReplaysCompletedResponse(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			fingerprint := Fingerprint([]byte(`{"a":1}`))
+
+			if resp, err := store.Begin(ctx, "k1", fingerprint, time.Minute); err != nil || resp != nil {
+				t.Fatalf("first Begin() = %v, %v", resp, err)
+			}
+			if _, err := store.Begin(ctx, "k1", fingerprint, time.Minute); !errors.Is(err, ErrInProgress) {
+				t.Fatalf("expected ErrInProgress, got %v", err)
+			}
+			if err := store.Complete(ctx, "k1", Response{Fingerprint: fingerprint, Status: 200, Body: []byte(`{"ok":true}`)}, time.Minute); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			resp, err := store.Begin(ctx, "k1", fingerprint, time.Minute)
+			if err != nil || resp == nil || resp.Status != 200 || string(resp.Body) != `{"ok":true}` {
+				t.Fatalf("expected replayed response, got %+v, %v", resp, err)
+			}
+			if _, err := store.Begin(ctx, "k1", Fingerprint([]byte(`{"a":2}`)), time.Minute); !errors.Is(err, ErrFingerprintMismatch) {
+				t.Fatalf("expected ErrFingerprintMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreReleaseAllowsRetry(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Begin(ctx, "k2", "f", time.Minute); err != nil {
+				t.Fatalf("Begin() error = %v", err)
+			}
+			if err := store.Release(ctx, "k2"); err != nil {
+				t.Fatalf("Release() error = %v", err)
+			}
+			if resp, err := store.Begin(ctx, "k2", "f", time.Minute); err != nil || resp != nil {
+				t.Fatalf("expected fresh reservation, got %v, %v", resp, err)
+			}
+		})
+	}
+}
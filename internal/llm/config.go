@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/funpot/funpot-go-core/internal/config"
+)
+
+const (
+	GeminiPrefix = "gemini-"
+	FakePrefix   = "fake/"
+)
+
+// NewRegistryFromConfig registers every provider that has credentials configured.
+func NewRegistryFromConfig(client *http.Client, cfg config.LLMProvidersConfig) (*Registry, error) {
+	registry := NewRegistry()
+	if strings.TrimSpace(cfg.GeminiAPIKey) != "" {
+		gemini, err := NewGeminiProvider(client, cfg.GeminiBaseURL, cfg.GeminiAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(GeminiPrefix, gemini)
+	}
+	if strings.TrimSpace(cfg.OpenAIAPIKey) != "" {
+		openai, err := NewOpenAICompatibleProvider(client, cfg.OpenAIBaseURL, cfg.OpenAIAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		prefix := strings.TrimSpace(cfg.OpenAIPrefix)
+		if prefix == "" {
+			prefix = "openai/"
+		}
+		registry.Register(prefix, openai)
+	}
+	if cfg.FakeEnabled {
+		registry.Register(FakePrefix, NewFakeProvider())
+	}
+	return registry, nil
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// FakeProvider is a local provider for development and tests. Without a
+// scripted reply it answers with the first label of the requested schema at
+// full confidence.
+type FakeProvider struct {
+	mu      sync.Mutex
+	replies []FakeReply
+	calls   []Request
+}
+
+// FakeReply scripts one call; Err takes precedence over Text.
+type FakeReply struct {
+	Text string
+	Err  error
+}
+
+func NewFakeProvider(replies ...FakeReply) *FakeProvider {
+	return &FakeProvider{replies: replies}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, req)
+	var reply *FakeReply
+	if len(p.replies) > 0 {
+		reply = &p.replies[0]
+		p.replies = p.replies[1:]
+	}
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	if reply != nil {
+		if reply.Err != nil {
+			return Response{}, reply.Err
+		}
+		return Response{Text: reply.Text, Model: req.Model, TokensIn: len(req.Prompt), TokensOut: len(reply.Text)}, nil
+	}
+
+	text, err := json.Marshal(map[string]any{"label": firstSchemaLabel(req.ResponseSchema), "confidence": 1})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Text: string(text), Model: req.Model, TokensIn: len(req.Prompt), TokensOut: len(text)}, nil
+}
+
+// Calls returns the requests received so far.
+func (p *FakeProvider) Calls() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Request, len(p.calls))
+	copy(out, p.calls)
+	return out
+}
+
+func firstSchemaLabel(schema map[string]any) string {
+	properties, _ := schema["properties"].(map[string]any)
+	label, _ := properties["label"].(map[string]any)
+	switch values := label["enum"].(type) {
+	case []string:
+		if len(values) > 0 {
+			return values[0]
+		}
+	case []any:
+		if len(values) > 0 {
+			if first, ok := values[0].(string); ok {
+				return first
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+// GeminiProvider calls the Gemini generateContent REST API.
+type GeminiProvider struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+func NewGeminiProvider(client *http.Client, baseURL, apiKey string) (*GeminiProvider, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, errors.New("gemini api key is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	return &GeminiProvider{client: client, baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey}, nil
+}
+
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *geminiInlineData `json:"inlineData,omitempty"`
+	FileData   *geminiFileData   `json:"fileData,omitempty"`
+}
+
+type geminiInlineData struct {
+	MIMEType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+type geminiFileData struct {
+	MIMEType string `json:"mimeType"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float64        `json:"temperature"`
+	MaxOutputTokens  int            `json:"maxOutputTokens,omitempty"`
+	ResponseMIMEType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	parts := []geminiPart{{Text: req.Prompt}}
+	for _, media := range req.Media {
+		if len(media.Data) > 0 {
+			parts = append(parts, geminiPart{InlineData: &geminiInlineData{MIMEType: media.MIMEType, Data: media.Data}})
+			continue
+		}
+		parts = append(parts, geminiPart{FileData: &geminiFileData{MIMEType: media.MIMEType, FileURI: media.URI}})
+	}
+	body := geminiRequest{
+		Contents: []geminiContent{{Role: "user", Parts: parts}},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		},
+	}
+	if req.ResponseSchema != nil {
+		body.GenerationConfig.ResponseMIMEType = "application/json"
+		body.GenerationConfig.ResponseSchema = geminiSchema(req.ResponseSchema)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent", p.baseURL, url.PathEscape(req.Model))
+	var decoded geminiResponse
+	if err := postJSON(ctx, p.client, endpoint, map[string]string{"x-goog-api-key": p.apiKey}, body, &decoded); err != nil {
+		return Response{}, err
+	}
+	if len(decoded.Candidates) == 0 || len(decoded.Candidates[0].Content.Parts) == 0 {
+		return Response{}, ErrEmptyResponse
+	}
+
+	var text strings.Builder
+	for _, part := range decoded.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return Response{
+		Text:      text.String(),
+		Model:     req.Model,
+		TokensIn:  decoded.UsageMetadata.PromptTokenCount,
+		TokensOut: decoded.UsageMetadata.CandidatesTokenCount,
+	}, nil
+}
+
+// geminiSchema drops JSON Schema keywords the Gemini responseSchema subset
+// rejects and turns nullable type unions into its nullable flag.
+func geminiSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if key == "additionalProperties" {
+			continue
+		}
+		switch typed := value.(type) {
+		case map[string]any:
+			out[key] = geminiSchema(typed)
+		case []string:
+			if key != "type" {
+				out[key] = value
+				continue
+			}
+			for _, name := range typed {
+				if name == "null" {
+					out["nullable"] = true
+				} else {
+					out[key] = name
+				}
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
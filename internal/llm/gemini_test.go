@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeminiProviderGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "key" {
+			t.Errorf("missing api key header")
+		}
+		var body geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if body.GenerationConfig.ResponseMIMEType != "application/json" {
+			t.Errorf("expected JSON response mime type, got %q", body.GenerationConfig.ResponseMIMEType)
+		}
+		if _, ok := body.GenerationConfig.ResponseSchema["additionalProperties"]; ok {
+			t.Errorf("expected additionalProperties to be stripped")
+		}
+		reasoning, _ := body.GenerationConfig.ResponseSchema["properties"].(map[string]any)["reasoning"].(map[string]any)
+		if reasoning["type"] != "string" || reasoning["nullable"] != true {
+			t.Errorf("expected nullable reasoning, got %v", reasoning)
+		}
+		if len(body.Contents) != 1 || len(body.Contents[0].Parts) != 2 || body.Contents[0].Parts[1].FileData.FileURI != "gs://chunk.mp4" {
+			t.Errorf("unexpected contents %+v", body.Contents)
+		}
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"{\"label\":\"cs_detected\","},{"text":"\"confidence\":0.9}"}]}}],"usageMetadata":{"promptTokenCount":120,"candidatesTokenCount":12}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewGeminiProvider(server.Client(), server.URL, "key")
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	resp, err := provider.Generate(context.Background(), Request{
+		Model:  "gemini-2.0-flash",
+		Prompt: "classify",
+		Media:  []Media{{URI: "gs://chunk.mp4", MIMEType: "video/mp4"}},
+		ResponseSchema: map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{"reasoning": map[string]any{"type": []string{"string", "null"}}},
+			"additionalProperties": false,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text != `{"label":"cs_detected","confidence":0.9}` || resp.TokensIn != 120 || resp.TokensOut != 12 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestGeminiProviderReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "quota", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider, _ := NewGeminiProvider(server.Client(), server.URL, "key")
+	if _, err := provider.Generate(context.Background(), Request{Model: "gemini-2.0-flash"}); err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+}
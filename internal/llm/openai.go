@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// OpenAICompatibleProvider calls any /chat/completions endpoint that follows
+// the OpenAI wire format (OpenAI, vLLM, OpenRouter, LiteLLM, ...).
+type OpenAICompatibleProvider struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+func NewOpenAICompatibleProvider(client *http.Client, baseURL, apiKey string) (*OpenAICompatibleProvider, error) {
+	if strings.TrimSpace(baseURL) == "" {
+		return nil, errors.New("openai-compatible base url is required")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &OpenAICompatibleProvider{client: client, baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey}, nil
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+	return "openai-compatible"
+}
+
+type openAIContentPart struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	ImageURL *openAIContentURL `json:"image_url,omitempty"`
+}
+
+type openAIContentURL struct {
+	URL string `json:"url"`
+}
+
+type openAIMessage struct {
+	Role    string              `json:"role"`
+	Content []openAIContentPart `json:"content"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    float64               `json:"temperature"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	content := []openAIContentPart{{Type: "text", Text: req.Prompt}}
+	for _, media := range req.Media {
+		url := media.URI
+		if len(media.Data) > 0 {
+			url = "data:" + media.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(media.Data)
+		}
+		content = append(content, openAIContentPart{Type: "image_url", ImageURL: &openAIContentURL{URL: url}})
+	}
+	body := openAIRequest{
+		Model:       req.Model,
+		Messages:    []openAIMessage{{Role: "user", Content: content}},
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.ResponseSchema != nil {
+		body.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "stage_output", Schema: req.ResponseSchema, Strict: true},
+		}
+	}
+
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	var decoded openAIResponse
+	if err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", headers, body, &decoded); err != nil {
+		return Response{}, err
+	}
+	if len(decoded.Choices) == 0 || decoded.Choices[0].Message.Content == "" {
+		return Response{}, ErrEmptyResponse
+	}
+	return Response{
+		Text:      decoded.Choices[0].Message.Content,
+		Model:     req.Model,
+		TokensIn:  decoded.Usage.PromptTokens,
+		TokensOut: decoded.Usage.CompletionTokens,
+	}, nil
+}
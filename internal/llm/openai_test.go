@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompatibleProviderGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("missing bearer token")
+		}
+		var body openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if body.Model != "gpt-4o-mini" || body.ResponseFormat == nil || body.ResponseFormat.Type != "json_schema" {
+			t.Errorf("unexpected request %+v", body)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"label\":\"not_cs\",\"confidence\":0.8}"}}],"usage":{"prompt_tokens":50,"completion_tokens":9}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(server.Client(), server.URL+"/v1/", "key")
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider() error = %v", err)
+	}
+	resp, err := provider.Generate(context.Background(), Request{
+		Model:          "gpt-4o-mini",
+		Prompt:         "classify",
+		ResponseSchema: map[string]any{"type": "object"},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text != `{"label":"not_cs","confidence":0.8}` || resp.TokensIn != 50 || resp.TokensOut != 9 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownModel  = errors.New("no llm provider registered for model")
+	ErrEmptyResponse = errors.New("llm provider returned an empty response")
+)
+
+// Request is a provider-agnostic generation call.
+type Request struct {
+	Model       string
+	Prompt      string
+	Temperature float64
+	MaxTokens   int
+	// Media holds the clip or keyframes sent alongside the prompt.
+	Media []Media
+	// ResponseSchema requests structured JSON output when non-nil.
+	ResponseSchema map[string]any
+}
+
+// Media is one attachment of a request. Data is sent inline when set,
+// otherwise URI is passed to the provider as a remote reference.
+type Media struct {
+	URI      string
+	MIMEType string
+	Data     []byte
+}
+
+// Response is the raw text answer and token usage of a generation call.
+type Response struct {
+	Text      string
+	Model     string
+	TokensIn  int
+	TokensOut int
+}
+
+// Provider executes generation calls against one LLM backend.
+type Provider interface {
+	Name() string
+	Generate(ctx context.Context, req Request) (Response, error)
+}
+
+// Registry routes models to providers by the longest matching model prefix.
+// Prefixes ending with "/" are routing-only and stripped before the call,
+// e.g. "openai/gpt-4o-mini" reaches the provider as "gpt-4o-mini".
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register binds a model prefix to a provider, replacing any previous binding.
+func (r *Registry) Register(prefix string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[strings.TrimSpace(prefix)] = provider
+}
+
+// Resolve returns the provider for a model and the model name it expects.
+func (r *Registry) Resolve(model string) (Provider, string, error) {
+	model = strings.TrimSpace(model)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefixes := make([]string, 0, len(r.providers))
+	for prefix := range r.providers {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(model, prefix) {
+			continue
+		}
+		if strings.HasSuffix(prefix, "/") {
+			return r.providers[prefix], strings.TrimPrefix(model, prefix), nil
+		}
+		return r.providers[prefix], model, nil
+	}
+	return nil, "", fmt.Errorf("%w: %q", ErrUnknownModel, model)
+}
+
+// Generate resolves the provider for req.Model and executes the call. The
+// returned Response.Model is the model as configured, including any routing prefix.
+func (r *Registry) Generate(ctx context.Context, req Request) (Response, error) {
+	provider, providerModel, err := r.Resolve(req.Model)
+	if err != nil {
+		return Response{}, err
+	}
+	configured := req.Model
+	req.Model = providerModel
+	resp, err := provider.Generate(ctx, req)
+	if err != nil {
+		return Response{}, fmt.Errorf("%s: %w", provider.Name(), err)
+	}
+	resp.Model = configured
+	return resp, nil
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegistryRoutesByLongestPrefixAndStripsRoutingPrefix(t *testing.T) {
+	gemini := NewFakeProvider()
+	openai := NewFakeProvider()
+	special := NewFakeProvider()
+	registry := NewRegistry()
+	registry.Register(GeminiPrefix, gemini)
+	registry.Register("openai/", openai)
+	registry.Register("openai/gpt-4o", special)
+
+	provider, model, err := registry.Resolve("gemini-2.0-flash")
+	if err != nil || provider != gemini || model != "gemini-2.0-flash" {
+		t.Fatalf("unexpected gemini routing: %v %q %v", provider, model, err)
+	}
+	provider, model, err = registry.Resolve("openai/gpt-4o-mini")
+	if err != nil || provider != special || model != "openai/gpt-4o-mini" {
+		t.Fatalf("expected longest prefix to win, got %v %q %v", provider, model, err)
+	}
+	provider, model, err = registry.Resolve("openai/o3-mini")
+	if err != nil || provider != openai || model != "o3-mini" {
+		t.Fatalf("expected stripped openai model, got %v %q %v", provider, model, err)
+	}
+	if _, _, err := registry.Resolve("claude-x"); !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("expected ErrUnknownModel, got %v", err)
+	}
+}
+
+func TestRegistryGenerateReportsConfiguredModel(t *testing.T) {
+	fake := NewFakeProvider(FakeReply{Text: `{"label":"not_cs","confidence":0.7}`})
+	registry := NewRegistry()
+	registry.Register(FakePrefix, fake)
+
+	resp, err := registry.Generate(context.Background(), Request{Model: "fake/local", Prompt: "p"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Model != "fake/local" || resp.Text != `{"label":"not_cs","confidence":0.7}` {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || calls[0].Model != "local" {
+		t.Fatalf("expected provider to receive stripped model, got %+v", calls)
+	}
+}
+
+func TestFakeProviderAnswersWithFirstSchemaLabel(t *testing.T) {
+	resp, err := NewFakeProvider().Generate(context.Background(), Request{
+		Model: "local",
+		ResponseSchema: map[string]any{
+			"properties": map[string]any{"label": map[string]any{"enum": []string{"cs_detected", "not_cs"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text != `{"confidence":1,"label":"cs_detected"}` {
+		t.Fatalf("unexpected text %q", resp.Text)
+	}
+}
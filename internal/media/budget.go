@@ -0,0 +1,275 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/config"
+	"github.com/funpot/funpot-go-core/internal/llm"
+)
+
+var ErrBudgetExceeded = errors.New("llm daily budget exceeded")
+
+// BudgetUsage reports consumption of one budget scope for the current UTC day.
+type BudgetUsage struct {
+	Scope        string
+	Tokens       int64
+	CostUSD      float64
+	TokenLimit   int64
+	CostLimitUSD float64
+}
+
+// Utilization returns the highest ratio of consumption to any configured cap.
+func (u BudgetUsage) Utilization() float64 {
+	ratio := 0.0
+	if u.TokenLimit > 0 {
+		ratio = float64(u.Tokens) / float64(u.TokenLimit)
+	}
+	if u.CostLimitUSD > 0 {
+		if costRatio := u.CostUSD / u.CostLimitUSD; costRatio > ratio {
+			ratio = costRatio
+		}
+	}
+	return ratio
+}
+
+// Budget tracks daily LLM token and cost consumption at global, per-model and
+// per-streamer level. Counters reset at UTC midnight. Spend is reserved before
+// a call and settled afterwards, so concurrent calls cannot overrun a cap;
+// sharing the counters through Redis extends that across nodes.
+type Budget struct {
+	cfg      config.LLMBudgetConfig
+	counters BudgetCounters
+	logger   *zap.Logger
+	nowFn    func() time.Time
+}
+
+func NewBudget(cfg config.LLMBudgetConfig) *Budget {
+	if cfg.SoftLimitRatio <= 0 || cfg.SoftLimitRatio > 1 {
+		cfg.SoftLimitRatio = 0.8
+	}
+	if cfg.DegradedIntervalFactor < 1 {
+		cfg.DegradedIntervalFactor = 1
+	}
+	if cfg.ImageTokens <= 0 {
+		cfg.ImageTokens = 258
+	}
+	if cfg.ClipTokens <= 0 {
+		cfg.ClipTokens = 3000
+	}
+	return &Budget{
+		cfg:      cfg,
+		counters: NewInMemoryBudgetCounters(),
+		logger:   zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithCounters shares spend across nodes, e.g. through Redis. Counter
+// failures outside Reserve are logged.
+func (b *Budget) WithCounters(counters BudgetCounters, logger *zap.Logger) {
+	if counters != nil {
+		b.counters = counters
+	}
+	if logger != nil {
+		b.logger = logger
+	}
+}
+
+// BudgetReservation is the spend reserved for one model call on Day.
+type BudgetReservation struct {
+	Day        string
+	StreamerID string
+	Model      string
+	TokensIn   int
+	TokensOut  int
+}
+
+// defaultOutputTokens is reserved for calls without a MaxTokens cap.
+const defaultOutputTokens = 8192
+
+// Reserve charges an estimate of req to every scope that applies to the
+// call, or rejects it when any scope would exceed its cap.
+func (b *Budget) Reserve(ctx context.Context, streamerID string, req llm.Request) (BudgetReservation, error) {
+	reservation := BudgetReservation{Day: b.day(), StreamerID: streamerID, Model: req.Model}
+	reservation.TokensIn, reservation.TokensOut = b.estimate(req)
+	cost := b.cost(req.Model, reservation.TokensIn, reservation.TokensOut)
+	keys := budgetKeys(streamerID, req.Model)
+	charges := make([]BudgetCharge, 0, len(keys))
+	for _, key := range keys {
+		limits := b.limits(key)
+		charges = append(charges, BudgetCharge{
+			Scope:        key,
+			Tokens:       int64(reservation.TokensIn + reservation.TokensOut),
+			CostUSD:      cost,
+			TokenLimit:   limits.TokenLimit,
+			CostLimitUSD: limits.CostLimitUSD,
+		})
+	}
+	exceeded, err := b.counters.Reserve(ctx, reservation.Day, charges)
+	if err != nil {
+		return BudgetReservation{}, fmt.Errorf("reserve llm budget: %w", err)
+	}
+	if exceeded != "" {
+		return BudgetReservation{}, fmt.Errorf("%w: %s", ErrBudgetExceeded, exceeded)
+	}
+	return reservation, nil
+}
+
+// Settle replaces a reservation with the tokens the call reported, on the
+// day it was reserved. Failed calls settle with zero tokens to release the
+// reservation.
+func (b *Budget) Settle(ctx context.Context, reservation BudgetReservation, tokensIn, tokensOut int) {
+	tokens := int64(tokensIn + tokensOut - reservation.TokensIn - reservation.TokensOut)
+	cost := b.cost(reservation.Model, tokensIn, tokensOut) - b.cost(reservation.Model, reservation.TokensIn, reservation.TokensOut)
+	if tokens == 0 && cost == 0 {
+		return
+	}
+	if err := b.counters.Add(ctx, reservation.Day, budgetKeys(reservation.StreamerID, reservation.Model), tokens, cost); err != nil {
+		b.logger.Warn("failed to settle llm budget",
+			zap.String("streamer_id", reservation.StreamerID),
+			zap.String("model", reservation.Model),
+			zap.Error(err),
+		)
+	}
+}
+
+// estimate returns the input and output tokens to reserve for req: the
+// prompt at about 4 characters per token plus a fixed amount per keyframe or
+// clip, and MaxTokens of output.
+func (b *Budget) estimate(req llm.Request) (int, int) {
+	tokensIn := (len(req.Prompt) + 3) / 4
+	for _, item := range req.Media {
+		if strings.HasPrefix(item.MIMEType, "image/") {
+			tokensIn += b.cfg.ImageTokens
+		} else {
+			tokensIn += b.cfg.ClipTokens
+		}
+	}
+	tokensOut := req.MaxTokens
+	if tokensOut <= 0 {
+		tokensOut = defaultOutputTokens
+	}
+	return tokensIn, tokensOut
+}
+
+// SamplingFactor returns how many scheduler intervals a streamer should wait
+// between cycles: 1 normally, DegradedIntervalFactor once any scope that
+// applies to the streamer crosses the soft limit.
+func (b *Budget) SamplingFactor(ctx context.Context, streamerID string) int {
+	spent, err := b.counters.Load(ctx, b.day())
+	if err != nil {
+		b.logger.Warn("failed to load llm budget", zap.Error(err))
+		return 1
+	}
+	keys := budgetKeys(streamerID, "")
+	for model := range b.cfg.ModelDailyTokens {
+		keys = append(keys, "model:"+model)
+	}
+	for model := range b.cfg.ModelDailyCostUSD {
+		keys = append(keys, "model:"+model)
+	}
+	for _, key := range keys {
+		if b.usage(key, spent[key]).Utilization() >= b.cfg.SoftLimitRatio {
+			return b.cfg.DegradedIntervalFactor
+		}
+	}
+	return 1
+}
+
+// Usage returns a snapshot of every scope with recorded consumption today.
+func (b *Budget) Usage(ctx context.Context) ([]BudgetUsage, error) {
+	spent, err := b.counters.Load(ctx, b.day())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]BudgetUsage, 0, len(spent))
+	for key, counter := range spent {
+		items = append(items, b.usage(key, counter))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Scope < items[j].Scope })
+	return items, nil
+}
+
+// RegisterMetrics exposes budget consumption as observable gauges.
+func (b *Budget) RegisterMetrics(meter metric.Meter) error {
+	tokens, err := meter.Int64ObservableGauge("funpot_llm_budget_tokens_used", metric.WithDescription("LLM tokens consumed today per budget scope"))
+	if err != nil {
+		return err
+	}
+	cost, err := meter.Float64ObservableGauge("funpot_llm_budget_cost_usd_used", metric.WithDescription("LLM cost in USD consumed today per budget scope"))
+	if err != nil {
+		return err
+	}
+	utilization, err := meter.Float64ObservableGauge("funpot_llm_budget_utilization", metric.WithDescription("Ratio of consumption to the tightest cap per budget scope"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		items, err := b.Usage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, usage := range items {
+			attrs := metric.WithAttributes(attribute.String("scope", usage.Scope))
+			observer.ObserveInt64(tokens, usage.Tokens, attrs)
+			observer.ObserveFloat64(cost, usage.CostUSD, attrs)
+			observer.ObserveFloat64(utilization, usage.Utilization(), attrs)
+		}
+		return nil
+	}, tokens, cost, utilization)
+	return err
+}
+
+func (b *Budget) day() string {
+	return b.nowFn().Format(time.DateOnly)
+}
+
+func (b *Budget) usage(key string, spent BudgetCounter) BudgetUsage {
+	usage := b.limits(key)
+	usage.Tokens = spent.Tokens
+	usage.CostUSD = spent.CostUSD
+	return usage
+}
+
+func (b *Budget) limits(key string) BudgetUsage {
+	usage := BudgetUsage{Scope: key}
+	switch {
+	case key == "global":
+		usage.TokenLimit = int64(b.cfg.GlobalDailyTokens)
+		usage.CostLimitUSD = b.cfg.GlobalDailyCostUSD
+	case strings.HasPrefix(key, "model:"):
+		model := strings.TrimPrefix(key, "model:")
+		usage.TokenLimit = int64(b.cfg.ModelDailyTokens[model])
+		usage.CostLimitUSD = b.cfg.ModelDailyCostUSD[model]
+	case strings.HasPrefix(key, "streamer:"):
+		usage.TokenLimit = int64(b.cfg.StreamerDailyTokens)
+		usage.CostLimitUSD = b.cfg.StreamerDailyCostUSD
+	}
+	return usage
+}
+
+func (b *Budget) cost(model string, tokensIn, tokensOut int) float64 {
+	return (float64(tokensIn)*b.cfg.InputPricePerMTok[model] + float64(tokensOut)*b.cfg.OutputPricePerMTok[model]) / 1_000_000
+}
+
+func budgetKeys(streamerID, model string) []string {
+	keys := []string{"global"}
+	if model = strings.TrimSpace(model); model != "" {
+		keys = append(keys, "model:"+model)
+	}
+	if streamerID = strings.TrimSpace(streamerID); streamerID != "" {
+		keys = append(keys, "streamer:"+streamerID)
+	}
+	return keys
+}
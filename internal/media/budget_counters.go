@@ -0,0 +1,232 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BudgetCounter is the spend of one budget scope on one day.
+type BudgetCounter struct {
+	Tokens  int64
+	CostUSD float64
+}
+
+// BudgetCharge adds Tokens and CostUSD to Scope unless that pushes it past
+// one of its caps; a zero cap is unlimited.
+type BudgetCharge struct {
+	Scope        string
+	Tokens       int64
+	CostUSD      float64
+	TokenLimit   int64
+	CostLimitUSD float64
+}
+
+// BudgetCounters stores daily LLM spend per budget scope.
+type BudgetCounters interface {
+	// Reserve applies every charge, or none of them when one would exceed a
+	// cap, in which case it returns that scope and cap. It is atomic for every
+	// caller sharing the counters.
+	Reserve(ctx context.Context, day string, charges []BudgetCharge) (string, error)
+	// Add adjusts the given scopes, e.g. to settle a reservation.
+	Add(ctx context.Context, day string, scopes []string, tokens int64, costUSD float64) error
+	Load(ctx context.Context, day string) (map[string]BudgetCounter, error)
+}
+
+// InMemoryBudgetCounters keeps spend in process for the latest day and the
+// one before it, so calls reserved before midnight can still settle.
+type InMemoryBudgetCounters struct {
+	mu     sync.Mutex
+	latest string
+	days   map[string]map[string]BudgetCounter
+}
+
+func NewInMemoryBudgetCounters() *InMemoryBudgetCounters {
+	return &InMemoryBudgetCounters{days: make(map[string]map[string]BudgetCounter)}
+}
+
+func (c *InMemoryBudgetCounters) Reserve(_ context.Context, day string, charges []BudgetCharge) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counters := c.dayLocked(day)
+
+	for _, charge := range charges {
+		if exceeded := exceedsCap(counters[charge.Scope], charge); exceeded != "" {
+			return exceeded, nil
+		}
+	}
+	for _, charge := range charges {
+		counter := counters[charge.Scope]
+		counter.Tokens += charge.Tokens
+		counter.CostUSD += charge.CostUSD
+		counters[charge.Scope] = counter
+	}
+	return "", nil
+}
+
+func (c *InMemoryBudgetCounters) Add(_ context.Context, day string, scopes []string, tokens int64, costUSD float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counters := c.dayLocked(day)
+
+	for _, scope := range scopes {
+		counter := counters[scope]
+		counter.Tokens += tokens
+		counter.CostUSD += costUSD
+		counters[scope] = counter
+	}
+	return nil
+}
+
+func (c *InMemoryBudgetCounters) Load(_ context.Context, day string) (map[string]BudgetCounter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counters := make(map[string]BudgetCounter, len(c.days[day]))
+	for scope, counter := range c.days[day] {
+		counters[scope] = counter
+	}
+	return counters, nil
+}
+
+// dayLocked returns the counters of day. Starting a new day drops every day
+// before the previous one.
+func (c *InMemoryBudgetCounters) dayLocked(day string) map[string]BudgetCounter {
+	if day > c.latest {
+		for old := range c.days {
+			if old < c.latest {
+				delete(c.days, old)
+			}
+		}
+		c.latest = day
+	}
+	counters, ok := c.days[day]
+	if !ok {
+		counters = make(map[string]BudgetCounter)
+		c.days[day] = counters
+	}
+	return counters
+}
+
+func exceedsCap(counter BudgetCounter, charge BudgetCharge) string {
+	if charge.TokenLimit > 0 && counter.Tokens+charge.Tokens > charge.TokenLimit {
+		return charge.Scope + " tokens"
+	}
+	if charge.CostLimitUSD > 0 && counter.CostUSD+charge.CostUSD > charge.CostLimitUSD {
+		return charge.Scope + " cost"
+	}
+	return ""
+}
+
+// budgetRetention keeps a day's counters around for metrics after midnight.
+const budgetRetention = 48 * time.Hour
+
+// reserveBudgetScript checks every charge against the day's hash and applies
+// them only when none exceeds a cap. ARGV holds the retention in seconds and
+// then scope, tokens, cost, token limit and cost limit per charge.
+var reserveBudgetScript = redis.NewScript(`
+for i = 2, #ARGV, 5 do
+	local scope = ARGV[i]
+	local tokens = tonumber(redis.call("HGET", KEYS[1], scope .. ":tokens") or "0")
+	local cost = tonumber(redis.call("HGET", KEYS[1], scope .. ":cost") or "0")
+	local tokenLimit = tonumber(ARGV[i + 3])
+	local costLimit = tonumber(ARGV[i + 4])
+	if tokenLimit > 0 and tokens + tonumber(ARGV[i + 1]) > tokenLimit then
+		return scope .. " tokens"
+	end
+	if costLimit > 0 and cost + tonumber(ARGV[i + 2]) > costLimit then
+		return scope .. " cost"
+	end
+end
+for i = 2, #ARGV, 5 do
+	redis.call("HINCRBY", KEYS[1], ARGV[i] .. ":tokens", ARGV[i + 1])
+	redis.call("HINCRBYFLOAT", KEYS[1], ARGV[i] .. ":cost", ARGV[i + 2])
+end
+redis.call("EXPIRE", KEYS[1], ARGV[1])
+return ""
+`)
+
+// RedisBudgetCounters shares spend across nodes in one Redis hash per day
+// with "<scope>:tokens" and "<scope>:cost" fields.
+type RedisBudgetCounters struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisBudgetCounters(client redis.UniversalClient, keyPrefix string) (*RedisBudgetCounters, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = "funpot:llm-budget"
+	}
+	return &RedisBudgetCounters{client: client, keyPrefix: keyPrefix}, nil
+}
+
+func (c *RedisBudgetCounters) key(day string) string {
+	return c.keyPrefix + ":" + day
+}
+
+func (c *RedisBudgetCounters) Reserve(ctx context.Context, day string, charges []BudgetCharge) (string, error) {
+	args := make([]any, 0, 1+5*len(charges))
+	args = append(args, int64(budgetRetention/time.Second))
+	for _, charge := range charges {
+		args = append(args,
+			charge.Scope,
+			charge.Tokens,
+			formatCost(charge.CostUSD),
+			charge.TokenLimit,
+			formatCost(charge.CostLimitUSD),
+		)
+	}
+	return reserveBudgetScript.Run(ctx, c.client, []string{c.key(day)}, args...).Text()
+}
+
+func (c *RedisBudgetCounters) Add(ctx context.Context, day string, scopes []string, tokens int64, costUSD float64) error {
+	key := c.key(day)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, scope := range scopes {
+			pipe.HIncrBy(ctx, key, scope+":tokens", tokens)
+			pipe.HIncrByFloat(ctx, key, scope+":cost", costUSD)
+		}
+		pipe.Expire(ctx, key, budgetRetention)
+		return nil
+	})
+	return err
+}
+
+func (c *RedisBudgetCounters) Load(ctx context.Context, day string) (map[string]BudgetCounter, error) {
+	fields, err := c.client.HGetAll(ctx, c.key(day)).Result()
+	if err != nil {
+		return nil, err
+	}
+	counters := make(map[string]BudgetCounter)
+	for field, value := range fields {
+		cut := strings.LastIndex(field, ":")
+		if cut < 0 {
+			continue
+		}
+		scope := field[:cut]
+		counter := counters[scope]
+		switch field[cut+1:] {
+		case "tokens":
+			counter.Tokens, err = strconv.ParseInt(value, 10, 64)
+		case "cost":
+			counter.CostUSD, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			return nil, err
+		}
+		counters[scope] = counter
+	}
+	return counters, nil
+}
+
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
@@ -0,0 +1,173 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	metricSdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/funpot/funpot-go-core/internal/config"
+	"github.com/funpot/funpot-go-core/internal/llm"
+)
+
+func TestBudgetReserveEnforcesScopes(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	redisCounters, err := NewRedisBudgetCounters(client, "test")
+	if err != nil {
+		t.Fatalf("NewRedisBudgetCounters() error = %v", err)
+	}
+
+	counters := map[string]BudgetCounters{
+		"memory": NewInMemoryBudgetCounters(),
+		"redis":  redisCounters,
+	}
+	for name, counter := range counters {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			budget := NewBudget(config.LLMBudgetConfig{
+				GlobalDailyTokens:   10_000,
+				StreamerDailyTokens: 1_000,
+				ModelDailyCostUSD:   map[string]float64{"gemini-2.0-flash": 0.01},
+				OutputPricePerMTok:  map[string]float64{"gemini-2.0-flash": 10},
+			})
+			budget.WithCounters(counter, nil)
+
+			reservation, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "gemini-2.0-flash", MaxTokens: 500})
+			if err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			// The reservation alone leaves no room for a second call.
+			if _, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "gemini-2.0-flash", MaxTokens: 501}); !errors.Is(err, ErrBudgetExceeded) {
+				t.Fatalf("expected reserved tokens to count against the streamer cap, got %v", err)
+			}
+			budget.Settle(ctx, reservation, 600, 100)
+
+			if _, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "gemini-2.0-flash", MaxTokens: 500}); !errors.Is(err, ErrBudgetExceeded) {
+				t.Fatalf("expected streamer token cap to reject, got %v", err)
+			}
+			reservation, err = budget.Reserve(ctx, "str-2", llm.Request{Model: "other-model", MaxTokens: 500})
+			if err != nil {
+				t.Fatalf("expected other streamer to be allowed, got %v", err)
+			}
+			budget.Settle(ctx, reservation, 0, 0)
+
+			spend(budget, "str-2", "gemini-2.0-flash", 0, 900)
+			if _, err := budget.Reserve(ctx, "str-3", llm.Request{Model: "gemini-2.0-flash", MaxTokens: 10}); !errors.Is(err, ErrBudgetExceeded) {
+				t.Fatalf("expected model cost cap to reject, got %v", err)
+			}
+
+			usage, err := budget.Usage(ctx)
+			if err != nil {
+				t.Fatalf("Usage() error = %v", err)
+			}
+			for _, item := range usage {
+				if item.Scope == "global" && item.Tokens != 1600 {
+					t.Fatalf("expected released reservations to leave 1600 global tokens, got %+v", item)
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetResetsAtUTCMidnight(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 23, 59, 0, 0, time.UTC)
+	budget := NewBudget(config.LLMBudgetConfig{GlobalDailyTokens: 100})
+	budget.nowFn = func() time.Time { return now }
+
+	spend(budget, "str-1", "m", 90, 0)
+	if _, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "m", MaxTokens: 11}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected global cap to reject, got %v", err)
+	}
+	reservation, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "m", MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	// The call finishes after midnight and releases its reservation from the
+	// day it was made.
+	now = now.Add(2 * time.Minute)
+	budget.Settle(ctx, reservation, 0, 0)
+	if _, err := budget.Reserve(ctx, "str-1", llm.Request{Model: "m", MaxTokens: 100}); err != nil {
+		t.Fatalf("expected counters to reset on new day, got %v", err)
+	}
+	spent, _ := budget.counters.Load(ctx, "2025-01-01")
+	if spent["global"].Tokens != 90 {
+		t.Fatalf("expected the reservation released on the previous day, got %+v", spent["global"])
+	}
+}
+
+func TestBudgetReserveEstimatesInput(t *testing.T) {
+	ctx := context.Background()
+	budget := NewBudget(config.LLMBudgetConfig{
+		ImageTokens:       250,
+		ClipTokens:        3000,
+		InputPricePerMTok: map[string]float64{"m": 1},
+	})
+
+	reservation, err := budget.Reserve(ctx, "str-1", llm.Request{
+		Model:  "m",
+		Prompt: strings.Repeat("a", 400),
+		Media:  []llm.Media{{MIMEType: "image/jpeg"}, {MIMEType: "image/jpeg"}, {MIMEType: "video/mp2t"}},
+	})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if reservation.TokensIn != 100+2*250+3000 || reservation.TokensOut != defaultOutputTokens {
+		t.Fatalf("unexpected reservation %+v", reservation)
+	}
+	usage, _ := budget.Usage(ctx)
+	if usage[0].Scope != "global" || usage[0].CostUSD != 0.0036 {
+		t.Fatalf("expected input cost reserved, got %+v", usage)
+	}
+}
+
+// spend records usage that was never reserved.
+func spend(budget *Budget, streamerID, model string, tokensIn, tokensOut int) {
+	budget.Settle(context.Background(), BudgetReservation{Day: budget.day(), StreamerID: streamerID, Model: model}, tokensIn, tokensOut)
+}
+
+func TestBudgetSamplingFactorAndMetrics(t *testing.T) {
+	budget := NewBudget(config.LLMBudgetConfig{
+		StreamerDailyTokens:    100,
+		SoftLimitRatio:         0.8,
+		DegradedIntervalFactor: 3,
+	})
+	spend(budget, "str-1", "m", 85, 0)
+
+	if got := budget.SamplingFactor(context.Background(), "str-1"); got != 3 {
+		t.Fatalf("SamplingFactor(str-1) = %d, want 3", got)
+	}
+	if got := budget.SamplingFactor(context.Background(), "str-2"); got != 1 {
+		t.Fatalf("SamplingFactor(str-2) = %d, want 1", got)
+	}
+
+	reader := metricSdk.NewManualReader()
+	provider := metricSdk.NewMeterProvider(metricSdk.WithReader(reader))
+	if err := budget.RegisterMetrics(provider.Meter("test")); err != nil {
+		t.Fatalf("RegisterMetrics() error = %v", err)
+	}
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	found := false
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == "funpot_llm_budget_utilization" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected funpot_llm_budget_utilization metric")
+	}
+}
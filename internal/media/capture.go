@@ -0,0 +1,69 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+// StreamerLookup resolves streamer IDs; *streamers.Service satisfies it.
+type StreamerLookup interface {
+	Get(ctx context.Context, id string) (streamers.Streamer, error)
+}
+
+type CaptureConfig struct {
+	// URLTemplate is the ffmpeg input with "{username}" replaced by the
+	// streamer's username, e.g. an HLS relay URL.
+	URLTemplate string
+	Duration    time.Duration
+	WorkDir     string
+}
+
+// FFmpegCapture records a chunk of each streamer's live stream with ffmpeg.
+// Every streamer has a single chunk file that the next cycle overwrites; the
+// worker lock keeps cycles of one streamer from overlapping.
+type FFmpegCapture struct {
+	runner    FFmpegRunner
+	streamers StreamerLookup
+	cfg       CaptureConfig
+}
+
+func NewFFmpegCapture(runner FFmpegRunner, lookup StreamerLookup, cfg CaptureConfig) *FFmpegCapture {
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = os.TempDir()
+	}
+	return &FFmpegCapture{runner: runner, streamers: lookup, cfg: cfg}
+}
+
+func (c *FFmpegCapture) Capture(ctx context.Context, streamerID string) (ChunkRef, error) {
+	streamer, err := c.streamers.Get(ctx, streamerID)
+	if err != nil {
+		return ChunkRef{}, err
+	}
+	dir := filepath.Join(c.cfg.WorkDir, "funpot-capture")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ChunkRef{}, err
+	}
+	input := strings.ReplaceAll(c.cfg.URLTemplate, "{username}", url.PathEscape(streamer.Username))
+	output := filepath.Join(dir, sanitizePathPart(streamerID)+".ts")
+	if err := c.runner.Run(ctx,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		"-t", strconv.FormatFloat(c.cfg.Duration.Seconds(), 'f', -1, 64),
+		"-c", "copy",
+		output,
+	); err != nil {
+		return ChunkRef{}, fmt.Errorf("capture stream of %s: %w", streamer.Username, err)
+	}
+	return ChunkRef{Reference: output}, nil
+}
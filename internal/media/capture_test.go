@@ -0,0 +1,60 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+type fakeLookup map[string]streamers.Streamer
+
+func (f fakeLookup) Get(_ context.Context, id string) (streamers.Streamer, error) {
+	streamer, ok := f[id]
+	if !ok {
+		return streamers.Streamer{}, streamers.ErrNotFound
+	}
+	return streamer, nil
+}
+
+func TestFFmpegCapture(t *testing.T) {
+	runner := &fakeFFmpeg{}
+	lookup := fakeLookup{"str-1": {ID: "str-1", Username: "best_streamer"}}
+	capture := NewFFmpegCapture(runner, lookup, CaptureConfig{
+		URLTemplate: "https://relay.example/{username}/index.m3u8",
+		Duration:    15 * time.Second,
+		WorkDir:     t.TempDir(),
+	})
+
+	chunk, err := capture.Capture(context.Background(), "str-1")
+	if err != nil {
+		t.Fatalf("Capture() error = %v", err)
+	}
+	if filepath.Base(chunk.Reference) != "str-1.ts" {
+		t.Fatalf("unexpected chunk %s", chunk.Reference)
+	}
+	if _, err := os.Stat(chunk.Reference); err != nil {
+		t.Fatalf("chunk was not written: %v", err)
+	}
+	args := runner.calls[0]
+	if !containsArgs(args, "-i", "https://relay.example/best_streamer/index.m3u8") || !containsArgs(args, "-t", "15") {
+		t.Fatalf("unexpected ffmpeg args %v", args)
+	}
+
+	if _, err := capture.Capture(context.Background(), "missing"); !errors.Is(err, streamers.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func containsArgs(args []string, flag, value string) bool {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag && args[i+1] == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/llm"
+)
+
+// Generator executes a model call; *llm.Registry satisfies it.
+type Generator interface {
+	Generate(ctx context.Context, req llm.Request) (llm.Response, error)
+}
+
+// BudgetGuard reserves LLM spend before a call and settles it with the
+// reported usage afterwards; *Budget satisfies it.
+type BudgetGuard interface {
+	Reserve(ctx context.Context, streamerID string, req llm.Request) (BudgetReservation, error)
+	Settle(ctx context.Context, reservation BudgetReservation, tokensIn, tokensOut int)
+}
+
+// LLMClassifier classifies chunks through the provider registry. When the
+// primary model fails, exceeds the prompt timeout or is over budget and the
+// prompt version has a fallback model, the call is retried once against the
+// fallback.
+type LLMClassifier struct {
+	generator Generator
+	budget    BudgetGuard
+	nowFn     func() time.Time
+}
+
+func NewLLMClassifier(generator Generator) *LLMClassifier {
+	return &LLMClassifier{generator: generator, nowFn: time.Now}
+}
+
+// WithBudget enforces LLM spend limits on every model call, the fallback
+// included.
+func (c *LLMClassifier) WithBudget(budget BudgetGuard) {
+	c.budget = budget
+}
+
+func (c *LLMClassifier) Classify(ctx context.Context, req ClassifyRequest) (StageAClassification, error) {
+	started := c.nowFn()
+	resp, err := c.attempt(ctx, req, req.Prompt.Model)
+	fallback := strings.TrimSpace(req.Prompt.FallbackModel)
+	if err != nil && fallback != "" && ctx.Err() == nil {
+		resp, err = c.attempt(ctx, req, fallback)
+		if err != nil {
+			err = fmt.Errorf("fallback model %s: %w", fallback, err)
+		}
+	}
+	if err != nil {
+		return StageAClassification{}, err
+	}
+	return StageAClassification{
+		RawResponse: resp.Text,
+		Model:       resp.Model,
+		TokensIn:    resp.TokensIn,
+		TokensOut:   resp.TokensOut,
+		Latency:     c.nowFn().Sub(started),
+	}, nil
+}
+
+func (c *LLMClassifier) attempt(ctx context.Context, req ClassifyRequest, model string) (llm.Response, error) {
+	if strings.TrimSpace(model) == "" {
+		return llm.Response{}, errors.New("prompt model is not configured")
+	}
+	if req.Prompt.TimeoutMS > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Prompt.TimeoutMS)*time.Millisecond)
+		defer cancel()
+	}
+	media, err := requestMedia(req)
+	if err != nil {
+		return llm.Response{}, err
+	}
+	llmReq := llm.Request{
+		Model:          model,
+		Prompt:         req.Prompt.Template,
+		Temperature:    req.Prompt.Temperature,
+		MaxTokens:      req.Prompt.MaxTokens,
+		Media:          media,
+		ResponseSchema: req.Prompt.OutputSchema.JSONSchema(),
+	}
+	var reservation BudgetReservation
+	if c.budget != nil {
+		if reservation, err = c.budget.Reserve(ctx, req.StreamerID, llmReq); err != nil {
+			return llm.Response{}, err
+		}
+	}
+	resp, err := c.generator.Generate(ctx, llmReq)
+	if c.budget != nil {
+		c.budget.Settle(context.WithoutCancel(ctx), reservation, resp.TokensIn, resp.TokensOut)
+	}
+	return resp, err
+}
+
+// requestMedia inlines extracted keyframes and otherwise references the chunk.
+func requestMedia(req ClassifyRequest) ([]llm.Media, error) {
+	if len(req.Frames) == 0 {
+		if req.Chunk.Reference == "" {
+			return nil, nil
+		}
+		return []llm.Media{{URI: req.Chunk.Reference, MIMEType: mime.TypeByExtension(path.Ext(req.Chunk.Reference))}}, nil
+	}
+	media := make([]llm.Media, 0, len(req.Frames))
+	for _, frame := range req.Frames {
+		data, err := os.ReadFile(frame)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, llm.Media{URI: frame, MIMEType: mime.TypeByExtension(path.Ext(frame)), Data: data})
+	}
+	return media, nil
+}
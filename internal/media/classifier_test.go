@@ -0,0 +1,109 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/config"
+	"github.com/funpot/funpot-go-core/internal/llm"
+	"github.com/funpot/funpot-go-core/internal/prompts"
+)
+
+type slowProvider struct{}
+
+func (slowProvider) Name() string { return "slow" }
+
+func (slowProvider) Generate(ctx context.Context, _ llm.Request) (llm.Response, error) {
+	<-ctx.Done()
+	return llm.Response{}, ctx.Err()
+}
+
+func TestLLMClassifierFallsBackWhenPrimaryTimesOut(t *testing.T) {
+	fallback := llm.NewFakeProvider(llm.FakeReply{Text: `{"label":"cs_detected","confidence":0.8}`})
+	registry := llm.NewRegistry()
+	registry.Register("slow/", slowProvider{})
+	registry.Register(llm.FakePrefix, fallback)
+
+	classifier := NewLLMClassifier(registry)
+	result, err := classifier.Classify(context.Background(), ClassifyRequest{
+		Chunk: ChunkRef{Reference: "/tmp/chunk.mp4"},
+		Prompt: prompts.PromptVersion{
+			Template:      "classify",
+			Model:         "slow/model",
+			FallbackModel: "fake/backup",
+			TimeoutMS:     10,
+			OutputSchema:  prompts.DefaultOutputSchema(prompts.StageA),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if result.Model != "fake/backup" || result.RawResponse == "" {
+		t.Fatalf("expected fallback response, got %+v", result)
+	}
+	calls := fallback.Calls()
+	if len(calls) != 1 || len(calls[0].Media) != 1 || calls[0].Media[0].URI != "/tmp/chunk.mp4" || calls[0].ResponseSchema == nil {
+		t.Fatalf("unexpected fallback calls %+v", calls)
+	}
+}
+
+func TestLLMClassifierReturnsPrimaryErrorWithoutFallback(t *testing.T) {
+	registry := llm.NewRegistry()
+	registry.Register(llm.FakePrefix, llm.NewFakeProvider(llm.FakeReply{Err: errors.New("unavailable")}))
+
+	_, err := NewLLMClassifier(registry).Classify(context.Background(), ClassifyRequest{
+		Prompt: prompts.PromptVersion{Model: "fake/primary", TimeoutMS: int(time.Second / time.Millisecond)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("expected primary error, got %v", err)
+	}
+}
+
+func TestLLMClassifierEnforcesBudgetOnFallback(t *testing.T) {
+	ctx := context.Background()
+	registry := llm.NewRegistry()
+	registry.Register(llm.FakePrefix, llm.NewFakeProvider(llm.FakeReply{Text: `{"label":"cs_detected","confidence":0.8}`}))
+	budget := NewBudget(config.LLMBudgetConfig{
+		ModelDailyTokens: map[string]int{"fake/primary": 100, "fake/backup": 100},
+	})
+	classifier := NewLLMClassifier(registry)
+	classifier.WithBudget(budget)
+	req := ClassifyRequest{
+		StreamerID: "str-1",
+		Prompt: prompts.PromptVersion{
+			Model:         "fake/primary",
+			FallbackModel: "fake/backup",
+			MaxTokens:     60,
+			OutputSchema:  prompts.DefaultOutputSchema(prompts.StageA),
+		},
+	}
+
+	// The primary model cap is spent, so the call goes to the fallback.
+	spend(budget, "str-9", "fake/primary", 100, 0)
+	result, err := classifier.Classify(ctx, req)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if result.Model != "fake/backup" {
+		t.Fatalf("expected fallback model, got %+v", result)
+	}
+	// The fake reports the reply length as output tokens.
+	usage, _ := budget.Usage(ctx)
+	settled := false
+	for _, item := range usage {
+		if item.Scope == "model:fake/backup" {
+			settled = item.Tokens == 40
+		}
+	}
+	if !settled {
+		t.Fatalf("expected the fallback reservation settled to 40 tokens, got %+v", usage)
+	}
+
+	spend(budget, "str-9", "fake/backup", 50, 0)
+	if _, err := classifier.Classify(ctx, req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected both models over budget, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+)
+
+// DifferenceHash computes a 64-bit dHash: the image is reduced to a 9x8
+// grayscale grid and each bit records whether a cell is brighter than its
+// right neighbour. Similar frames produce hashes with a small Hamming distance.
+func DifferenceHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return 0
+	}
+
+	var grid [rows][cols]float64
+	for row := 0; row < rows; row++ {
+		y0, y1 := bounds.Min.Y+row*height/rows, bounds.Min.Y+(row+1)*height/rows
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for col := 0; col < cols; col++ {
+			x0, x1 := bounds.Min.X+col*width/cols, bounds.Min.X+(col+1)*width/cols
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+				}
+			}
+			grid[row][col] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	var hash uint64
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols-1; col++ {
+			hash <<= 1
+			if grid[row][col] > grid[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance counts the differing bits of two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,223 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PreprocessModeKeyframes = "keyframes"
+	PreprocessModeClip      = "clip"
+)
+
+var ErrChunkUnchanged = errors.New("chunk frames unchanged since previous cycle")
+
+// FFmpegRunner executes ffmpeg with the given arguments.
+type FFmpegRunner interface {
+	Run(ctx context.Context, args ...string) error
+}
+
+// ExecFFmpegRunner runs the ffmpeg binary found at Binary (or on PATH).
+type ExecFFmpegRunner struct {
+	Binary string
+}
+
+func (r ExecFFmpegRunner) Run(ctx context.Context, args ...string) error {
+	binary := r.Binary
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	output, err := exec.CommandContext(ctx, binary, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(lastLine(string(output))))
+	}
+	return nil
+}
+
+// PreparedChunk is a captured chunk reduced to what is sent to the classifier.
+type PreparedChunk struct {
+	// Chunk is the downscaled clip in clip mode and the original chunk otherwise.
+	Chunk  ChunkRef
+	Frames []string
+	Hashes []uint64
+	// Unchanged is set when every frame matches the last classified cycle.
+	Unchanged bool
+	dir       string
+}
+
+type PreprocessConfig struct {
+	Mode       string
+	FrameCount int
+	// Width is the downscaled frame width in pixels; height keeps the aspect ratio.
+	Width int
+	// HashThreshold is the maximum per-frame Hamming distance still treated as
+	// unchanged. A negative value disables change detection.
+	HashThreshold int
+	WorkDir       string
+}
+
+// Preprocessor extracts keyframes (and optionally a downscaled clip) from
+// captured chunks with ffmpeg and detects cycles whose frames did not change.
+type Preprocessor struct {
+	runner FFmpegRunner
+	cfg    PreprocessConfig
+	mu     sync.Mutex
+	last   map[string][]uint64
+	nowFn  func() time.Time
+}
+
+func NewPreprocessor(runner FFmpegRunner, cfg PreprocessConfig) *Preprocessor {
+	if cfg.Mode != PreprocessModeClip {
+		cfg.Mode = PreprocessModeKeyframes
+	}
+	if cfg.FrameCount <= 0 {
+		cfg.FrameCount = 4
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = 512
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = os.TempDir()
+	}
+	return &Preprocessor{
+		runner: runner,
+		cfg:    cfg,
+		last:   make(map[string][]uint64),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+func (p *Preprocessor) Prepare(ctx context.Context, streamerID string, chunk ChunkRef) (PreparedChunk, error) {
+	dir, err := os.MkdirTemp(p.cfg.WorkDir, fmt.Sprintf("funpot-%s-%d-", sanitizePathPart(streamerID), p.nowFn().Unix()))
+	if err != nil {
+		return PreparedChunk{}, err
+	}
+	prepared := PreparedChunk{Chunk: chunk, dir: dir}
+
+	scale := fmt.Sprintf("scale=%d:-2", p.cfg.Width)
+	if err := p.runner.Run(ctx,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", chunk.Reference,
+		"-vf", "select='eq(pict_type,I)',"+scale,
+		"-vsync", "vfr",
+		"-frames:v", strconv.Itoa(p.cfg.FrameCount),
+		filepath.Join(dir, "frame-%02d.jpg"),
+	); err != nil {
+		_ = os.RemoveAll(dir)
+		return PreparedChunk{}, err
+	}
+
+	frames, err := filepath.Glob(filepath.Join(dir, "frame-*.jpg"))
+	if err != nil || len(frames) == 0 {
+		_ = os.RemoveAll(dir)
+		return PreparedChunk{}, fmt.Errorf("ffmpeg extracted no keyframes from %s", chunk.Reference)
+	}
+	sort.Strings(frames)
+	prepared.Frames = frames
+	for _, frame := range frames {
+		hash, err := hashFile(frame)
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return PreparedChunk{}, err
+		}
+		prepared.Hashes = append(prepared.Hashes, hash)
+	}
+
+	if p.unchanged(streamerID, prepared.Hashes) {
+		prepared.Unchanged = true
+		return prepared, nil
+	}
+
+	if p.cfg.Mode == PreprocessModeClip {
+		clip := filepath.Join(dir, "clip.mp4")
+		if err := p.runner.Run(ctx,
+			"-hide_banner", "-loglevel", "error", "-y",
+			"-i", chunk.Reference,
+			"-vf", scale,
+			"-an", "-c:v", "libx264", "-preset", "veryfast", "-crf", "32",
+			clip,
+		); err != nil {
+			_ = os.RemoveAll(dir)
+			return PreparedChunk{}, err
+		}
+		prepared.Chunk = ChunkRef{Reference: clip}
+		prepared.Frames = nil
+	}
+	return prepared, nil
+}
+
+// Complete removes the prepared files and, when the chunk was classified,
+// remembers its frame hashes for change detection in the next cycle.
+func (p *Preprocessor) Complete(streamerID string, prepared PreparedChunk, classified bool) {
+	if prepared.dir != "" {
+		_ = os.RemoveAll(prepared.dir)
+	}
+	if !classified || len(prepared.Hashes) == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.last[streamerID] = append([]uint64(nil), prepared.Hashes...)
+	p.mu.Unlock()
+}
+
+func (p *Preprocessor) unchanged(streamerID string, hashes []uint64) bool {
+	if p.cfg.HashThreshold < 0 {
+		return false
+	}
+	p.mu.Lock()
+	previous := p.last[streamerID]
+	p.mu.Unlock()
+	if len(previous) == 0 || len(previous) != len(hashes) {
+		return false
+	}
+	for i := range hashes {
+		if HammingDistance(previous[i], hashes[i]) > p.cfg.HashThreshold {
+			return false
+		}
+	}
+	return true
+}
+
+func hashFile(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return 0, fmt.Errorf("decode frame %s: %w", filepath.Base(path), err)
+	}
+	return DifferenceHash(img), nil
+}
+
+func sanitizePathPart(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, value)
+}
+
+func lastLine(output string) string {
+	output = strings.TrimSpace(output)
+	if idx := strings.LastIndex(output, "\n"); idx >= 0 {
+		return output[idx+1:]
+	}
+	return output
+}
@@ -0,0 +1,129 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFFmpeg writes generated frames to the output pattern instead of running ffmpeg.
+type fakeFFmpeg struct {
+	frames   []image.Image
+	calls    [][]string
+	failWith error
+}
+
+func (f *fakeFFmpeg) Run(_ context.Context, args ...string) error {
+	f.calls = append(f.calls, args)
+	if f.failWith != nil {
+		return f.failWith
+	}
+	output := args[len(args)-1]
+	if !strings.Contains(output, "%02d") {
+		return os.WriteFile(output, []byte("clip"), 0o600)
+	}
+	for i, frame := range f.frames {
+		file, err := os.Create(fmt.Sprintf(output, i+1))
+		if err != nil {
+			return err
+		}
+		if err := jpeg.Encode(file, frame, nil); err != nil {
+			file.Close()
+			return err
+		}
+		file.Close()
+	}
+	return nil
+}
+
+func gradientFrame(reverse bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 64, 36))
+	for y := 0; y < 36; y++ {
+		for x := 0; x < 64; x++ {
+			value := uint8(x * 4)
+			if reverse {
+				value = 255 - value
+			}
+			img.SetGray(x, y, color.Gray{Y: value})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHashDistinguishesFrames(t *testing.T) {
+	a := DifferenceHash(gradientFrame(false))
+	b := DifferenceHash(gradientFrame(true))
+	if HammingDistance(a, DifferenceHash(gradientFrame(false))) != 0 {
+		t.Fatal("expected identical frames to hash identically")
+	}
+	if HammingDistance(a, b) < 32 {
+		t.Fatalf("expected distant hashes, got distance %d", HammingDistance(a, b))
+	}
+}
+
+func TestPreprocessorSkipsUnchangedFramesAfterClassification(t *testing.T) {
+	runner := &fakeFFmpeg{frames: []image.Image{gradientFrame(false), gradientFrame(false)}}
+	preprocessor := NewPreprocessor(runner, PreprocessConfig{FrameCount: 2, HashThreshold: 4, WorkDir: t.TempDir()})
+
+	first, err := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if first.Unchanged || len(first.Frames) != 2 || len(first.Hashes) != 2 {
+		t.Fatalf("unexpected first cycle %+v", first)
+	}
+	preprocessor.Complete("str-1", first, false)
+	if _, err := os.Stat(filepath.Dir(first.Frames[0])); !os.IsNotExist(err) {
+		t.Fatalf("expected work dir to be removed, stat err = %v", err)
+	}
+
+	second, _ := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"})
+	if second.Unchanged {
+		t.Fatal("expected no skip before any cycle was classified")
+	}
+	preprocessor.Complete("str-1", second, true)
+
+	third, _ := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"})
+	if !third.Unchanged {
+		t.Fatal("expected unchanged frames to be detected")
+	}
+	preprocessor.Complete("str-1", third, false)
+
+	runner.frames = []image.Image{gradientFrame(true), gradientFrame(true)}
+	fourth, _ := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"})
+	if fourth.Unchanged {
+		t.Fatal("expected changed frames to be classified")
+	}
+	preprocessor.Complete("str-1", fourth, true)
+}
+
+func TestPreprocessorClipModeProducesDownscaledClip(t *testing.T) {
+	runner := &fakeFFmpeg{frames: []image.Image{gradientFrame(false)}}
+	preprocessor := NewPreprocessor(runner, PreprocessConfig{Mode: PreprocessModeClip, Width: 320, WorkDir: t.TempDir()})
+
+	prepared, err := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer preprocessor.Complete("str-1", prepared, true)
+	if filepath.Base(prepared.Chunk.Reference) != "clip.mp4" || len(prepared.Frames) != 0 {
+		t.Fatalf("expected clip reference, got %+v", prepared)
+	}
+	if len(runner.calls) != 2 || !strings.Contains(strings.Join(runner.calls[1], " "), "scale=320:-2") {
+		t.Fatalf("unexpected ffmpeg calls %v", runner.calls)
+	}
+}
+
+func TestPreprocessorReportsRunnerErrors(t *testing.T) {
+	preprocessor := NewPreprocessor(&fakeFFmpeg{failWith: errors.New("boom")}, PreprocessConfig{WorkDir: t.TempDir()})
+	if _, err := preprocessor.Prepare(context.Background(), "str-1", ChunkRef{Reference: "chunk.ts"}); err == nil {
+		t.Fatal("expected runner error")
+	}
+}
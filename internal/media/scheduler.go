@@ -0,0 +1,121 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+// StreamerStatusApproved selects streamers eligible for analysis cycles.
+const StreamerStatusApproved = "approved"
+
+type StreamerProcessor interface {
+	ProcessStreamer(ctx context.Context, streamerID string) (streamers.LLMDecision, error)
+}
+
+type StreamerSource interface {
+	ListByStatus(ctx context.Context, status string) []streamers.Streamer
+}
+
+type SamplingAdvisor interface {
+	SamplingFactor(ctx context.Context, streamerID string) int
+}
+
+type SchedulerConfig struct {
+	Interval time.Duration
+	GameID   string
+}
+
+// Scheduler periodically starts analysis cycles for approved streamers.
+type Scheduler struct {
+	processor StreamerProcessor
+	source    StreamerSource
+	switches  Switches
+	sampling  SamplingAdvisor
+	logger    *zap.Logger
+	interval  time.Duration
+	gameID    string
+	// skipTicks holds how many upcoming ticks a streamer sits out while its
+	// sampling interval is degraded.
+	skipTicks map[string]int
+}
+
+func NewScheduler(processor StreamerProcessor, source StreamerSource, logger *zap.Logger, cfg SchedulerConfig) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Scheduler{
+		processor: processor,
+		source:    source,
+		logger:    logger,
+		interval:  cfg.Interval,
+		gameID:    strings.TrimSpace(cfg.GameID),
+		skipTicks: make(map[string]int),
+	}
+}
+
+// WithSwitches makes the scheduler skip streamers whose pipeline is paused.
+func (s *Scheduler) WithSwitches(switches Switches) {
+	s.switches = switches
+}
+
+// WithSampling stretches per-streamer sampling intervals, e.g. when the LLM
+// budget is close to its cap.
+func (s *Scheduler) WithSampling(sampling SamplingAdvisor) {
+	s.sampling = sampling
+}
+
+// Run executes cycles every interval until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce processes every eligible streamer once and returns how many cycles ran.
+func (s *Scheduler) RunOnce(ctx context.Context) int {
+	processed := 0
+	for _, item := range s.source.ListByStatus(ctx, StreamerStatusApproved) {
+		if ctx.Err() != nil {
+			return processed
+		}
+		if s.switches != nil && s.switches.Resolve(ctx, item.ID, s.gameID).Paused {
+			continue
+		}
+		if s.skipTicks[item.ID] > 0 {
+			s.skipTicks[item.ID]--
+			continue
+		}
+		if s.sampling != nil {
+			if factor := s.sampling.SamplingFactor(ctx, item.ID); factor > 1 {
+				s.skipTicks[item.ID] = factor - 1
+			}
+		}
+		if _, err := s.processor.ProcessStreamer(ctx, item.ID); err != nil {
+			if errors.Is(err, ErrStreamerBusy) || errors.Is(err, ErrPipelinePaused) ||
+				errors.Is(err, ErrBudgetExceeded) || errors.Is(err, ErrChunkUnchanged) {
+				continue
+			}
+			s.logger.Warn("stream analysis cycle failed", zap.String("streamer_id", item.ID), zap.Error(err))
+			continue
+		}
+		processed++
+	}
+	return processed
+}
@@ -0,0 +1,115 @@
+package media
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/pipeline"
+	"github.com/funpot/funpot-go-core/internal/prompts"
+	"github.com/funpot/funpot-go-core/internal/streamers"
+)
+
+type fakeStreamerSource struct {
+	items []streamers.Streamer
+}
+
+func (f fakeStreamerSource) ListByStatus(_ context.Context, _ string) []streamers.Streamer {
+	return f.items
+}
+
+type recordingProcessor struct {
+	processed []string
+}
+
+func (p *recordingProcessor) ProcessStreamer(_ context.Context, streamerID string) (streamers.LLMDecision, error) {
+	p.processed = append(p.processed, streamerID)
+	return streamers.LLMDecision{StreamerID: streamerID}, nil
+}
+
+func TestSchedulerRunOnceSkipsPausedStreamers(t *testing.T) {
+	switches := pipeline.NewService()
+	if _, err := switches.Set(context.Background(), pipeline.SetRequest{Scope: pipeline.ScopeStreamer, TargetID: "str-2", Paused: true}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	processor := &recordingProcessor{}
+	scheduler := NewScheduler(processor, fakeStreamerSource{items: []streamers.Streamer{{ID: "str-1"}, {ID: "str-2"}}}, zap.NewNop(), SchedulerConfig{})
+	scheduler.WithSwitches(switches)
+
+	if got := scheduler.RunOnce(context.Background()); got != 1 {
+		t.Fatalf("RunOnce() = %d, want 1", got)
+	}
+	if len(processor.processed) != 1 || processor.processed[0] != "str-1" {
+		t.Fatalf("processed = %v, want [str-1]", processor.processed)
+	}
+}
+
+type countingCapture struct {
+	calls int
+}
+
+func (c *countingCapture) Capture(_ context.Context, _ string) (ChunkRef, error) {
+	c.calls++
+	return ChunkRef{Reference: "chunk-1"}, nil
+}
+
+func TestSchedulerStopsWorkWhenSwitchDisabled(t *testing.T) {
+	ctx := context.Background()
+	switches := pipeline.NewService()
+	capture := &countingCapture{}
+	worker := NewWorker(
+		capture,
+		fakeClassifier{result: StageAClassification{Label: "cs_detected", Confidence: 0.9}},
+		&InMemoryRunStore{},
+		&fakeDecisionStore{},
+		NewInMemoryLocker(),
+		WorkerConfig{GameID: "cs2"},
+	)
+	worker.WithSwitches(switches)
+	scheduler := NewScheduler(worker, fakeStreamerSource{items: []streamers.Streamer{{ID: "str-1"}}}, zap.NewNop(), SchedulerConfig{GameID: "cs2"})
+	scheduler.WithSwitches(switches)
+
+	if got := scheduler.RunOnce(ctx); got != 1 || capture.calls != 1 {
+		t.Fatalf("RunOnce() = %d with %d captures, want 1 and 1", got, capture.calls)
+	}
+
+	if _, err := switches.Set(ctx, pipeline.SetRequest{Scope: pipeline.ScopeGlobal, Paused: true}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := scheduler.RunOnce(ctx); got != 0 || capture.calls != 1 {
+		t.Fatalf("paused pipeline ran %d cycles with %d captures", got, capture.calls)
+	}
+
+	// A paused stage A lets the scheduler through but the worker stops
+	// before capturing.
+	if _, err := switches.Set(ctx, pipeline.SetRequest{Scope: pipeline.ScopeGlobal}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := switches.Set(ctx, pipeline.SetRequest{Scope: pipeline.ScopeGame, TargetID: "cs2", PausedStages: []string{prompts.StageA}}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := scheduler.RunOnce(ctx); got != 0 || capture.calls != 1 {
+		t.Fatalf("paused stage A ran %d cycles with %d captures", got, capture.calls)
+	}
+}
+
+type fixedSampling int
+
+func (f fixedSampling) SamplingFactor(_ context.Context, _ string) int {
+	return int(f)
+}
+
+func TestSchedulerDegradesSamplingInterval(t *testing.T) {
+	processor := &recordingProcessor{}
+	scheduler := NewScheduler(processor, fakeStreamerSource{items: []streamers.Streamer{{ID: "str-1"}}}, zap.NewNop(), SchedulerConfig{})
+	scheduler.WithSampling(fixedSampling(3))
+
+	for i := 0; i < 6; i++ {
+		scheduler.RunOnce(context.Background())
+	}
+	if len(processor.processed) != 2 {
+		t.Fatalf("expected 2 cycles in 6 ticks with factor 3, got %d", len(processor.processed))
+	}
+}
@@ -7,25 +7,44 @@ import (
 	"strings"
 	"time"
 
+	"github.com/funpot/funpot-go-core/internal/pipeline"
+	"github.com/funpot/funpot-go-core/internal/prompts"
 	"github.com/funpot/funpot-go-core/internal/streamers"
 )
 
+// ErrorCodeSchemaViolation marks decisions whose raw response failed output schema validation.
+const ErrorCodeSchemaViolation = "schema_violation"
+
 var (
 	ErrStreamerIDRequired = errors.New("streamerID is required")
 	ErrStreamerBusy       = errors.New("streamer is already being processed")
+	ErrPipelinePaused     = errors.New("pipeline stage is paused for streamer")
 )
 
 type ChunkRef struct {
 	Reference string
 }
 
+// ClassifyRequest carries the captured chunk and the prompt version whose
+// output schema the classifier must request from the model. Frames lists
+// extracted keyframes when a preprocessor is configured.
+type ClassifyRequest struct {
+	StreamerID string
+	Chunk      ChunkRef
+	Frames     []string
+	Prompt     prompts.PromptVersion
+}
+
 type StageAClassification struct {
 	Label       string
 	Confidence  float64
 	RawResponse string
-	TokensIn    int
-	TokensOut   int
-	Latency     time.Duration
+	// Model is the model that produced the answer, which differs from the
+	// prompt model when the fallback was used.
+	Model     string
+	TokensIn  int
+	TokensOut int
+	Latency   time.Duration
 }
 
 type StreamCapture interface {
@@ -33,7 +52,7 @@ type StreamCapture interface {
 }
 
 type StageAClassifier interface {
-	Classify(ctx context.Context, input ChunkRef) (StageAClassification, error)
+	Classify(ctx context.Context, req ClassifyRequest) (StageAClassification, error)
 }
 
 type RunStore interface {
@@ -44,6 +63,19 @@ type DecisionStore interface {
 	RecordLLMDecision(ctx context.Context, req streamers.RecordDecisionRequest) (streamers.LLMDecision, error)
 }
 
+type PromptSource interface {
+	Active(ctx context.Context, stage string) (prompts.PromptVersion, error)
+}
+
+type Switches interface {
+	Resolve(ctx context.Context, streamerID, gameID string) pipeline.State
+}
+
+type ChunkPreprocessor interface {
+	Prepare(ctx context.Context, streamerID string, chunk ChunkRef) (PreparedChunk, error)
+	Complete(streamerID string, prepared PreparedChunk, classified bool)
+}
+
 type Locker interface {
 	TryLock(key string, ttl time.Duration) bool
 	Unlock(key string)
@@ -54,14 +86,20 @@ type Worker struct {
 	classifier    StageAClassifier
 	runs          RunStore
 	decisions     DecisionStore
+	prompts       PromptSource
+	switches      Switches
+	preprocessor  ChunkPreprocessor
 	locker        Locker
 	lockTTL       time.Duration
 	minConfidence float64
+	gameID        string
 }
 
 type WorkerConfig struct {
 	LockTTL       time.Duration
 	MinConfidence float64
+	// GameID binds the pipeline to a game so game-level switches apply.
+	GameID string
 }
 
 func NewWorker(capture StreamCapture, classifier StageAClassifier, runs RunStore, decisions DecisionStore, locker Locker, cfg WorkerConfig) *Worker {
@@ -79,14 +117,35 @@ func NewWorker(capture StreamCapture, classifier StageAClassifier, runs RunStore
 		locker:        locker,
 		lockTTL:       cfg.LockTTL,
 		minConfidence: cfg.MinConfidence,
+		gameID:        strings.TrimSpace(cfg.GameID),
 	}
 }
 
+// WithPrompts makes the worker classify with the active stage A prompt version
+// instead of the default stage schema.
+func (w *Worker) WithPrompts(source PromptSource) {
+	w.prompts = source
+}
+
+// WithSwitches makes the worker skip streamers whose stage A is paused by an admin.
+func (w *Worker) WithSwitches(switches Switches) {
+	w.switches = switches
+}
+
+// WithPreprocessor reduces captured chunks to keyframes or a downscaled clip
+// and skips classification when the frames did not change.
+func (w *Worker) WithPreprocessor(preprocessor ChunkPreprocessor) {
+	w.preprocessor = preprocessor
+}
+
 func (w *Worker) ProcessStreamer(ctx context.Context, streamerID string) (streamers.LLMDecision, error) {
 	id := strings.TrimSpace(streamerID)
 	if id == "" {
 		return streamers.LLMDecision{}, ErrStreamerIDRequired
 	}
+	if w.switches != nil && !w.switches.Resolve(ctx, id, w.gameID).StageEnabled(prompts.StageA) {
+		return streamers.LLMDecision{}, ErrPipelinePaused
+	}
 
 	lockKey := fmt.Sprintf("stream-capture:%s", id)
 	if !w.locker.TryLock(lockKey, w.lockTTL) {
@@ -104,21 +163,99 @@ func (w *Worker) ProcessStreamer(ctx context.Context, streamerID string) (stream
 		return streamers.LLMDecision{}, err
 	}
 
-	result, err := w.classifier.Classify(ctx, chunk)
+	if w.preprocessor == nil {
+		return w.classify(ctx, id, runID, ClassifyRequest{Chunk: chunk})
+	}
+
+	prepared, err := w.preprocessor.Prepare(ctx, id, chunk)
 	if err != nil {
 		return streamers.LLMDecision{}, err
 	}
+	if prepared.Unchanged {
+		w.preprocessor.Complete(id, prepared, false)
+		return streamers.LLMDecision{}, ErrChunkUnchanged
+	}
+	decision, err := w.classify(ctx, id, runID, ClassifyRequest{Chunk: prepared.Chunk, Frames: prepared.Frames})
+	w.preprocessor.Complete(id, prepared, err == nil)
+	return decision, err
+}
 
-	label := NormalizeStageALabel(result.Label)
-	if result.Confidence < w.minConfidence {
+// classify runs the active stage A prompt against the chunk and records the decision.
+func (w *Worker) classify(ctx context.Context, id, runID string, req ClassifyRequest) (streamers.LLMDecision, error) {
+	prompt, err := w.activePrompt(ctx)
+	if err != nil {
+		return streamers.LLMDecision{}, err
+	}
+
+	req.StreamerID = id
+	req.Prompt = prompt
+	result, err := w.classifier.Classify(ctx, req)
+	if err != nil {
+		return streamers.LLMDecision{}, err
+	}
+	model := result.Model
+	if model == "" {
+		model = prompt.Model
+	}
+
+	output, err := parseClassification(prompt.OutputSchema, result)
+	if err != nil {
+		decision, recordErr := w.decisions.RecordLLMDecision(ctx, streamers.RecordDecisionRequest{
+			RunID:        runID,
+			StreamerID:   id,
+			Stage:        prompts.StageA,
+			Model:        model,
+			ErrorCode:    ErrorCodeSchemaViolation,
+			ErrorMessage: err.Error(),
+		})
+		if recordErr != nil {
+			return streamers.LLMDecision{}, errors.Join(err, recordErr)
+		}
+		return decision, err
+	}
+
+	label := StageALabel(output.Label)
+	if output.Confidence < w.minConfidence {
 		label = StageALabelUncertain
 	}
 
 	return w.decisions.RecordLLMDecision(ctx, streamers.RecordDecisionRequest{
 		RunID:      runID,
 		StreamerID: id,
-		Stage:      "stage_a",
+		Stage:      prompts.StageA,
 		Label:      string(label),
-		Confidence: result.Confidence,
+		Confidence: output.Confidence,
+		Model:      model,
 	})
 }
+
+func (w *Worker) activePrompt(ctx context.Context) (prompts.PromptVersion, error) {
+	fallback := prompts.PromptVersion{Stage: prompts.StageA, OutputSchema: prompts.DefaultOutputSchema(prompts.StageA)}
+	if w.prompts == nil {
+		return fallback, nil
+	}
+	prompt, err := w.prompts.Active(ctx, prompts.StageA)
+	if errors.Is(err, prompts.ErrNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return prompts.PromptVersion{}, err
+	}
+	if len(prompt.OutputSchema.Labels) == 0 {
+		prompt.OutputSchema = fallback.OutputSchema
+	}
+	return prompt, nil
+}
+
+// parseClassification validates the raw structured response when present and
+// falls back to checking the pre-decoded label for classifiers that decode themselves.
+func parseClassification(schema prompts.OutputSchema, result StageAClassification) (prompts.StructuredOutput, error) {
+	if strings.TrimSpace(result.RawResponse) != "" {
+		return schema.Parse(result.RawResponse)
+	}
+	output := prompts.StructuredOutput{Label: result.Label, Confidence: result.Confidence}
+	if err := schema.Validate(output); err != nil {
+		return prompts.StructuredOutput{}, err
+	}
+	return output, nil
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/funpot/funpot-go-core/internal/pipeline"
+	"github.com/funpot/funpot-go-core/internal/prompts"
 	"github.com/funpot/funpot-go-core/internal/streamers"
 )
 
@@ -25,7 +27,7 @@ type fakeClassifier struct {
 	err    error
 }
 
-func (f fakeClassifier) Classify(_ context.Context, _ ChunkRef) (StageAClassification, error) {
+func (f fakeClassifier) Classify(_ context.Context, _ ClassifyRequest) (StageAClassification, error) {
 	if f.err != nil {
 		return StageAClassification{}, f.err
 	}
@@ -38,7 +40,7 @@ type fakeDecisionStore struct {
 
 func (s *fakeDecisionStore) RecordLLMDecision(_ context.Context, req streamers.RecordDecisionRequest) (streamers.LLMDecision, error) {
 	s.last = req
-	return streamers.LLMDecision{RunID: req.RunID, StreamerID: req.StreamerID, Stage: req.Stage, Label: req.Label, Confidence: req.Confidence}, nil
+	return streamers.LLMDecision{RunID: req.RunID, StreamerID: req.StreamerID, Stage: req.Stage, Label: req.Label, Confidence: req.Confidence, ErrorCode: req.ErrorCode}, nil
 }
 
 func TestWorkerProcessStreamerStageASuccess(t *testing.T) {
@@ -109,3 +111,130 @@ func TestWorkerProcessStreamerBusy(t *testing.T) {
 		t.Fatalf("error = %v, want %v", err, ErrStreamerBusy)
 	}
 }
+
+func TestWorkerProcessStreamerParsesStructuredResponse(t *testing.T) {
+	decisions := &fakeDecisionStore{}
+	worker := NewWorker(
+		fakeCapture{chunk: ChunkRef{Reference: "chunk-1"}},
+		fakeClassifier{result: StageAClassification{RawResponse: `{"label":"not_cs","confidence":0.8}`}},
+		&InMemoryRunStore{},
+		decisions,
+		NewInMemoryLocker(),
+		WorkerConfig{MinConfidence: 0.5},
+	)
+
+	got, err := worker.ProcessStreamer(context.Background(), "str-1")
+	if err != nil {
+		t.Fatalf("ProcessStreamer() error = %v", err)
+	}
+	if got.Label != string(StageALabelNotCS) || got.Confidence != 0.8 {
+		t.Fatalf("unexpected decision: %+v", got)
+	}
+}
+
+func TestWorkerProcessStreamerRecordsSchemaViolation(t *testing.T) {
+	decisions := &fakeDecisionStore{}
+	worker := NewWorker(
+		fakeCapture{chunk: ChunkRef{Reference: "chunk-1"}},
+		fakeClassifier{result: StageAClassification{RawResponse: "yes, counter strike", Confidence: 0.95}},
+		&InMemoryRunStore{},
+		decisions,
+		NewInMemoryLocker(),
+		WorkerConfig{MinConfidence: 0.5},
+	)
+
+	got, err := worker.ProcessStreamer(context.Background(), "str-1")
+	if !errors.Is(err, prompts.ErrSchemaViolation) {
+		t.Fatalf("error = %v, want %v", err, prompts.ErrSchemaViolation)
+	}
+	if got.ErrorCode != ErrorCodeSchemaViolation {
+		t.Fatalf("errorCode = %q, want %q", got.ErrorCode, ErrorCodeSchemaViolation)
+	}
+	if decisions.last.Label != "" {
+		t.Fatalf("expected no label for schema violation, got %q", decisions.last.Label)
+	}
+}
+
+func TestWorkerProcessStreamerRespectsPausedStage(t *testing.T) {
+	switches := pipeline.NewService()
+	if _, err := switches.Set(context.Background(), pipeline.SetRequest{Scope: pipeline.ScopeGame, TargetID: "cs2", PausedStages: []string{prompts.StageA}}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	worker := NewWorker(
+		fakeCapture{chunk: ChunkRef{Reference: "chunk-1"}},
+		fakeClassifier{result: StageAClassification{Label: "cs_detected", Confidence: 0.9}},
+		&InMemoryRunStore{},
+		&fakeDecisionStore{},
+		NewInMemoryLocker(),
+		WorkerConfig{GameID: "cs2"},
+	)
+	worker.WithSwitches(switches)
+
+	_, err := worker.ProcessStreamer(context.Background(), "str-1")
+	if !errors.Is(err, ErrPipelinePaused) {
+		t.Fatalf("error = %v, want %v", err, ErrPipelinePaused)
+	}
+}
+
+func TestWorkerProcessStreamerRecordsModelUsed(t *testing.T) {
+	decisions := &fakeDecisionStore{}
+	worker := NewWorker(
+		fakeCapture{chunk: ChunkRef{Reference: "chunk-1"}},
+		fakeClassifier{result: StageAClassification{RawResponse: `{"label":"cs_detected","confidence":0.9}`, Model: "fake/backup", TokensIn: 10, TokensOut: 5}},
+		&InMemoryRunStore{},
+		decisions,
+		NewInMemoryLocker(),
+		WorkerConfig{},
+	)
+
+	if _, err := worker.ProcessStreamer(context.Background(), "str-1"); err != nil {
+		t.Fatalf("ProcessStreamer() error = %v", err)
+	}
+	if decisions.last.Model != "fake/backup" {
+		t.Fatalf("recorded model = %q, want fake/backup", decisions.last.Model)
+	}
+}
+
+type stubPreprocessor struct {
+	prepared   PreparedChunk
+	classified []bool
+}
+
+func (p *stubPreprocessor) Prepare(_ context.Context, _ string, _ ChunkRef) (PreparedChunk, error) {
+	return p.prepared, nil
+}
+
+func (p *stubPreprocessor) Complete(_ string, _ PreparedChunk, classified bool) {
+	p.classified = append(p.classified, classified)
+}
+
+func TestWorkerProcessStreamerSkipsUnchangedChunk(t *testing.T) {
+	decisions := &fakeDecisionStore{}
+	preprocessor := &stubPreprocessor{prepared: PreparedChunk{Unchanged: true}}
+	worker := NewWorker(
+		fakeCapture{chunk: ChunkRef{Reference: "chunk-1"}},
+		fakeClassifier{err: errors.New("classifier must not be called")},
+		&InMemoryRunStore{},
+		decisions,
+		NewInMemoryLocker(),
+		WorkerConfig{},
+	)
+	worker.WithPreprocessor(preprocessor)
+
+	if _, err := worker.ProcessStreamer(context.Background(), "str-1"); !errors.Is(err, ErrChunkUnchanged) {
+		t.Fatalf("error = %v, want %v", err, ErrChunkUnchanged)
+	}
+	if decisions.last.StreamerID != "" || len(preprocessor.classified) != 1 || preprocessor.classified[0] {
+		t.Fatalf("expected skipped cycle, got decision %+v completions %v", decisions.last, preprocessor.classified)
+	}
+
+	preprocessor.prepared = PreparedChunk{Chunk: ChunkRef{Reference: "chunk-1"}, Frames: []string{"frame-01.jpg"}}
+	worker.classifier = fakeClassifier{result: StageAClassification{Label: "cs_detected", Confidence: 0.9}}
+	if _, err := worker.ProcessStreamer(context.Background(), "str-1"); err != nil {
+		t.Fatalf("ProcessStreamer() error = %v", err)
+	}
+	if len(preprocessor.classified) != 2 || !preprocessor.classified[1] {
+		t.Fatalf("expected classified completion, got %v", preprocessor.classified)
+	}
+}
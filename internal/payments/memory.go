@@ -0,0 +1,128 @@
+package payments
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryRepository keeps payments in process memory; used in tests and
+// local runs without PostgreSQL.
+type InMemoryRepository struct {
+	mu    sync.RWMutex
+	items map[string]Payment
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{items: make(map[string]Payment)}
+}
+
+func (r *InMemoryRepository) Create(_ context.Context, payment Payment) (Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if payment.IdempotencyKey != "" {
+		if existing, ok := r.findByIdempotencyKey(payment.UserID, payment.IdempotencyKey); ok {
+			return existing, nil
+		}
+	}
+	r.items[payment.InvoiceID] = payment
+	return payment, nil
+}
+
+func (r *InMemoryRepository) GetByInvoiceID(_ context.Context, invoiceID string) (Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	payment, ok := r.items[invoiceID]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	return payment, nil
+}
+
+func (r *InMemoryRepository) FindByIdempotencyKey(_ context.Context, userID, key string) (Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if payment, ok := r.findByIdempotencyKey(userID, key); ok {
+		return payment, nil
+	}
+	return Payment{}, ErrNotFound
+}
+
+func (r *InMemoryRepository) findByIdempotencyKey(userID, key string) (Payment, bool) {
+	for _, payment := range r.items {
+		if payment.UserID == userID && payment.IdempotencyKey == key {
+			return payment, true
+		}
+	}
+	return Payment{}, false
+}
+
+func (r *InMemoryRepository) Update(_ context.Context, payment Payment, fromStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.items[payment.InvoiceID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Status != fromStatus {
+		return ErrInvalidTransition
+	}
+	current.Status = payment.Status
+	current.Payload = payment.Payload
+	current.UpdatedAt = payment.UpdatedAt
+	r.items[payment.InvoiceID] = current
+	return nil
+}
+
+func (r *InMemoryRepository) ListSince(_ context.Context, since time.Time) ([]Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Payment, 0)
+	for _, payment := range r.items {
+		if !payment.CreatedAt.Before(since) {
+			result = append(result, payment)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (r *InMemoryRepository) ListFlagged(_ context.Context) ([]Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Payment, 0)
+	for _, payment := range r.items {
+		if payment.Payload.Flag != "" {
+			result = append(result, payment)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UpdatedAt.Equal(result[j].UpdatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+	})
+	return result, nil
+}
+
+func (r *InMemoryRepository) PaidUsers(_ context.Context, userIDs []string) (map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	wanted := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		wanted[userID] = true
+	}
+	result := make(map[string]bool)
+	for _, payment := range r.items {
+		if payment.Status == StatusPaid && wanted[payment.UserID] {
+			result[payment.UserID] = true
+		}
+	}
+	return result, nil
+}
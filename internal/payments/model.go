@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserIDRequired      = errors.New("userId is required")
+	ErrInvalidAmount       = errors.New("amountINT must be positive")
+	ErrIdempotencyConflict = errors.New("idempotency key was already used for a different amount")
+	ErrInvoiceUnavailable  = errors.New("failed to create telegram invoice")
+	ErrRateLimited         = errors.New("invoice rate limit exceeded")
+	ErrNotFound            = errors.New("payment not found")
+	ErrInvalidTransition   = errors.New("payment status changed concurrently")
+	ErrWebhookUnauthorized = errors.New("invalid webhook secret token")
+	ErrPaymentMismatch     = errors.New("payment does not match invoice")
+	ErrLedgerUnavailable   = errors.New("wallet ledger is not configured")
+	ErrReasonRequired      = errors.New("reason is required")
+	ErrNotRefundable       = errors.New("only paid payments can be refunded")
+	ErrRefundUnavailable   = errors.New("failed to refund telegram stars")
+	ErrPayerUnknown        = errors.New("payment has no telegram payer recorded; refund it from telegram and reverse it manually")
+)
+
+const ProviderTelegramStars = "telegram_stars"
+
+// Payment statuses from docs/erd.md.
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusRefunded = "refunded"
+)
+
+// RateLimitError reports an invoice refused by the per-user limit.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Payment is a Telegram Stars top-up. InvoiceID doubles as the invoice
+// payload Telegram echoes back when the payment succeeds.
+type Payment struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"userId"`
+	Provider       string    `json:"provider"`
+	InvoiceID      string    `json:"invoiceId"`
+	AmountINT      int64     `json:"amountINT"`
+	Stars          int64     `json:"stars"`
+	Status         string    `json:"status"`
+	IdempotencyKey string    `json:"-"`
+	Payload        Payload   `json:"payload"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Payload holds provider details kept in the payments.payload JSONB column.
+type Payload struct {
+	InvoiceLink string  `json:"invoiceLink,omitempty"`
+	StarsRate   float64 `json:"starsRate,omitempty"`
+	// TelegramChargeID identifies the successful payment; Stars refunds
+	// need it.
+	TelegramChargeID string `json:"telegramChargeId,omitempty"`
+	ProviderChargeID string `json:"providerChargeId,omitempty"`
+	// TelegramUserID is the payer; refundStarPayment needs it.
+	TelegramUserID int64  `json:"telegramUserId,omitempty"`
+	LedgerEntryID  string `json:"ledgerEntryId,omitempty"`
+	// RefundReason and ReversalEntryID are set once the top-up is refunded.
+	RefundReason    string `json:"refundReason,omitempty"`
+	ReversalEntryID string `json:"reversalEntryId,omitempty"`
+	// Flag explains why the payment needs manual review, e.g. a refunded
+	// top-up that could not be reversed or a charge Telegram does not list.
+	Flag string `json:"flag,omitempty"`
+}
+
+// InvoiceRequest asks for a Stars invoice of AmountINT for UserID.
+// IdempotencyKey, when set, returns the user's earlier invoice for the key.
+type InvoiceRequest struct {
+	UserID         string
+	AmountINT      int64
+	IdempotencyKey string
+}
+
+// Invoice is returned to the Mini App, which opens InvoiceLink.
+type Invoice struct {
+	InvoiceID        string `json:"invoiceId"`
+	TgInvoicePayload string `json:"tgInvoicePayload"`
+	InvoiceLink      string `json:"invoiceLink"`
+	AmountINT        int64  `json:"amountINT"`
+	Stars            int64  `json:"stars"`
+	Status           string `json:"status"`
+}
+
+func invoiceFrom(payment Payment) Invoice {
+	return Invoice{
+		InvoiceID:        payment.InvoiceID,
+		TgInvoicePayload: payment.InvoiceID,
+		InvoiceLink:      payment.Payload.InvoiceLink,
+		AmountINT:        payment.AmountINT,
+		Stars:            payment.Stars,
+		Status:           payment.Status,
+	}
+}
@@ -0,0 +1,197 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const paymentColumns = `id, user_id, provider, invoice_id, amount_int, stars, status, idempotency_key, payload, created_at, updated_at`
+
+// PostgresRepository persists payments in PostgreSQL.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, payment Payment) (Payment, error) {
+	payload, err := json.Marshal(payment.Payload)
+	if err != nil {
+		return Payment{}, fmt.Errorf("encode payment payload: %w", err)
+	}
+	query := `INSERT INTO payments (` + paymentColumns + `)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (user_id, idempotency_key) WHERE idempotency_key <> '' DO NOTHING`
+	result, err := r.db.ExecContext(ctx, query,
+		payment.ID,
+		payment.UserID,
+		payment.Provider,
+		payment.InvoiceID,
+		payment.AmountINT,
+		payment.Stars,
+		payment.Status,
+		payment.IdempotencyKey,
+		payload,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		return Payment{}, fmt.Errorf("insert payment: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Payment{}, err
+	}
+	if rowsAffected == 0 {
+		return r.FindByIdempotencyKey(ctx, payment.UserID, payment.IdempotencyKey)
+	}
+	return payment, nil
+}
+
+func (r *PostgresRepository) GetByInvoiceID(ctx context.Context, invoiceID string) (Payment, error) {
+	return r.get(ctx, `SELECT `+paymentColumns+` FROM payments WHERE invoice_id = $1`, invoiceID)
+}
+
+func (r *PostgresRepository) FindByIdempotencyKey(ctx context.Context, userID, key string) (Payment, error) {
+	return r.get(ctx, `SELECT `+paymentColumns+` FROM payments WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, payment Payment, fromStatus string) error {
+	payload, err := json.Marshal(payment.Payload)
+	if err != nil {
+		return fmt.Errorf("encode payment payload: %w", err)
+	}
+	const query = `
+		UPDATE payments
+		SET status = $2,
+		    payload = $3,
+		    updated_at = $4
+		WHERE invoice_id = $1 AND status = $5
+	`
+	result, err := r.db.ExecContext(ctx, query, payment.InvoiceID, payment.Status, payload, payment.UpdatedAt, fromStatus)
+	if err != nil {
+		return fmt.Errorf("update payment: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetByInvoiceID(ctx, payment.InvoiceID); err != nil {
+			return err
+		}
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListSince(ctx context.Context, since time.Time) ([]Payment, error) {
+	return r.list(ctx, `SELECT `+paymentColumns+` FROM payments WHERE created_at >= $1 ORDER BY created_at, id`, since)
+}
+
+func (r *PostgresRepository) ListFlagged(ctx context.Context) ([]Payment, error) {
+	return r.list(ctx, `SELECT `+paymentColumns+` FROM payments WHERE payload ->> 'flag' <> '' ORDER BY updated_at, id`)
+}
+
+func (r *PostgresRepository) PaidUsers(ctx context.Context, userIDs []string) (map[string]bool, error) {
+	result := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	placeholders := make([]string, len(userIDs))
+	args := make([]any, 0, len(userIDs)+1)
+	args = append(args, StatusPaid)
+	for i, userID := range userIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, userID)
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM payments WHERE status = $1 AND user_id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select paid users: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("scan paid user: %w", err)
+		}
+		result[userID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate paid users: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresRepository) list(ctx context.Context, query string, args ...any) ([]Payment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select payments: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Payment, 0)
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan payment: %w", err)
+		}
+		result = append(result, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate payments: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresRepository) get(ctx context.Context, query string, args ...any) (Payment, error) {
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Payment{}, ErrNotFound
+		}
+		return Payment{}, fmt.Errorf("select payment: %w", err)
+	}
+	return payment, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayment(row rowScanner) (Payment, error) {
+	var (
+		payment Payment
+		payload []byte
+	)
+	if err := row.Scan(
+		&payment.ID,
+		&payment.UserID,
+		&payment.Provider,
+		&payment.InvoiceID,
+		&payment.AmountINT,
+		&payment.Stars,
+		&payment.Status,
+		&payment.IdempotencyKey,
+		&payload,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	); err != nil {
+		return Payment{}, err
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &payment.Payload); err != nil {
+			return Payment{}, fmt.Errorf("decode payment payload: %w", err)
+		}
+	}
+	payment.CreatedAt = payment.CreatedAt.UTC()
+	payment.UpdatedAt = payment.UpdatedAt.UTC()
+	return payment, nil
+}
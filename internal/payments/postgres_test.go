@@ -0,0 +1,126 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var paymentRowColumns = []string{"id", "user_id", "provider", "invoice_id", "amount_int", "stars", "status", "idempotency_key", "payload", "created_at", "updated_at"}
+
+func TestPostgresRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	payment := Payment{InvoiceID: "inv_1", Status: StatusPaid, Payload: Payload{TelegramChargeID: "tg-1"}, UpdatedAt: now}
+	updateQuery := regexp.QuoteMeta("UPDATE payments")
+
+	mock.ExpectExec(updateQuery).
+		WithArgs("inv_1", StatusPaid, []byte(`{"telegramChargeId":"tg-1"}`), now, StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.Update(context.Background(), payment, StatusPending); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	mock.ExpectExec(updateQuery).
+		WithArgs("inv_1", StatusPaid, sqlmock.AnyArg(), now, StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + paymentColumns + " FROM payments WHERE invoice_id = $1")).
+		WithArgs("inv_1").
+		WillReturnRows(sqlmock.NewRows(paymentRowColumns).AddRow("pay-1", "u-1", ProviderTelegramStars, "inv_1", int64(10), int64(5), StatusPaid, "", []byte(`{}`), now, now))
+	if err := repo.Update(context.Background(), payment, StatusPending); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_ListSince(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + paymentColumns + " FROM payments WHERE created_at >= $1 ORDER BY created_at, id")).
+		WithArgs(now.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows(paymentRowColumns).
+			AddRow("pay-1", "u-1", ProviderTelegramStars, "inv_1", int64(10), int64(5), StatusPaid, "", []byte(`{"telegramChargeId":"tg-1","telegramUserId":42}`), now, now))
+
+	items, err := repo.ListSince(context.Background(), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListSince() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Payload.TelegramChargeID != "tg-1" || items[0].Payload.TelegramUserID != 42 {
+		t.Fatalf("unexpected payments %+v", items)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_CreateReturnsExistingOnKeyConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	payment := Payment{ID: "pay-2", UserID: "u-1", Provider: ProviderTelegramStars, InvoiceID: "inv_2", AmountINT: 10, Stars: 5, Status: StatusPending, IdempotencyKey: "k-1", CreatedAt: now, UpdatedAt: now}
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (user_id, idempotency_key) WHERE idempotency_key <> '' DO NOTHING")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT "+paymentColumns+" FROM payments WHERE user_id = $1 AND idempotency_key = $2")).
+		WithArgs("u-1", "k-1").
+		WillReturnRows(sqlmock.NewRows(paymentRowColumns).
+			AddRow("pay-1", "u-1", ProviderTelegramStars, "inv_1", int64(10), int64(5), StatusPending, "k-1", []byte(`{}`), now, now))
+
+	stored, err := repo.Create(context.Background(), payment)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if stored.ID != "pay-1" || stored.InvoiceID != "inv_1" {
+		t.Fatalf("expected the existing payment, got %+v", stored)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_PaidUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT user_id FROM payments WHERE status = $1 AND user_id IN ($2, $3)")).
+		WithArgs(StatusPaid, "u-1", "u-2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u-2"))
+
+	paid, err := repo.PaidUsers(context.Background(), []string{"u-1", "u-2"})
+	if err != nil {
+		t.Fatalf("PaidUsers() error = %v", err)
+	}
+	if paid["u-1"] || !paid["u-2"] {
+		t.Fatalf("unexpected paid users %v", paid)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
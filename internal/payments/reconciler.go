@@ -0,0 +1,235 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// starTransactionsPage is the largest page getStarTransactions returns.
+const starTransactionsPage = 100
+
+// Lease serializes reconciliation across replicas.
+type Lease interface {
+	TryLock(key string, ttl time.Duration) bool
+	Unlock(key string)
+}
+
+type ReconcilerConfig struct {
+	Interval time.Duration
+	// Window bounds how far back payments are compared with Telegram.
+	Window time.Duration
+	// Grace skips payments too recent to show up in Telegram's list yet.
+	Grace    time.Duration
+	LeaseTTL time.Duration
+}
+
+// ReconcileReport counts what a reconciliation pass changed.
+type ReconcileReport struct {
+	Checked   int
+	Completed int
+	Reversed  int
+	Flagged   int
+	Unmatched int
+}
+
+// missingChargeFlag marks paid payments Telegram does not list.
+const missingChargeFlag = "missing from telegram transactions"
+
+// Reconciler compares the bot's Stars transactions with the payments table.
+// Pending invoices Telegram charged are completed, paid invoices refunded on
+// Telegram are reversed, paid invoices missing from its list are flagged for
+// an admin, and charges without a matching invoice are logged for review.
+type Reconciler struct {
+	payments *Service
+	telegram StarTransactionLister
+	lease    Lease
+	logger   *zap.Logger
+	cfg      ReconcilerConfig
+	nowFn    func() time.Time
+}
+
+func NewReconciler(payments *Service, telegram StarTransactionLister, lease Lease, logger *zap.Logger, cfg ReconcilerConfig) *Reconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 7 * 24 * time.Hour
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = 10 * time.Minute
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 5 * time.Minute
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Reconciler{
+		payments: payments,
+		telegram: telegram,
+		lease:    lease,
+		logger:   logger,
+		cfg:      cfg,
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Run reconciles every interval until ctx is cancelled.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			const key = "payments:reconcile"
+			if !r.lease.TryLock(key, r.cfg.LeaseTTL) {
+				continue
+			}
+			report, err := r.RunOnce(ctx)
+			r.lease.Unlock(key)
+			if err != nil {
+				r.logger.Warn("failed to reconcile payments", zap.Error(err))
+				continue
+			}
+			if report.Completed+report.Reversed+report.Flagged+report.Unmatched > 0 {
+				r.logger.Info("payments reconciled",
+					zap.Int("checked", report.Checked),
+					zap.Int("completed", report.Completed),
+					zap.Int("reversed", report.Reversed),
+					zap.Int("flagged", report.Flagged),
+					zap.Int("unmatched", report.Unmatched),
+				)
+			}
+		}
+	}
+}
+
+// RunOnce runs a single reconciliation pass over the window.
+func (r *Reconciler) RunOnce(ctx context.Context) (ReconcileReport, error) {
+	now := r.nowFn()
+	since := now.Add(-r.cfg.Window)
+	charges, refunded, err := r.fetchTransactions(ctx, since)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	items, err := r.payments.repo.ListSince(ctx, since)
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("list payments: %w", err)
+	}
+
+	var report ReconcileReport
+	known := make(map[string]bool, len(items))
+	for _, payment := range items {
+		known[payment.InvoiceID] = true
+		if payment.CreatedAt.After(now.Add(-r.cfg.Grace)) {
+			continue
+		}
+		report.Checked++
+		switch payment.Status {
+		case StatusPending:
+			charge, ok := charges[payment.InvoiceID]
+			if !ok {
+				continue
+			}
+			err := r.payments.CompletePayment(ctx, charge.Source.User.ID, SuccessfulPayment{
+				Currency:                starsCurrency,
+				TotalAmount:             charge.Amount,
+				InvoicePayload:          payment.InvoiceID,
+				TelegramPaymentChargeID: charge.ID,
+			})
+			if err != nil {
+				r.logger.Warn("failed to complete charged invoice", zap.String("invoice_id", payment.InvoiceID), zap.Error(err))
+				continue
+			}
+			report.Completed++
+		case StatusPaid:
+			charge, charged := charges[payment.InvoiceID]
+			switch {
+			case refunded[payment.Payload.TelegramChargeID]:
+				if _, err := r.payments.reverse(ctx, payment, "refunded on telegram"); err != nil {
+					r.logger.Warn("failed to reverse stars top-up", zap.String("invoice_id", payment.InvoiceID), zap.Error(err))
+					continue
+				}
+				report.Reversed++
+			case !charged:
+				// A missing charge may be a gap in Telegram's list rather than
+				// a refund, so an admin decides instead of reversing blindly.
+				if payment.Payload.Flag != "" {
+					continue
+				}
+				payment.Payload.Flag = missingChargeFlag
+				if err := r.payments.updatePayload(ctx, payment); err != nil {
+					r.logger.Warn("failed to flag payment", zap.String("invoice_id", payment.InvoiceID), zap.Error(err))
+					continue
+				}
+				r.logger.Warn("paid invoice missing from telegram transactions", zap.String("invoice_id", payment.InvoiceID), zap.String("user_id", payment.UserID))
+				report.Flagged++
+			case payment.Payload.TelegramUserID == 0 && charge.Source.User != nil:
+				// Payments completed before the payer was recorded cannot be
+				// refunded; backfill the payer from the charge.
+				payment.Payload.TelegramUserID = charge.Source.User.ID
+				if payment.Payload.TelegramChargeID == "" {
+					payment.Payload.TelegramChargeID = charge.ID
+				}
+				if err := r.payments.updatePayload(ctx, payment); err != nil {
+					r.logger.Warn("failed to backfill payment payer", zap.String("invoice_id", payment.InvoiceID), zap.Error(err))
+				}
+			}
+		}
+	}
+
+	for invoiceID, charge := range charges {
+		if known[invoiceID] {
+			continue
+		}
+		// The invoice may predate the window while its charge does not.
+		if _, err := r.payments.repo.GetByInvoiceID(ctx, invoiceID); err == nil {
+			continue
+		}
+		report.Unmatched++
+		r.logger.Warn("telegram charge without a matching invoice",
+			zap.String("charge_id", charge.ID),
+			zap.String("invoice_payload", invoiceID),
+			zap.Int64("stars", charge.Amount),
+		)
+	}
+	return report, nil
+}
+
+// fetchTransactions pages through the bot's transactions and returns user
+// payments by invoice payload and the charge IDs refunded to users.
+// Telegram lists transactions newest first, so paging stops at the first
+// page that reaches back past since.
+func (r *Reconciler) fetchTransactions(ctx context.Context, since time.Time) (map[string]StarTransaction, map[string]bool, error) {
+	charges := make(map[string]StarTransaction)
+	refunded := make(map[string]bool)
+	for offset := 0; ; offset += starTransactionsPage {
+		page, err := r.telegram.GetStarTransactions(ctx, offset, starTransactionsPage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get star transactions: %w", err)
+		}
+		pastWindow := false
+		for _, transaction := range page {
+			if time.Unix(transaction.Date, 0).Before(since) {
+				pastWindow = true
+				continue
+			}
+			switch {
+			case transaction.fromUser():
+				charges[transaction.Source.InvoicePayload] = transaction
+			case transaction.toUser():
+				refunded[transaction.ID] = true
+			}
+		}
+		if pastWindow || len(page) < starTransactionsPage {
+			return charges, refunded, nil
+		}
+	}
+}
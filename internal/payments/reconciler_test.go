@@ -0,0 +1,120 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeTransactions struct {
+	items []StarTransaction
+	pages int
+}
+
+func (f *fakeTransactions) GetStarTransactions(_ context.Context, offset, limit int) ([]StarTransaction, error) {
+	f.pages++
+	if offset >= len(f.items) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(f.items) {
+		end = len(f.items)
+	}
+	return f.items[offset:end], nil
+}
+
+func charge(id, invoiceID string, stars int64, at time.Time) StarTransaction {
+	return StarTransaction{ID: id, Amount: stars, Date: at.Unix(), Source: &TransactionPartner{Type: "user", User: &TelegramUser{ID: 42}, InvoicePayload: invoiceID}}
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, missed := newWebhookFixture(t)
+	kept := newPaidInvoice(t, svc, "u-2", "tg-kept")
+	refunded := newPaidInvoice(t, svc, "u-3", "tg-refunded")
+	vanished := newPaidInvoice(t, svc, "u-4", "tg-vanished")
+	now := time.Now().UTC()
+
+	transactions := &fakeTransactions{}
+	transactions.items = append(transactions.items,
+		charge("tg-kept", kept.InvoiceID, kept.Stars, now),
+		charge("tg-refunded", refunded.InvoiceID, refunded.Stars, now),
+		StarTransaction{ID: "tg-refunded", Amount: refunded.Stars, Date: now.Unix(), Receiver: &TransactionPartner{Type: "user", User: &TelegramUser{ID: 42}}},
+		charge("tg-missed", missed.InvoiceID, missed.Stars, now),
+		charge("tg-unknown", "inv_unknown", 5, now),
+	)
+	// Older history follows; the reconciler must not page through it.
+	for i := 0; i < 2*starTransactionsPage; i++ {
+		transactions.items = append(transactions.items, StarTransaction{ID: "old", Amount: 1, Date: now.Add(-30 * 24 * time.Hour).Unix(), Source: &TransactionPartner{Type: "fragment"}})
+	}
+
+	reconciler := NewReconciler(svc, transactions, nil, nil, ReconcilerConfig{Window: 24 * time.Hour, Grace: time.Minute})
+	reconciler.nowFn = func() time.Time { return now.Add(time.Hour) }
+
+	report, err := reconciler.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	want := ReconcileReport{Checked: 4, Completed: 1, Reversed: 1, Flagged: 1, Unmatched: 1}
+	if report != want {
+		t.Fatalf("expected report %+v, got %+v", want, report)
+	}
+	if transactions.pages != 1 {
+		t.Fatalf("expected paging to stop past the window, fetched %d pages", transactions.pages)
+	}
+	for userID, balance := range map[string]int64{"u-1": 10, "u-2": 10, "u-3": 0, "u-4": 10} {
+		if got, _ := ledger.Balance(ctx, userID); got != balance {
+			t.Fatalf("expected %s balance %d, got %d", userID, balance, got)
+		}
+	}
+	for invoiceID, status := range map[string]string{missed.InvoiceID: StatusPaid, kept.InvoiceID: StatusPaid, refunded.InvoiceID: StatusRefunded, vanished.InvoiceID: StatusPaid} {
+		if payment, _ := svc.repo.GetByInvoiceID(ctx, invoiceID); payment.Status != status {
+			t.Fatalf("expected %s %s, got %s", invoiceID, status, payment.Status)
+		}
+	}
+
+	flagged, err := svc.ListFlagged(ctx)
+	if err != nil || len(flagged) != 1 || flagged[0].InvoiceID != vanished.InvoiceID || flagged[0].Payload.Flag != missingChargeFlag {
+		t.Fatalf("expected the vanished payment to be flagged, got %+v (%v)", flagged, err)
+	}
+
+	again, err := reconciler.RunOnce(ctx)
+	if err != nil || again != (ReconcileReport{Checked: 4, Unmatched: 1}) {
+		t.Fatalf("expected a second pass to change nothing, got %+v (%v)", again, err)
+	}
+}
+
+func TestReconcilerBackfillsPayer(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newWebhookFixture(t)
+	refunder := &fakeRefunder{}
+	svc.WithRefunder(refunder)
+	invoice, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-2", AmountINT: 10})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	// Payments completed before the payer was recorded.
+	if err := svc.CompletePayment(ctx, 0, SuccessfulPayment{Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload}); err != nil {
+		t.Fatalf("CompletePayment() error = %v", err)
+	}
+	if _, err := svc.Refund(ctx, invoice.InvoiceID, "duplicate purchase"); !errors.Is(err, ErrPayerUnknown) {
+		t.Fatalf("expected ErrPayerUnknown, got %v", err)
+	}
+
+	now := time.Now().UTC()
+	transactions := &fakeTransactions{items: []StarTransaction{charge("tg-late", invoice.InvoiceID, invoice.Stars, now)}}
+	reconciler := NewReconciler(svc, transactions, nil, nil, ReconcilerConfig{Window: 24 * time.Hour, Grace: time.Minute})
+	reconciler.nowFn = func() time.Time { return now.Add(time.Hour) }
+	if _, err := reconciler.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	payment, err := svc.Refund(ctx, invoice.InvoiceID, "duplicate purchase")
+	if err != nil || payment.Status != StatusRefunded {
+		t.Fatalf("expected the backfilled payment to refund, got %+v (%v)", payment, err)
+	}
+	if payment.Payload.TelegramUserID != 42 || payment.Payload.TelegramChargeID != "tg-late" {
+		t.Fatalf("expected payer 42 and charge tg-late, got %+v", payment.Payload)
+	}
+}
@@ -0,0 +1,127 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// Refund returns the Stars of a paid invoice through the Bot API and
+// reverses its top-up. Refunding a refunded payment only re-runs the
+// reversal observer, so a failed delivery is finished on retry.
+func (s *Service) Refund(ctx context.Context, invoiceID, reason string) (Payment, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return Payment{}, ErrReasonRequired
+	}
+	if s.refunder == nil {
+		return Payment{}, ErrRefundUnavailable
+	}
+	payment, err := s.repo.GetByInvoiceID(ctx, invoiceID)
+	if err != nil {
+		return Payment{}, err
+	}
+	switch payment.Status {
+	case StatusRefunded:
+		if err := s.notifyReversal(ctx, payment); err != nil {
+			return Payment{}, err
+		}
+		return payment, nil
+	case StatusPaid:
+	default:
+		return Payment{}, ErrNotRefundable
+	}
+
+	if payment.Payload.TelegramUserID == 0 || payment.Payload.TelegramChargeID == "" {
+		// Payments completed before the payer was recorded get it from the
+		// reconciler once their charge shows up in Telegram's list.
+		return Payment{}, ErrPayerUnknown
+	}
+	err = s.refunder.RefundStarPayment(ctx, payment.Payload.TelegramUserID, payment.Payload.TelegramChargeID)
+	if err != nil && !strings.Contains(err.Error(), "CHARGE_ALREADY_REFUNDED") {
+		return Payment{}, fmt.Errorf("%w: %v", ErrRefundUnavailable, err)
+	}
+	return s.reverse(ctx, payment, reason)
+}
+
+// reverse debits a refunded top-up and marks the payment refunded. When the
+// user already spent the INT the payment is flagged for manual review
+// instead of driving the balance negative.
+func (s *Service) reverse(ctx context.Context, payment Payment, reason string) (Payment, error) {
+	if s.ledger == nil {
+		return Payment{}, ErrLedgerUnavailable
+	}
+	result, err := s.ledger.Debit(ctx, payment.UserID, payment.AmountINT, wallet.ReasonStarsTopup, payment.ID, ReversalKey(payment.InvoiceID))
+	switch {
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		payment.Payload.Flag = "balance too low to reverse top-up"
+		s.logger.Warn("refunded top-up needs manual review",
+			zap.String("invoice_id", payment.InvoiceID),
+			zap.String("user_id", payment.UserID),
+			zap.Int64("amount_int", payment.AmountINT),
+		)
+	case err != nil:
+		return Payment{}, fmt.Errorf("reverse stars top-up: %w", err)
+	default:
+		payment.Payload.ReversalEntryID = result.Entry.ID
+	}
+
+	fromStatus := payment.Status
+	payment.Status = StatusRefunded
+	payment.Payload.RefundReason = reason
+	payment.UpdatedAt = s.nowFn()
+	if err := s.repo.Update(ctx, payment, fromStatus); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return s.repo.GetByInvoiceID(ctx, payment.InvoiceID)
+		}
+		return Payment{}, err
+	}
+	if err := s.notifyReversal(ctx, payment); err != nil {
+		return Payment{}, err
+	}
+	return payment, nil
+}
+
+// TopupReversalObserver is notified after a credited top-up was refunded or
+// reversed, e.g. to take back the referral bonus it earned. It may run more
+// than once per payment and must be idempotent.
+type TopupReversalObserver interface {
+	ObserveTopupReversal(ctx context.Context, paymentID string) error
+}
+
+func (s *Service) notifyReversal(ctx context.Context, payment Payment) error {
+	if s.reversals == nil {
+		return nil
+	}
+	if err := s.reversals.ObserveTopupReversal(ctx, payment.ID); err != nil {
+		return fmt.Errorf("observe top-up reversal: %w", err)
+	}
+	return nil
+}
+
+// updatePayload stores payment's payload without changing its status.
+func (s *Service) updatePayload(ctx context.Context, payment Payment) error {
+	payment.UpdatedAt = s.nowFn()
+	return s.repo.Update(ctx, payment, payment.Status)
+}
+
+// ToppedUp reports which of userIDs have a paid Stars top-up.
+func (s *Service) ToppedUp(ctx context.Context, userIDs []string) (map[string]bool, error) {
+	return s.repo.PaidUsers(ctx, userIDs)
+}
+
+// ListFlagged returns payments flagged for manual review, oldest first.
+func (s *Service) ListFlagged(ctx context.Context) ([]Payment, error) {
+	return s.repo.ListFlagged(ctx)
+}
+
+// ReversalKey is the ledger idempotency key of the debit that reverses an
+// invoice's top-up.
+func ReversalKey(invoiceID string) string {
+	return "topup-reversal:" + invoiceID
+}
@@ -0,0 +1,127 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+type fakeRefunder struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeRefunder) RefundStarPayment(_ context.Context, userID int64, chargeID string) error {
+	f.calls = append(f.calls, chargeID)
+	if userID == 0 {
+		return errors.New("Bad Request: USER_ID_INVALID")
+	}
+	return f.err
+}
+
+// newPaidInvoice creates an invoice for userID and completes it as paid by
+// Telegram user 42 with charge ID chargeID.
+func newPaidInvoice(t *testing.T, svc *Service, userID, chargeID string) Invoice {
+	t.Helper()
+	ctx := context.Background()
+	invoice, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: userID, AmountINT: 10})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	if err := svc.CompletePayment(ctx, 42, SuccessfulPayment{Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload, TelegramPaymentChargeID: chargeID}); err != nil {
+		t.Fatalf("CompletePayment() error = %v", err)
+	}
+	return invoice
+}
+
+func TestRefund(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, pending := newWebhookFixture(t)
+	refunder := &fakeRefunder{}
+	svc.WithRefunder(refunder)
+	invoice := newPaidInvoice(t, svc, "u-1", "tg-1")
+
+	if _, err := svc.Refund(ctx, invoice.InvoiceID, " "); !errors.Is(err, ErrReasonRequired) {
+		t.Fatalf("expected ErrReasonRequired, got %v", err)
+	}
+	if _, err := svc.Refund(ctx, pending.InvoiceID, "duplicate purchase"); !errors.Is(err, ErrNotRefundable) {
+		t.Fatalf("expected ErrNotRefundable for a pending invoice, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		payment, err := svc.Refund(ctx, invoice.InvoiceID, "duplicate purchase")
+		if err != nil || payment.Status != StatusRefunded || payment.Payload.RefundReason != "duplicate purchase" || payment.Payload.Flag != "" {
+			t.Fatalf("refund %d: unexpected payment %+v (%v)", i+1, payment, err)
+		}
+	}
+	if len(refunder.calls) != 1 || refunder.calls[0] != "tg-1" {
+		t.Fatalf("expected one refundStarPayment call for tg-1, got %v", refunder.calls)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-1"); balance != 0 {
+		t.Fatalf("expected the top-up reversed, got balance %d", balance)
+	}
+}
+
+type fakeReversals struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeReversals) ObserveTopupReversal(_ context.Context, paymentID string) error {
+	f.calls = append(f.calls, paymentID)
+	return f.err
+}
+
+func TestRefundNotifiesReversalObserver(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newWebhookFixture(t)
+	svc.WithRefunder(&fakeRefunder{})
+	reversals := &fakeReversals{err: errors.New("db down")}
+	svc.WithTopupReversalObserver(reversals)
+	invoice := newPaidInvoice(t, svc, "u-4", "tg-4")
+
+	if _, err := svc.Refund(ctx, invoice.InvoiceID, "chargeback"); err == nil {
+		t.Fatal("expected the observer error to be returned")
+	}
+	reversals.err = nil
+	payment, err := svc.Refund(ctx, invoice.InvoiceID, "chargeback")
+	if err != nil || payment.Status != StatusRefunded {
+		t.Fatalf("expected retry to finish the reversal, got %+v (%v)", payment, err)
+	}
+	if len(reversals.calls) != 2 || reversals.calls[1] != payment.ID {
+		t.Fatalf("expected the observer to run again on retry, got %v", reversals.calls)
+	}
+}
+
+func TestRefundFlagsSpentTopup(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, _ := newWebhookFixture(t)
+	svc.WithRefunder(&fakeRefunder{})
+	invoice := newPaidInvoice(t, svc, "u-2", "tg-2")
+	if _, err := ledger.Debit(ctx, "u-2", 6, wallet.ReasonVoteCost, "vote-1", "vote:vote-1"); err != nil {
+		t.Fatalf("Debit() error = %v", err)
+	}
+
+	payment, err := svc.Refund(ctx, invoice.InvoiceID, "chargeback")
+	if err != nil || payment.Status != StatusRefunded || payment.Payload.Flag == "" || payment.Payload.ReversalEntryID != "" {
+		t.Fatalf("expected a flagged refund, got %+v (%v)", payment, err)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-2"); balance != 4 {
+		t.Fatalf("expected the balance left alone, got %d", balance)
+	}
+}
+
+func TestRefundTelegramError(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, _ := newWebhookFixture(t)
+	svc.WithRefunder(&fakeRefunder{err: errors.New("Bad Request: CHARGE_NOT_FOUND")})
+	invoice := newPaidInvoice(t, svc, "u-3", "tg-3")
+
+	if _, err := svc.Refund(ctx, invoice.InvoiceID, "duplicate purchase"); !errors.Is(err, ErrRefundUnavailable) {
+		t.Fatalf("expected ErrRefundUnavailable, got %v", err)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-3"); balance != 10 {
+		t.Fatalf("expected no reversal after a failed refund, got %d", balance)
+	}
+}
@@ -0,0 +1,29 @@
+package payments
+
+import (
+	"context"
+	"time"
+)
+
+// Repository persists payments.
+type Repository interface {
+	// Create stores payment and returns it. When the user already has a
+	// payment with the same non-empty idempotency key nothing is stored and
+	// that payment is returned instead.
+	Create(ctx context.Context, payment Payment) (Payment, error)
+	GetByInvoiceID(ctx context.Context, invoiceID string) (Payment, error)
+	// FindByIdempotencyKey returns the user's payment created with key or
+	// ErrNotFound.
+	FindByIdempotencyKey(ctx context.Context, userID, key string) (Payment, error)
+	// Update persists payment's status and payload if it is still in
+	// fromStatus and returns ErrInvalidTransition when another writer
+	// changed it first.
+	Update(ctx context.Context, payment Payment, fromStatus string) error
+	// ListSince returns payments created at or after since, oldest first.
+	ListSince(ctx context.Context, since time.Time) ([]Payment, error)
+	// ListFlagged returns payments whose payload carries a review flag,
+	// oldest update first.
+	ListFlagged(ctx context.Context) ([]Payment, error)
+	// PaidUsers returns which of userIDs have a paid payment.
+	PaidUsers(ctx context.Context, userIDs []string) (map[string]bool, error)
+}
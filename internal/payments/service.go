@@ -0,0 +1,182 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
+)
+
+// invoiceLimit caps how many invoices a user can open.
+var invoiceLimit = ratelimit.PerWindow(3, 5*time.Minute)
+
+type Service struct {
+	repo      Repository
+	linker    InvoiceLinker
+	starsRate float64
+	limiter   ratelimit.Limiter
+	ledger    *wallet.Service
+	refunder  StarsRefunder
+	observer  TopupObserver
+	reversals TopupReversalObserver
+	secret    string
+	logger    *zap.Logger
+	nowFn     func() time.Time
+}
+
+// NewService creates invoices through linker. starsRate is the INT credited
+// per Star; values <= 0 fall back to 1.
+func NewService(repo Repository, linker InvoiceLinker, starsRate float64) *Service {
+	if starsRate <= 0 {
+		starsRate = 1
+	}
+	return &Service{
+		repo:      repo,
+		linker:    linker,
+		starsRate: starsRate,
+		limiter:   ratelimit.NewInMemoryLimiter(),
+		logger:    zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithRateLimiter shares invoice limits across nodes, e.g. through Redis.
+// Limiter errors are logged and let the invoice through.
+func (s *Service) WithRateLimiter(limiter ratelimit.Limiter, logger *zap.Logger) {
+	if limiter != nil {
+		s.limiter = limiter
+	}
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// WithLedger credits successful payments to the wallet ledger.
+func (s *Service) WithLedger(ledger *wallet.Service) {
+	s.ledger = ledger
+}
+
+// WithRefunder enables admin refunds through the Bot API.
+func (s *Service) WithRefunder(refunder StarsRefunder) {
+	s.refunder = refunder
+}
+
+// WithTopupObserver registers a hook that runs after each credited top-up.
+func (s *Service) WithTopupObserver(observer TopupObserver) {
+	s.observer = observer
+}
+
+// WithTopupReversalObserver registers a hook that runs after a top-up was
+// refunded or reversed.
+func (s *Service) WithTopupReversalObserver(observer TopupReversalObserver) {
+	s.reversals = observer
+}
+
+// WithWebhookSecret sets the secret_token registered with setWebhook.
+// Webhooks are refused while it is empty.
+func (s *Service) WithWebhookSecret(secret string) {
+	s.secret = secret
+}
+
+// StarsFor converts an INT amount to Stars, rounding up so a top-up never
+// credits more than was paid.
+func (s *Service) StarsFor(amountINT int64) int64 {
+	return int64(math.Ceil(float64(amountINT) / s.starsRate))
+}
+
+// CreateInvoice opens a pending Stars invoice. A repeated idempotency key
+// returns the user's existing invoice without counting against the limit.
+// The invoice is stored before its link is requested from Telegram, so
+// concurrent requests with one key share a single invoice.
+func (s *Service) CreateInvoice(ctx context.Context, req InvoiceRequest) (Invoice, error) {
+	userID := strings.TrimSpace(req.UserID)
+	if userID == "" {
+		return Invoice{}, ErrUserIDRequired
+	}
+	if req.AmountINT <= 0 {
+		return Invoice{}, ErrInvalidAmount
+	}
+	key := strings.TrimSpace(req.IdempotencyKey)
+	if key != "" {
+		existing, err := s.repo.FindByIdempotencyKey(ctx, userID, key)
+		switch {
+		case err == nil:
+			return s.resume(ctx, existing, req.AmountINT)
+		case !errors.Is(err, ErrNotFound):
+			return Invoice{}, err
+		}
+	}
+
+	result, err := s.limiter.Allow(ctx, "invoices:"+userID, invoiceLimit)
+	if err != nil {
+		s.logger.Warn("invoice rate limiter unavailable", zap.String("user_id", userID), zap.Error(err))
+	} else if !result.Allowed {
+		return Invoice{}, &RateLimitError{RetryAfter: result.RetryAfter}
+	}
+
+	now := s.nowFn()
+	payment := Payment{
+		ID:             uuid.NewString(),
+		UserID:         userID,
+		Provider:       ProviderTelegramStars,
+		InvoiceID:      "inv_" + uuid.NewString(),
+		AmountINT:      req.AmountINT,
+		Stars:          s.StarsFor(req.AmountINT),
+		Status:         StatusPending,
+		IdempotencyKey: key,
+		Payload:        Payload{StarsRate: s.starsRate},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	stored, err := s.repo.Create(ctx, payment)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if stored.ID != payment.ID {
+		// A concurrent request with the same key stored its invoice first.
+		return s.resume(ctx, stored, req.AmountINT)
+	}
+	return s.attachLink(ctx, stored)
+}
+
+// resume returns an invoice found by idempotency key, requesting its link
+// again when an earlier attempt stored the invoice but got no link.
+func (s *Service) resume(ctx context.Context, payment Payment, amountINT int64) (Invoice, error) {
+	if payment.AmountINT != amountINT {
+		return Invoice{}, ErrIdempotencyConflict
+	}
+	if payment.Payload.InvoiceLink != "" || payment.Status != StatusPending {
+		return invoiceFrom(payment), nil
+	}
+	return s.attachLink(ctx, payment)
+}
+
+// attachLink creates the Telegram invoice link of a stored pending payment.
+// Every link carries the invoice ID as payload and the pre-checkout only
+// accepts a pending invoice, so racing requests cannot charge it twice.
+func (s *Service) attachLink(ctx context.Context, payment Payment) (Invoice, error) {
+	link, err := s.linker.CreateInvoiceLink(ctx, LinkRequest{
+		Title:       fmt.Sprintf("%d INT", payment.AmountINT),
+		Description: fmt.Sprintf("Top up %d INT for %d Telegram Stars", payment.AmountINT, payment.Stars),
+		Payload:     payment.InvoiceID,
+		Stars:       payment.Stars,
+	})
+	if err != nil {
+		return Invoice{}, fmt.Errorf("%w: %v", ErrInvoiceUnavailable, err)
+	}
+	payment.Payload.InvoiceLink = link
+	if err := s.updatePayload(ctx, payment); err != nil {
+		return Invoice{}, err
+	}
+	return invoiceFrom(payment), nil
+}
@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBotAPIStandIn serves createInvoiceLink like the Telegram Bot API and
+// counts the calls it receives.
+func newBotAPIStandIn(t *testing.T, calls *int32) *BotAPI {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.URL.Path != "/bottest-token/createInvoiceLink" {
+			http.NotFound(w, r)
+			return
+		}
+		var req createInvoiceLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: invalid json"}`))
+			return
+		}
+		if req.Currency != "XTR" || len(req.Prices) != 1 || req.Prices[0].Amount <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: CURRENCY_TOTAL_AMOUNT_INVALID"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": "https://t.me/$" + req.Payload})
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := NewBotAPI(server.Client(), server.URL, "test-token")
+	if err != nil {
+		t.Fatalf("NewBotAPI() error = %v", err)
+	}
+	return api
+}
+
+func TestCreateInvoice(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	repo := NewInMemoryRepository()
+	svc := NewService(repo, newBotAPIStandIn(t, &calls), 2)
+
+	invoice, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 5, IdempotencyKey: "k-1"})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	if invoice.Stars != 3 || invoice.Status != StatusPending || invoice.InvoiceLink != "https://t.me/$"+invoice.InvoiceID || invoice.TgInvoicePayload != invoice.InvoiceID {
+		t.Fatalf("unexpected invoice %+v", invoice)
+	}
+	stored, err := repo.GetByInvoiceID(ctx, invoice.InvoiceID)
+	if err != nil || stored.Status != StatusPending || stored.UserID != "u-1" {
+		t.Fatalf("expected pending payment row, got %+v (%v)", stored, err)
+	}
+
+	again, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 5, IdempotencyKey: "k-1"})
+	if err != nil || again.InvoiceID != invoice.InvoiceID {
+		t.Fatalf("expected the existing invoice, got %+v (%v)", again, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected one Bot API call, got %d", calls)
+	}
+	if _, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 7, IdempotencyKey: "k-1"}); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Fatalf("expected ErrIdempotencyConflict, got %v", err)
+	}
+	if _, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 0}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestCreateInvoiceRateLimit(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	svc := NewService(NewInMemoryRepository(), newBotAPIStandIn(t, &calls), 1)
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 10}); err != nil {
+			t.Fatalf("invoice %d: unexpected error %v", i+1, err)
+		}
+	}
+	_, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 10})
+	var limited *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limited) || limited.RetryAfter <= 0 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if _, err := svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-2", AmountINT: 10}); err != nil {
+		t.Fatalf("expected other users unaffected, got %v", err)
+	}
+}
+
+func TestCreateInvoiceBotAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer server.Close()
+	api, err := NewBotAPI(server.Client(), server.URL, "bad-token")
+	if err != nil {
+		t.Fatalf("NewBotAPI() error = %v", err)
+	}
+	repo := NewInMemoryRepository()
+	svc := NewService(repo, api, 1)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.CreateInvoice(context.Background(), InvoiceRequest{UserID: "u-1", AmountINT: 10, IdempotencyKey: "k-1"}); !errors.Is(err, ErrInvoiceUnavailable) {
+			t.Fatalf("attempt %d: expected ErrInvoiceUnavailable, got %v", i+1, err)
+		}
+	}
+	// The reserved invoice stays pending without a link until a retry gets one.
+	payments, err := repo.ListSince(context.Background(), time.Time{})
+	if err != nil || len(payments) != 1 || payments[0].Status != StatusPending || payments[0].Payload.InvoiceLink != "" {
+		t.Fatalf("expected one pending invoice without a link, got %+v (%v)", payments, err)
+	}
+}
+
+func TestCreateInvoiceConcurrentIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	repo := NewInMemoryRepository()
+	svc := NewService(repo, newBotAPIStandIn(t, &calls), 1)
+
+	var wg sync.WaitGroup
+	invoices := make([]Invoice, 3)
+	errs := make([]error, len(invoices))
+	for i := range invoices {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			invoices[i], errs[i] = svc.CreateInvoice(ctx, InvoiceRequest{UserID: "u-1", AmountINT: 10, IdempotencyKey: "k-1"})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil || invoices[i].InvoiceID != invoices[0].InvoiceID || invoices[i].InvoiceLink == "" {
+			t.Fatalf("request %d: expected the shared invoice, got %+v (%v)", i+1, invoices[i], err)
+		}
+	}
+	if payments, _ := repo.ListSince(ctx, time.Time{}); len(payments) != 1 {
+		t.Fatalf("expected one stored invoice, got %d", len(payments))
+	}
+}
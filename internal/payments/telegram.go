@@ -0,0 +1,237 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// starsCurrency is the Bot API currency code of Telegram Stars.
+const starsCurrency = "XTR"
+
+// LinkRequest describes a Stars invoice to create.
+type LinkRequest struct {
+	Title       string
+	Description string
+	Payload     string
+	Stars       int64
+}
+
+// InvoiceLinker creates Telegram invoice links.
+type InvoiceLinker interface {
+	CreateInvoiceLink(ctx context.Context, req LinkRequest) (string, error)
+}
+
+// StarsRefunder returns Stars of a successful payment to the payer.
+type StarsRefunder interface {
+	RefundStarPayment(ctx context.Context, userID int64, chargeID string) error
+}
+
+// StarTransactionLister pages through the bot's Stars transactions in
+// chronological order.
+type StarTransactionLister interface {
+	GetStarTransactions(ctx context.Context, offset, limit int) ([]StarTransaction, error)
+}
+
+// BotAPI calls the Telegram Bot API.
+type BotAPI struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func NewBotAPI(client *http.Client, baseURL, token string) (*BotAPI, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, errors.New("telegram bot token is required")
+	}
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &BotAPI{client: client, baseURL: strings.TrimRight(baseURL, "/"), token: token}, nil
+}
+
+type labeledPrice struct {
+	Label  string `json:"label"`
+	Amount int64  `json:"amount"`
+}
+
+type createInvoiceLinkRequest struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Payload     string         `json:"payload"`
+	Currency    string         `json:"currency"`
+	Prices      []labeledPrice `json:"prices"`
+}
+
+type botAPIResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// CreateInvoiceLink calls createInvoiceLink. Stars invoices carry no
+// provider token and a single price in XTR.
+func (b *BotAPI) CreateInvoiceLink(ctx context.Context, req LinkRequest) (string, error) {
+	var link string
+	err := b.call(ctx, "createInvoiceLink", createInvoiceLinkRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Payload:     req.Payload,
+		Currency:    starsCurrency,
+		Prices:      []labeledPrice{{Label: req.Title, Amount: req.Stars}},
+	}, &link)
+	return link, err
+}
+
+type refundStarPaymentRequest struct {
+	UserID   int64  `json:"user_id"`
+	ChargeID string `json:"telegram_payment_charge_id"`
+}
+
+// RefundStarPayment calls refundStarPayment.
+func (b *BotAPI) RefundStarPayment(ctx context.Context, userID int64, chargeID string) error {
+	var ok bool
+	return b.call(ctx, "refundStarPayment", refundStarPaymentRequest{UserID: userID, ChargeID: chargeID}, &ok)
+}
+
+type getStarTransactionsRequest struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type starTransactions struct {
+	Transactions []StarTransaction `json:"transactions"`
+}
+
+// GetStarTransactions calls getStarTransactions; limit is capped at 100 by
+// Telegram.
+func (b *BotAPI) GetStarTransactions(ctx context.Context, offset, limit int) ([]StarTransaction, error) {
+	var result starTransactions
+	if err := b.call(ctx, "getStarTransactions", getStarTransactionsRequest{Offset: offset, Limit: limit}, &result); err != nil {
+		return nil, err
+	}
+	return result.Transactions, nil
+}
+
+func (b *BotAPI) call(ctx context.Context, method string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode %s request: %w", method, err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/bot"+b.token+"/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", method, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		// The URL contains the bot token; keep it out of logs.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", method, err)
+	}
+	var decoded botAPIResponse
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return fmt.Errorf("decode %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !decoded.OK {
+		return fmt.Errorf("%s failed with status %d: %s", method, resp.StatusCode, decoded.Description)
+	}
+	if err := json.Unmarshal(decoded.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// Update is the subset of a Telegram webhook update that carries payments.
+type Update struct {
+	UpdateID         int64             `json:"update_id"`
+	PreCheckoutQuery *PreCheckoutQuery `json:"pre_checkout_query,omitempty"`
+	Message          *Message          `json:"message,omitempty"`
+}
+
+// TelegramUser identifies the Telegram account behind an update.
+type TelegramUser struct {
+	ID int64 `json:"id"`
+}
+
+// PreCheckoutQuery asks the bot to confirm an invoice before Telegram
+// charges the user. It must be answered within 10 seconds.
+type PreCheckoutQuery struct {
+	ID             string       `json:"id"`
+	From           TelegramUser `json:"from"`
+	Currency       string       `json:"currency"`
+	TotalAmount    int64        `json:"total_amount"`
+	InvoicePayload string       `json:"invoice_payload"`
+}
+
+// Message is the subset of a Telegram message that reports payments.
+type Message struct {
+	MessageID         int64              `json:"message_id"`
+	From              *TelegramUser      `json:"from,omitempty"`
+	SuccessfulPayment *SuccessfulPayment `json:"successful_payment,omitempty"`
+}
+
+// SuccessfulPayment confirms that Telegram charged the user.
+type SuccessfulPayment struct {
+	Currency                string `json:"currency"`
+	TotalAmount             int64  `json:"total_amount"`
+	InvoicePayload          string `json:"invoice_payload"`
+	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
+	ProviderPaymentChargeID string `json:"provider_payment_charge_id"`
+}
+
+// PreCheckoutAnswer is sent back as the answerPreCheckoutQuery method in the
+// webhook response, which saves a Bot API round trip inside the deadline.
+type PreCheckoutAnswer struct {
+	Method       string `json:"method"`
+	QueryID      string `json:"pre_checkout_query_id"`
+	OK           bool   `json:"ok"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// StarTransaction is a Stars movement of the bot. Payments from users have
+// a user Source and refunds a user Receiver; both carry the payment's
+// telegram_payment_charge_id as ID.
+type StarTransaction struct {
+	ID       string              `json:"id"`
+	Amount   int64               `json:"amount"`
+	Date     int64               `json:"date"`
+	Source   *TransactionPartner `json:"source,omitempty"`
+	Receiver *TransactionPartner `json:"receiver,omitempty"`
+}
+
+// TransactionPartner describes the other side of a StarTransaction.
+type TransactionPartner struct {
+	Type           string        `json:"type"`
+	User           *TelegramUser `json:"user,omitempty"`
+	InvoicePayload string        `json:"invoice_payload,omitempty"`
+}
+
+// fromUser reports whether the transaction is a payment by a user.
+func (t StarTransaction) fromUser() bool {
+	return t.Source != nil && t.Source.Type == "user" && t.Source.User != nil
+}
+
+// toUser reports whether the transaction returned Stars to a user.
+func (t StarTransaction) toUser() bool {
+	return t.Receiver != nil && t.Receiver.Type == "user"
+}
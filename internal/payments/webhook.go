@@ -0,0 +1,165 @@
+package payments
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// VerifyWebhook checks the X-Telegram-Bot-Api-Secret-Token header value.
+func (s *Service) VerifyWebhook(token string) error {
+	if s.secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.secret)) != 1 {
+		return ErrWebhookUnauthorized
+	}
+	return nil
+}
+
+// HandleUpdate processes a payments webhook update. Pre-checkout queries
+// return the answer to send back; successful payments are credited once and
+// return a nil answer. Other updates are ignored.
+func (s *Service) HandleUpdate(ctx context.Context, update Update) (*PreCheckoutAnswer, error) {
+	switch {
+	case update.PreCheckoutQuery != nil:
+		answer := s.AnswerPreCheckout(ctx, *update.PreCheckoutQuery)
+		return &answer, nil
+	case update.Message != nil && update.Message.SuccessfulPayment != nil:
+		var payerID int64
+		if update.Message.From != nil {
+			payerID = update.Message.From.ID
+		}
+		return nil, s.CompletePayment(ctx, payerID, *update.Message.SuccessfulPayment)
+	default:
+		return nil, nil
+	}
+}
+
+// AnswerPreCheckout approves a checkout only for a pending invoice whose
+// currency and price match what was issued.
+func (s *Service) AnswerPreCheckout(ctx context.Context, query PreCheckoutQuery) PreCheckoutAnswer {
+	answer := PreCheckoutAnswer{Method: "answerPreCheckoutQuery", QueryID: query.ID, OK: true}
+	payment, err := s.repo.GetByInvoiceID(ctx, query.InvoicePayload)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		answer.OK, answer.ErrorMessage = false, "This invoice is no longer available."
+	case err != nil:
+		s.logger.Error("failed to load payment for pre-checkout", zap.String("invoice_id", query.InvoicePayload), zap.Error(err))
+		answer.OK, answer.ErrorMessage = false, "Payments are temporarily unavailable, please try again."
+	case payment.Status == StatusPaid:
+		answer.OK, answer.ErrorMessage = false, "This invoice was already paid."
+	case payment.Status != StatusPending, query.Currency != starsCurrency || query.TotalAmount != payment.Stars:
+		s.logger.Warn("pre-checkout does not match invoice",
+			zap.String("invoice_id", payment.InvoiceID),
+			zap.String("currency", query.Currency),
+			zap.Int64("total_amount", query.TotalAmount),
+			zap.Int64("stars", payment.Stars),
+		)
+		answer.OK, answer.ErrorMessage = false, "This invoice is no longer available."
+	}
+	return answer
+}
+
+// CompletePayment marks the invoice paid by the Telegram user payerID and
+// credits its INT amount. The ledger posting is keyed by the invoice, so
+// Telegram retries and replayed updates credit the user exactly once.
+func (s *Service) CompletePayment(ctx context.Context, payerID int64, paid SuccessfulPayment) error {
+	if s.ledger == nil {
+		return ErrLedgerUnavailable
+	}
+	payment, err := s.repo.GetByInvoiceID(ctx, strings.TrimSpace(paid.InvoicePayload))
+	if err != nil {
+		return err
+	}
+	switch payment.Status {
+	case StatusPaid:
+		// Re-run the observer so a replay finishes work a failed delivery
+		// left behind.
+		return s.notifyTopup(ctx, payment)
+	case StatusRefunded, StatusFailed:
+		return nil
+	}
+	if paid.Currency != starsCurrency || paid.TotalAmount != payment.Stars {
+		detail := fmt.Sprintf("charged %d %s for %d stars", paid.TotalAmount, paid.Currency, payment.Stars)
+		if err := s.flagMismatch(ctx, payment, payerID, paid, detail); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrPaymentMismatch, detail)
+	}
+
+	result, err := s.ledger.Credit(ctx, payment.UserID, payment.AmountINT, wallet.ReasonStarsTopup, payment.ID, TopupKey(payment.InvoiceID))
+	if err != nil {
+		return fmt.Errorf("credit stars top-up: %w", err)
+	}
+
+	fromStatus := payment.Status
+	payment.Status = StatusPaid
+	payment.Payload.TelegramChargeID = paid.TelegramPaymentChargeID
+	payment.Payload.ProviderChargeID = paid.ProviderPaymentChargeID
+	payment.Payload.TelegramUserID = payerID
+	payment.Payload.LedgerEntryID = result.Entry.ID
+	payment.UpdatedAt = s.nowFn()
+	err = s.repo.Update(ctx, payment, fromStatus)
+	if errors.Is(err, ErrInvalidTransition) {
+		// A concurrent delivery of the same update marked it paid first.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.notifyTopup(ctx, payment)
+}
+
+// flagMismatch fails a payment whose charge does not match its invoice and
+// flags it for manual review. The charge and payer are kept so an admin can
+// refund the user.
+func (s *Service) flagMismatch(ctx context.Context, payment Payment, payerID int64, paid SuccessfulPayment, detail string) error {
+	fromStatus := payment.Status
+	payment.Status = StatusFailed
+	payment.Payload.TelegramChargeID = paid.TelegramPaymentChargeID
+	payment.Payload.ProviderChargeID = paid.ProviderPaymentChargeID
+	payment.Payload.TelegramUserID = payerID
+	payment.Payload.Flag = "charge does not match invoice: " + detail
+	payment.UpdatedAt = s.nowFn()
+	err := s.repo.Update(ctx, payment, fromStatus)
+	if errors.Is(err, ErrInvalidTransition) {
+		// A concurrent delivery of the same update flagged it first.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Warn("stars charge does not match invoice",
+		zap.String("invoice_id", payment.InvoiceID),
+		zap.String("user_id", payment.UserID),
+		zap.String("currency", paid.Currency),
+		zap.Int64("total_amount", paid.TotalAmount),
+		zap.Int64("stars", payment.Stars),
+	)
+	return nil
+}
+
+// TopupObserver is notified after a Stars top-up was credited. It may run
+// more than once per payment and must be idempotent.
+type TopupObserver interface {
+	ObserveTopup(ctx context.Context, userID, paymentID string, amountINT int64) error
+}
+
+func (s *Service) notifyTopup(ctx context.Context, payment Payment) error {
+	if s.observer == nil {
+		return nil
+	}
+	if err := s.observer.ObserveTopup(ctx, payment.UserID, payment.ID, payment.AmountINT); err != nil {
+		return fmt.Errorf("observe top-up: %w", err)
+	}
+	return nil
+}
+
+// TopupKey is the ledger idempotency key of an invoice's top-up credit.
+func TopupKey(invoiceID string) string {
+	return "topup:" + invoiceID
+}
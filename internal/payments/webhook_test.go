@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func newWebhookFixture(t *testing.T) (*Service, *wallet.Service, Invoice) {
+	t.Helper()
+	var calls int32
+	ledger := wallet.NewService(wallet.NewInMemoryRepository())
+	svc := NewService(NewInMemoryRepository(), newBotAPIStandIn(t, &calls), 2)
+	svc.WithLedger(ledger)
+	svc.WithWebhookSecret("hook-secret")
+	invoice, err := svc.CreateInvoice(context.Background(), InvoiceRequest{UserID: "u-1", AmountINT: 10})
+	if err != nil {
+		t.Fatalf("CreateInvoice() error = %v", err)
+	}
+	return svc, ledger, invoice
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	svc := NewService(NewInMemoryRepository(), nil, 1)
+	if err := svc.VerifyWebhook(""); !errors.Is(err, ErrWebhookUnauthorized) {
+		t.Fatalf("expected webhooks refused without a secret, got %v", err)
+	}
+	svc.WithWebhookSecret("hook-secret")
+	if err := svc.VerifyWebhook("wrong"); !errors.Is(err, ErrWebhookUnauthorized) {
+		t.Fatalf("expected ErrWebhookUnauthorized, got %v", err)
+	}
+	if err := svc.VerifyWebhook("hook-secret"); err != nil {
+		t.Fatalf("VerifyWebhook() error = %v", err)
+	}
+}
+
+func TestAnswerPreCheckout(t *testing.T) {
+	ctx := context.Background()
+	svc, _, invoice := newWebhookFixture(t)
+
+	answer, err := svc.HandleUpdate(ctx, Update{PreCheckoutQuery: &PreCheckoutQuery{ID: "q-1", Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload}})
+	if err != nil || answer == nil || !answer.OK || answer.QueryID != "q-1" || answer.Method != "answerPreCheckoutQuery" {
+		t.Fatalf("expected approval, got %+v (%v)", answer, err)
+	}
+	for name, query := range map[string]PreCheckoutQuery{
+		"unknown invoice": {ID: "q-2", Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: "inv_missing"},
+		"wrong amount":    {ID: "q-3", Currency: "XTR", TotalAmount: invoice.Stars + 1, InvoicePayload: invoice.TgInvoicePayload},
+		"wrong currency":  {ID: "q-4", Currency: "USD", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload},
+	} {
+		if answer := svc.AnswerPreCheckout(ctx, query); answer.OK || answer.ErrorMessage == "" {
+			t.Fatalf("%s: expected rejection, got %+v", name, answer)
+		}
+	}
+}
+
+func TestCompletePaymentCreditsOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, invoice := newWebhookFixture(t)
+	update := Update{UpdateID: 7, Message: &Message{From: &TelegramUser{ID: 42}, SuccessfulPayment: &SuccessfulPayment{
+		Currency:                "XTR",
+		TotalAmount:             invoice.Stars,
+		InvoicePayload:          invoice.TgInvoicePayload,
+		TelegramPaymentChargeID: "tg-charge-1",
+	}}}
+
+	for i := 0; i < 2; i++ {
+		answer, err := svc.HandleUpdate(ctx, update)
+		if err != nil || answer != nil {
+			t.Fatalf("delivery %d: unexpected result %+v (%v)", i+1, answer, err)
+		}
+	}
+	if balance, err := ledger.Balance(ctx, "u-1"); err != nil || balance != 10 {
+		t.Fatalf("expected balance 10, got %d (%v)", balance, err)
+	}
+	payment, err := svc.repo.GetByInvoiceID(ctx, invoice.InvoiceID)
+	if err != nil || payment.Status != StatusPaid || payment.Payload.TelegramChargeID != "tg-charge-1" || payment.Payload.TelegramUserID != 42 || payment.Payload.LedgerEntryID == "" {
+		t.Fatalf("expected paid payment, got %+v (%v)", payment, err)
+	}
+	if answer := svc.AnswerPreCheckout(ctx, PreCheckoutQuery{ID: "q-5", Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload}); answer.OK {
+		t.Fatalf("expected a paid invoice to be rejected at checkout, got %+v", answer)
+	}
+}
+
+func TestCompletePaymentMismatch(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, invoice := newWebhookFixture(t)
+
+	err := svc.CompletePayment(ctx, 42, SuccessfulPayment{Currency: "XTR", TotalAmount: 1, InvoicePayload: invoice.TgInvoicePayload, TelegramPaymentChargeID: "tg-charge-1"})
+	if !errors.Is(err, ErrPaymentMismatch) {
+		t.Fatalf("expected ErrPaymentMismatch, got %v", err)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-1"); balance != 0 {
+		t.Fatalf("expected no credit, got %d", balance)
+	}
+	flagged, err := svc.ListFlagged(ctx)
+	if err != nil || len(flagged) != 1 {
+		t.Fatalf("expected the payment flagged, got %+v (%v)", flagged, err)
+	}
+	if payment := flagged[0]; payment.Status != StatusFailed || payment.Payload.Flag == "" || payment.Payload.TelegramChargeID != "tg-charge-1" || payment.Payload.TelegramUserID != 42 {
+		t.Fatalf("unexpected flagged payment %+v", payment)
+	}
+	// A replay of the mismatched charge is already handled.
+	if err := svc.CompletePayment(ctx, 42, SuccessfulPayment{Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload}); err != nil {
+		t.Fatalf("expected a failed payment to be ignored, got %v", err)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-1"); balance != 0 {
+		t.Fatalf("expected no credit after replay, got %d", balance)
+	}
+	if err := svc.CompletePayment(ctx, 42, SuccessfulPayment{Currency: "XTR", TotalAmount: 1, InvoicePayload: "inv_missing"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+type topupObserverFunc func(ctx context.Context, userID, paymentID string, amountINT int64) error
+
+func (f topupObserverFunc) ObserveTopup(ctx context.Context, userID, paymentID string, amountINT int64) error {
+	return f(ctx, userID, paymentID, amountINT)
+}
+
+func TestCompletePaymentRetriesObserver(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, invoice := newWebhookFixture(t)
+	var observed []int64
+	svc.WithTopupObserver(topupObserverFunc(func(_ context.Context, userID, _ string, amountINT int64) error {
+		observed = append(observed, amountINT)
+		if len(observed) == 1 {
+			return errors.New("referrals unavailable")
+		}
+		if userID != "u-1" {
+			t.Fatalf("unexpected user %s", userID)
+		}
+		return nil
+	}))
+	paid := SuccessfulPayment{Currency: "XTR", TotalAmount: invoice.Stars, InvoicePayload: invoice.TgInvoicePayload}
+
+	if err := svc.CompletePayment(ctx, 42, paid); err == nil {
+		t.Fatal("expected the observer error to surface")
+	}
+	if err := svc.CompletePayment(ctx, 42, paid); err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	if len(observed) != 2 || observed[1] != 10 {
+		t.Fatalf("expected the observer to run again on replay, got %v", observed)
+	}
+	if balance, err := ledger.Balance(ctx, "u-1"); err != nil || balance != 10 {
+		t.Fatalf("expected balance 10, got %d (%v)", balance, err)
+	}
+}
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	ScopeGlobal   = "global"
+	ScopeStreamer = "streamer"
+	ScopeGame     = "game"
+)
+
+// Switch pauses the whole pipeline or individual stages for a scope.
+type Switch struct {
+	Scope        string    `json:"scope"`
+	TargetID     string    `json:"targetId,omitempty"`
+	Paused       bool      `json:"paused"`
+	PausedStages []string  `json:"pausedStages"`
+	UpdatedBy    string    `json:"updatedBy"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// SetRequest describes an admin change to a pipeline switch.
+type SetRequest struct {
+	Scope        string
+	TargetID     string
+	Paused       bool
+	PausedStages []string
+	ActorID      string
+}
+
+// State is the effective pipeline configuration after merging global,
+// game and streamer switches. Any pausing switch wins.
+type State struct {
+	Paused       bool     `json:"paused"`
+	PausedStages []string `json:"pausedStages"`
+	Sources      []string `json:"sources"`
+}
+
+// StageEnabled reports whether the given stage may run.
+func (s State) StageEnabled(stage string) bool {
+	if s.Paused {
+		return false
+	}
+	for _, paused := range s.PausedStages {
+		if paused == stage {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeStages(current, extra []string) []string {
+	seen := make(map[string]struct{}, len(current)+len(extra))
+	merged := make([]string, 0, len(current)+len(extra))
+	for _, stage := range append(append([]string{}, current...), extra...) {
+		if _, ok := seen[stage]; ok {
+			continue
+		}
+		seen[stage] = struct{}{}
+		merged = append(merged, stage)
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/prompts"
+)
+
+var (
+	ErrInvalidScope   = errors.New("scope must be one of: global, streamer, game")
+	ErrTargetRequired = errors.New("targetId is required for streamer and game scopes")
+	ErrInvalidStage   = errors.New("pausedStages must contain only: stage_a, stage_b, stage_c, stage_d")
+)
+
+// Service stores admin pipeline switches and resolves the effective state per streamer.
+type Service struct {
+	mu       sync.RWMutex
+	switches map[string]Switch
+	nowFn    func() time.Time
+}
+
+func NewService() *Service {
+	return &Service{
+		switches: make(map[string]Switch),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Set creates or replaces the switch for a scope. A switch that neither pauses
+// the pipeline nor any stage is removed, resuming normal processing.
+func (s *Service) Set(_ context.Context, req SetRequest) (Switch, error) {
+	scope := strings.ToLower(strings.TrimSpace(req.Scope))
+	targetID := strings.TrimSpace(req.TargetID)
+	switch scope {
+	case ScopeGlobal:
+		targetID = ""
+	case ScopeStreamer, ScopeGame:
+		if targetID == "" {
+			return Switch{}, ErrTargetRequired
+		}
+	default:
+		return Switch{}, ErrInvalidScope
+	}
+
+	stages := make([]string, 0, len(req.PausedStages))
+	for _, stage := range req.PausedStages {
+		stage = strings.ToLower(strings.TrimSpace(stage))
+		if !prompts.IsSupportedStage(stage) {
+			return Switch{}, ErrInvalidStage
+		}
+		stages = append(stages, stage)
+	}
+
+	item := Switch{
+		Scope:        scope,
+		TargetID:     targetID,
+		Paused:       req.Paused,
+		PausedStages: mergeStages(nil, stages),
+		UpdatedBy:    strings.TrimSpace(req.ActorID),
+		UpdatedAt:    s.nowFn(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := switchKey(scope, targetID)
+	if !item.Paused && len(item.PausedStages) == 0 {
+		delete(s.switches, key)
+		return item, nil
+	}
+	s.switches[key] = item
+	return item, nil
+}
+
+// List returns all active switches ordered by scope and target.
+func (s *Service) List(_ context.Context) []Switch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]Switch, 0, len(s.switches))
+	for _, item := range s.switches {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Scope == items[j].Scope {
+			return items[i].TargetID < items[j].TargetID
+		}
+		return items[i].Scope < items[j].Scope
+	})
+	return items
+}
+
+// Resolve merges global, game and streamer switches. gameID may be empty when
+// the streamer's pipeline is not bound to a game.
+func (s *Service) Resolve(_ context.Context, streamerID, gameID string) State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := State{PausedStages: []string{}, Sources: []string{}}
+	keys := []string{switchKey(ScopeGlobal, "")}
+	if id := strings.TrimSpace(gameID); id != "" {
+		keys = append(keys, switchKey(ScopeGame, id))
+	}
+	if id := strings.TrimSpace(streamerID); id != "" {
+		keys = append(keys, switchKey(ScopeStreamer, id))
+	}
+	for _, key := range keys {
+		item, ok := s.switches[key]
+		if !ok {
+			continue
+		}
+		state.Paused = state.Paused || item.Paused
+		state.PausedStages = mergeStages(state.PausedStages, item.PausedStages)
+		state.Sources = append(state.Sources, key)
+	}
+	return state
+}
+
+func switchKey(scope, targetID string) string {
+	if scope == ScopeGlobal {
+		return ScopeGlobal
+	}
+	return scope + ":" + targetID
+}
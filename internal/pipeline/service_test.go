@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSetAndResolve(t *testing.T) {
+	svc := NewService()
+	ctx := context.Background()
+
+	if _, err := svc.Set(ctx, SetRequest{Scope: ScopeGame, TargetID: "game-1", PausedStages: []string{"stage_d"}, ActorID: "admin-1"}); err != nil {
+		t.Fatalf("Set(game) error = %v", err)
+	}
+	if _, err := svc.Set(ctx, SetRequest{Scope: ScopeStreamer, TargetID: "str-1", PausedStages: []string{"stage_b"}, ActorID: "admin-1"}); err != nil {
+		t.Fatalf("Set(streamer) error = %v", err)
+	}
+
+	state := svc.Resolve(ctx, "str-1", "game-1")
+	if state.Paused {
+		t.Fatal("expected pipeline not fully paused")
+	}
+	if state.StageEnabled("stage_b") || state.StageEnabled("stage_d") {
+		t.Fatalf("expected stage_b and stage_d paused, got %+v", state.PausedStages)
+	}
+	if !state.StageEnabled("stage_a") {
+		t.Fatal("expected stage_a enabled")
+	}
+
+	if other := svc.Resolve(ctx, "str-2", ""); len(other.PausedStages) != 0 {
+		t.Fatalf("expected other streamer unaffected, got %+v", other)
+	}
+
+	if _, err := svc.Set(ctx, SetRequest{Scope: ScopeGlobal, Paused: true}); err != nil {
+		t.Fatalf("Set(global) error = %v", err)
+	}
+	if !svc.Resolve(ctx, "str-2", "").Paused {
+		t.Fatal("expected global pause to apply to every streamer")
+	}
+
+	if _, err := svc.Set(ctx, SetRequest{Scope: ScopeGlobal, Paused: false}); err != nil {
+		t.Fatalf("Set(global resume) error = %v", err)
+	}
+	if len(svc.List(ctx)) != 2 {
+		t.Fatalf("expected resumed global switch to be removed, got %d switches", len(svc.List(ctx)))
+	}
+}
+
+func TestSetValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  SetRequest
+		err  error
+	}{
+		{name: "invalid scope", req: SetRequest{Scope: "region", Paused: true}, err: ErrInvalidScope},
+		{name: "missing target", req: SetRequest{Scope: ScopeStreamer, Paused: true}, err: ErrTargetRequired},
+		{name: "invalid stage", req: SetRequest{Scope: ScopeGlobal, PausedStages: []string{"stage_z"}}, err: ErrInvalidStage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewService().Set(context.Background(), tt.req); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ var (
 	ErrInvalidBackoffMS     = errors.New("backoffMs must be greater than or equal to 0")
 	ErrInvalidCooldownMS    = errors.New("cooldownMs must be greater than or equal to 0")
 	ErrInvalidMinConfidence = errors.New("minConfidence must be between 0 and 1")
+	ErrInvalidFallbackModel = errors.New("fallbackModel must differ from model")
 	ErrNotFound             = errors.New("prompt version not found")
 )
 
@@ -38,6 +39,7 @@ type CreateRequest struct {
 	Stage         string
 	Template      string
 	Model         string
+	FallbackModel string
 	Temperature   float64
 	MaxTokens     int
 	TimeoutMS     int
@@ -45,27 +47,30 @@ type CreateRequest struct {
 	BackoffMS     int
 	CooldownMS    int
 	MinConfidence float64
+	OutputSchema  OutputSchema
 	ActorID       string
 }
 
 type PromptVersion struct {
-	ID            string    `json:"id"`
-	Stage         string    `json:"stage"`
-	Version       int       `json:"version"`
-	Template      string    `json:"template"`
-	Model         string    `json:"model"`
-	Temperature   float64   `json:"temperature"`
-	MaxTokens     int       `json:"maxTokens"`
-	TimeoutMS     int       `json:"timeoutMs"`
-	RetryCount    int       `json:"retryCount"`
-	BackoffMS     int       `json:"backoffMs"`
-	CooldownMS    int       `json:"cooldownMs"`
-	MinConfidence float64   `json:"minConfidence"`
-	IsActive      bool      `json:"isActive"`
-	CreatedBy     string    `json:"createdBy"`
-	ActivatedBy   string    `json:"activatedBy,omitempty"`
-	CreatedAt     time.Time `json:"createdAt"`
-	ActivatedAt   time.Time `json:"activatedAt,omitempty"`
+	ID            string       `json:"id"`
+	Stage         string       `json:"stage"`
+	Version       int          `json:"version"`
+	Template      string       `json:"template"`
+	Model         string       `json:"model"`
+	FallbackModel string       `json:"fallbackModel,omitempty"`
+	Temperature   float64      `json:"temperature"`
+	MaxTokens     int          `json:"maxTokens"`
+	TimeoutMS     int          `json:"timeoutMs"`
+	RetryCount    int          `json:"retryCount"`
+	BackoffMS     int          `json:"backoffMs"`
+	CooldownMS    int          `json:"cooldownMs"`
+	MinConfidence float64      `json:"minConfidence"`
+	OutputSchema  OutputSchema `json:"outputSchema"`
+	IsActive      bool         `json:"isActive"`
+	CreatedBy     string       `json:"createdBy"`
+	ActivatedBy   string       `json:"activatedBy,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	ActivatedAt   time.Time    `json:"activatedAt,omitempty"`
 }
 
 func ValidateCreateRequest(req CreateRequest) error {
@@ -79,6 +84,9 @@ func ValidateCreateRequest(req CreateRequest) error {
 	if strings.TrimSpace(req.Model) == "" {
 		return ErrInvalidModel
 	}
+	if fallback := strings.TrimSpace(req.FallbackModel); fallback != "" && fallback == strings.TrimSpace(req.Model) {
+		return ErrInvalidFallbackModel
+	}
 	if req.Temperature < 0 || req.Temperature > 2 {
 		return ErrInvalidTemperature
 	}
@@ -100,5 +108,13 @@ func ValidateCreateRequest(req CreateRequest) error {
 	if req.MinConfidence < 0 || req.MinConfidence > 1 {
 		return ErrInvalidMinConfidence
 	}
+	if len(req.OutputSchema.Labels) > 0 {
+		return ValidateOutputSchema(req.Stage, req.OutputSchema)
+	}
 	return nil
 }
+
+func IsSupportedStage(stage string) bool {
+	_, ok := supportedStages[stage]
+	return ok
+}
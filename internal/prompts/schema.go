@@ -0,0 +1,159 @@
+package prompts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidOutputSchema = errors.New("outputSchema labels must be a non-empty subset of the stage labels")
+	ErrSchemaViolation     = errors.New("llm response violates output schema")
+)
+
+var stageLabels = map[string][]string{
+	StageA: {"cs_detected", "not_cs", "uncertain"},
+	StageB: {"competitive", "faceit", "premier", "casual", "unknown"},
+	StageC: {"pregame", "in_progress", "finished", "unknown"},
+	StageD: {"win", "loss", "draw", "unknown"},
+}
+
+// OutputSchema declares the JSON object a stage response must conform to.
+// Every response carries a label from Labels and a confidence in [0, 1];
+// reasoning and evidence are accepted only when enabled.
+type OutputSchema struct {
+	Labels    []string `json:"labels"`
+	Reasoning bool     `json:"reasoning"`
+	Evidence  bool     `json:"evidence"`
+}
+
+// StructuredOutput is a stage response that passed schema validation.
+type StructuredOutput struct {
+	Label      string   `json:"label"`
+	Confidence float64  `json:"confidence"`
+	Reasoning  string   `json:"reasoning,omitempty"`
+	Evidence   []string `json:"evidence,omitempty"`
+}
+
+// DefaultOutputSchema returns the schema with the full label enum of a stage.
+func DefaultOutputSchema(stage string) OutputSchema {
+	labels := stageLabels[strings.TrimSpace(stage)]
+	out := make([]string, len(labels))
+	copy(out, labels)
+	return OutputSchema{Labels: out}
+}
+
+// ValidateOutputSchema checks that the schema labels are unique and supported by the stage.
+func ValidateOutputSchema(stage string, schema OutputSchema) error {
+	allowed := stageLabels[strings.TrimSpace(stage)]
+	if len(schema.Labels) == 0 || len(allowed) == 0 {
+		return ErrInvalidOutputSchema
+	}
+	seen := make(map[string]struct{}, len(schema.Labels))
+	for _, label := range schema.Labels {
+		if _, dup := seen[label]; dup || !containsLabel(allowed, label) {
+			return ErrInvalidOutputSchema
+		}
+		seen[label] = struct{}{}
+	}
+	return nil
+}
+
+// JSONSchema renders the schema as a JSON Schema document for providers that
+// support structured (constrained) output. Strict structured output requires
+// every property to be listed in required, so the optional reasoning and
+// evidence fields are required but nullable.
+func (s OutputSchema) JSONSchema() map[string]any {
+	properties := map[string]any{
+		"label":      map[string]any{"type": "string", "enum": s.Labels},
+		"confidence": map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+	}
+	required := []string{"label", "confidence"}
+	if s.Reasoning {
+		properties["reasoning"] = map[string]any{"type": []string{"string", "null"}}
+		required = append(required, "reasoning")
+	}
+	if s.Evidence {
+		properties["evidence"] = map[string]any{"type": []string{"array", "null"}, "items": map[string]any{"type": "string"}}
+		required = append(required, "evidence")
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// Parse strictly decodes a raw model response. Any deviation from the schema
+// is reported as ErrSchemaViolation.
+func (s OutputSchema) Parse(raw string) (StructuredOutput, error) {
+	var fields map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(raw))))
+	if err := decoder.Decode(&fields); err != nil {
+		return StructuredOutput{}, fmt.Errorf("%w: response is not a JSON object", ErrSchemaViolation)
+	}
+	if decoder.More() {
+		return StructuredOutput{}, fmt.Errorf("%w: trailing data after JSON object", ErrSchemaViolation)
+	}
+
+	var out StructuredOutput
+	for key, value := range fields {
+		var err error
+		switch {
+		case key == "label":
+			err = json.Unmarshal(value, &out.Label)
+		case key == "confidence":
+			var confidence *float64
+			err = json.Unmarshal(value, &confidence)
+			if err == nil && confidence == nil {
+				err = errors.New("null value")
+			}
+			if confidence != nil {
+				out.Confidence = *confidence
+			}
+		case key == "reasoning" && s.Reasoning:
+			err = json.Unmarshal(value, &out.Reasoning)
+		case key == "evidence" && s.Evidence:
+			err = json.Unmarshal(value, &out.Evidence)
+		default:
+			return StructuredOutput{}, fmt.Errorf("%w: unexpected field %q", ErrSchemaViolation, key)
+		}
+		if err != nil {
+			return StructuredOutput{}, fmt.Errorf("%w: invalid %s", ErrSchemaViolation, key)
+		}
+	}
+
+	if _, ok := fields["label"]; !ok {
+		return StructuredOutput{}, fmt.Errorf("%w: label is required", ErrSchemaViolation)
+	}
+	if _, ok := fields["confidence"]; !ok {
+		return StructuredOutput{}, fmt.Errorf("%w: confidence is required", ErrSchemaViolation)
+	}
+	if err := s.Validate(out); err != nil {
+		return StructuredOutput{}, err
+	}
+	return out, nil
+}
+
+// Validate checks already decoded output against the label enum and confidence range.
+func (s OutputSchema) Validate(out StructuredOutput) error {
+	if !containsLabel(s.Labels, out.Label) {
+		return fmt.Errorf("%w: label %q is not in enum", ErrSchemaViolation, out.Label)
+	}
+	if out.Confidence < 0 || out.Confidence > 1 {
+		return fmt.Errorf("%w: confidence must be between 0 and 1", ErrSchemaViolation)
+	}
+	return nil
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, item := range labels {
+		if item == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package prompts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOutputSchemaParse(t *testing.T) {
+	schema := OutputSchema{Labels: []string{"cs_detected", "not_cs", "uncertain"}, Reasoning: true}
+
+	tests := []struct {
+		name string
+		raw  string
+		want StructuredOutput
+		err  error
+	}{
+		{name: "valid", raw: `{"label":"cs_detected","confidence":0.9}`, want: StructuredOutput{Label: "cs_detected", Confidence: 0.9}},
+		{name: "valid with reasoning", raw: `{"label":"not_cs","confidence":0.7,"reasoning":"menu screen"}`, want: StructuredOutput{Label: "not_cs", Confidence: 0.7, Reasoning: "menu screen"}},
+		{name: "null reasoning", raw: `{"label":"not_cs","confidence":0.7,"reasoning":null}`, want: StructuredOutput{Label: "not_cs", Confidence: 0.7}},
+		{name: "free text", raw: "yes", err: ErrSchemaViolation},
+		{name: "label outside enum", raw: `{"label":"counter strike","confidence":0.9}`, err: ErrSchemaViolation},
+		{name: "missing confidence", raw: `{"label":"cs_detected"}`, err: ErrSchemaViolation},
+		{name: "confidence out of range", raw: `{"label":"cs_detected","confidence":1.4}`, err: ErrSchemaViolation},
+		{name: "evidence not enabled", raw: `{"label":"cs_detected","confidence":0.9,"evidence":["hud"]}`, err: ErrSchemaViolation},
+		{name: "trailing data", raw: `{"label":"cs_detected","confidence":0.9} {}`, err: ErrSchemaViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Parse(tt.raw)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.Label != tt.want.Label || got.Confidence != tt.want.Confidence || got.Reasoning != tt.want.Reasoning {
+				t.Fatalf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutputSchema(t *testing.T) {
+	if err := ValidateOutputSchema(StageC, OutputSchema{Labels: []string{"in_progress", "finished"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateOutputSchema(StageC, OutputSchema{Labels: []string{"win"}}); !errors.Is(err, ErrInvalidOutputSchema) {
+		t.Fatalf("expected ErrInvalidOutputSchema, got %v", err)
+	}
+	if err := ValidateOutputSchema(StageA, OutputSchema{Labels: []string{"not_cs", "not_cs"}}); !errors.Is(err, ErrInvalidOutputSchema) {
+		t.Fatalf("expected ErrInvalidOutputSchema for duplicates, got %v", err)
+	}
+}
+
+func TestOutputSchemaJSONSchemaIsStrict(t *testing.T) {
+	document := OutputSchema{Labels: []string{"win", "loss"}, Reasoning: true, Evidence: true}.JSONSchema()
+	properties := document["properties"].(map[string]any)
+	required := document["required"].([]string)
+	if len(required) != len(properties) {
+		t.Fatalf("expected every property to be required, got %v for %d properties", required, len(properties))
+	}
+	for _, name := range required {
+		if _, ok := properties[name]; !ok {
+			t.Fatalf("required %q is not a property", name)
+		}
+	}
+	reasoning := properties["reasoning"].(map[string]any)
+	if types, ok := reasoning["type"].([]string); !ok || len(types) != 2 || types[1] != "null" {
+		t.Fatalf("expected nullable reasoning, got %v", reasoning["type"])
+	}
+}
@@ -49,12 +49,17 @@ func (s *Service) Create(_ context.Context, req CreateRequest) (PromptVersion, e
 	nextVersion := len(s.versions[stage]) + 1
 	s.counter++
 	now := time.Now().UTC()
+	schema := req.OutputSchema
+	if len(schema.Labels) == 0 {
+		schema = DefaultOutputSchema(stage)
+	}
 	item := PromptVersion{
 		ID:            fmt.Sprintf("prompt-%d", s.counter),
 		Stage:         stage,
 		Version:       nextVersion,
 		Template:      strings.TrimSpace(req.Template),
 		Model:         strings.TrimSpace(req.Model),
+		FallbackModel: strings.TrimSpace(req.FallbackModel),
 		Temperature:   req.Temperature,
 		MaxTokens:     req.MaxTokens,
 		TimeoutMS:     req.TimeoutMS,
@@ -62,6 +67,7 @@ func (s *Service) Create(_ context.Context, req CreateRequest) (PromptVersion, e
 		BackoffMS:     req.BackoffMS,
 		CooldownMS:    req.CooldownMS,
 		MinConfidence: req.MinConfidence,
+		OutputSchema:  schema,
 		CreatedBy:     strings.TrimSpace(req.ActorID),
 		CreatedAt:     now,
 	}
@@ -98,3 +104,16 @@ func (s *Service) Activate(_ context.Context, id, actorID string) (PromptVersion
 
 	return PromptVersion{}, ErrNotFound
 }
+
+// Active returns the currently active prompt version for a stage.
+func (s *Service) Active(_ context.Context, stage string) (PromptVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.versions[strings.TrimSpace(stage)] {
+		if item.IsActive {
+			return item, nil
+		}
+	}
+	return PromptVersion{}, ErrNotFound
+}
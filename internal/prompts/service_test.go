@@ -34,6 +34,17 @@ func TestCreateAndActivate(t *testing.T) {
 	if !active.IsActive {
 		t.Fatal("expected active prompt")
 	}
+	if len(active.OutputSchema.Labels) != 3 {
+		t.Fatalf("expected default stage_a output schema, got %+v", active.OutputSchema)
+	}
+
+	current, err := svc.Active(context.Background(), StageA)
+	if err != nil {
+		t.Fatalf("Active() error = %v", err)
+	}
+	if current.ID != created.ID {
+		t.Fatalf("expected active prompt %s, got %s", created.ID, current.ID)
+	}
 }
 
 func TestValidateCreateRequest(t *testing.T) {
@@ -52,6 +63,16 @@ func TestValidateCreateRequest(t *testing.T) {
 			req:  CreateRequest{Stage: StageA, Template: "a", Model: "m", Temperature: 0, MaxTokens: 1, TimeoutMS: 1, MinConfidence: 1.5},
 			err:  ErrInvalidMinConfidence,
 		},
+		{
+			name: "invalid output schema",
+			req:  CreateRequest{Stage: StageA, Template: "a", Model: "m", Temperature: 0, MaxTokens: 1, TimeoutMS: 1, MinConfidence: 0.1, OutputSchema: OutputSchema{Labels: []string{"win"}}},
+			err:  ErrInvalidOutputSchema,
+		},
+		{
+			name: "fallback equals model",
+			req:  CreateRequest{Stage: StageA, Template: "a", Model: "m", FallbackModel: " m ", Temperature: 0, MaxTokens: 1, TimeoutMS: 1, MinConfidence: 0.1},
+			err:  ErrInvalidFallbackModel,
+		},
 		{
 			name: "ok",
 			req:  CreateRequest{Stage: StageB, Template: "a", Model: "m", Temperature: 0.2, MaxTokens: 1, TimeoutMS: 1, MinConfidence: 0.4},
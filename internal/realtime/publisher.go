@@ -0,0 +1,98 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Message types from docs/ws_messages.md.
+const (
+	TypeEventCreated   = "EVENT_CREATED"
+	TypeEventUpdated   = "EVENT_UPDATED"
+	TypeEventClosed    = "EVENT_CLOSED"
+	TypeBalanceUpdated = "BALANCE_UPDATED"
+	TypeSystemNotice   = "SYSTEM_NOTICE"
+)
+
+// Message is the envelope delivered to WebSocket subscribers.
+type Message struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+}
+
+// Publisher fans messages out to a subscription channel on every node.
+type Publisher interface {
+	Publish(ctx context.Context, channel string, msg Message) error
+}
+
+func StreamerChannel(streamerID string) string {
+	return "streamer:" + streamerID
+}
+
+func GameChannel(gameID string) string {
+	return "game:" + gameID
+}
+
+func UserChannel(userID string) string {
+	return "user:" + userID
+}
+
+// RedisPublisher publishes messages through Redis Pub/Sub.
+type RedisPublisher struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedisPublisher(client redis.UniversalClient, keyPrefix string) (*RedisPublisher, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = "funpot:realtime"
+	}
+	return &RedisPublisher{client: client, keyPrefix: keyPrefix}, nil
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, channel string, msg Message) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(ctx, p.keyPrefix+":"+channel, raw).Err()
+}
+
+// Published is a message captured by InMemoryPublisher.
+type Published struct {
+	Channel string
+	Message Message
+}
+
+// InMemoryPublisher records messages; used in tests and single-node setups
+// without Redis.
+type InMemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Published
+}
+
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{}
+}
+
+func (p *InMemoryPublisher) Publish(_ context.Context, channel string, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, Published{Channel: channel, Message: msg})
+	return nil
+}
+
+func (p *InMemoryPublisher) Messages() []Published {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Published, len(p.messages))
+	copy(out, p.messages)
+	return out
+}
@@ -0,0 +1,133 @@
+package referrals
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// InMemoryRepository keeps payouts and fingerprints in process memory; used
+// in tests and local runs without PostgreSQL.
+type InMemoryRepository struct {
+	mu           sync.RWMutex
+	items        map[string]Payout
+	fingerprints map[fingerprintKey]Fingerprint
+}
+
+type fingerprintKey struct {
+	userID, ipHash, deviceHash string
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		items:        make(map[string]Payout),
+		fingerprints: make(map[fingerprintKey]Fingerprint),
+	}
+}
+
+func (r *InMemoryRepository) Create(_ context.Context, payout Payout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[payout.ID]; !ok {
+		r.items[payout.ID] = payout
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) Get(_ context.Context, id string) (Payout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	payout, ok := r.items[id]
+	if !ok {
+		return Payout{}, ErrNotFound
+	}
+	return payout, nil
+}
+
+func (r *InMemoryRepository) Update(_ context.Context, payout Payout, fromStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.items[payout.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Status != fromStatus {
+		return ErrInvalidTransition
+	}
+	r.items[payout.ID] = payout
+	return nil
+}
+
+func (r *InMemoryRepository) ListByInviter(_ context.Context, inviterID string) ([]Payout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Payout, 0)
+	for _, payout := range r.items {
+		if payout.InviterUserID == inviterID {
+			result = append(result, payout)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID > result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (r *InMemoryRepository) List(_ context.Context, status string) ([]Payout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Payout, 0)
+	for _, payout := range r.items {
+		if status == "" || payout.Status == status {
+			result = append(result, payout)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (r *InMemoryRepository) RecordFingerprint(_ context.Context, fingerprint Fingerprint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fingerprints[fingerprintKey{fingerprint.UserID, fingerprint.IPHash, fingerprint.DeviceHash}] = fingerprint
+	return nil
+}
+
+func (r *InMemoryRepository) SharedFingerprintUsers(_ context.Context, userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ips := make(map[string]bool)
+	devices := make(map[string]bool)
+	for _, fingerprint := range r.fingerprints {
+		if fingerprint.UserID != userID {
+			continue
+		}
+		if fingerprint.IPHash != "" {
+			ips[fingerprint.IPHash] = true
+		}
+		if fingerprint.DeviceHash != "" {
+			devices[fingerprint.DeviceHash] = true
+		}
+	}
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	for _, fingerprint := range r.fingerprints {
+		if fingerprint.UserID == userID || seen[fingerprint.UserID] {
+			continue
+		}
+		if ips[fingerprint.IPHash] || devices[fingerprint.DeviceHash] {
+			seen[fingerprint.UserID] = true
+			result = append(result, fingerprint.UserID)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
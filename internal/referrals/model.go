@@ -0,0 +1,108 @@
+package referrals
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserIDRequired    = errors.New("userId is required")
+	ErrNotFound          = errors.New("referral payout not found")
+	ErrInvalidTransition = errors.New("referral payout status transition is not allowed")
+	ErrReasonRequired    = errors.New("reason is required")
+)
+
+// TierSingle pays inviters for their direct invitees only.
+const TierSingle = "single"
+
+// Payout statuses. A payout is pending until its ledger credit is posted
+// and paid afterwards; payouts scored as risky are held for an admin, who
+// pays or rejects them. When the top-up is refunded an unpaid payout is
+// rejected and a paid one is reversed by debiting the bonus back.
+const (
+	StatusPending  = "pending"
+	StatusHeld     = "held"
+	StatusPaid     = "paid"
+	StatusRejected = "rejected"
+	StatusReversed = "reversed"
+)
+
+// IsValidStatus reports whether status is a known payout status.
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusHeld, StatusPaid, StatusRejected, StatusReversed:
+		return true
+	default:
+		return false
+	}
+}
+
+func canTransition(from, to string) bool {
+	switch to {
+	case StatusPaid:
+		return from == StatusPending || from == StatusHeld
+	case StatusRejected:
+		return from == StatusHeld || from == StatusPending
+	case StatusReversed:
+		return from == StatusPaid
+	default:
+		return false
+	}
+}
+
+// Payout is a referral_bonus owed to an inviter for one of their invitees'
+// Stars top-ups.
+type Payout struct {
+	ID            string `json:"id"`
+	InviterUserID string `json:"inviterUserId"`
+	InviteeUserID string `json:"inviteeUserId"`
+	PaymentID     string `json:"paymentId"`
+	AmountINT     int64  `json:"amountINT"`
+	Status        string `json:"status"`
+	// RiskScore and RiskSignals record the abuse assessment made when the
+	// payout was created.
+	RiskScore     int       `json:"riskScore"`
+	RiskSignals   []string  `json:"riskSignals,omitempty"`
+	ReviewedBy    string    `json:"reviewedBy,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	LedgerEntryID string    `json:"ledgerEntryId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Fingerprint is a hashed IP address and device seen at a user's login.
+type Fingerprint struct {
+	UserID     string
+	IPHash     string
+	DeviceHash string
+	SeenAt     time.Time
+}
+
+// Invitee is a user attributed to an inviter and what they earned them.
+type Invitee struct {
+	UserID      string    `json:"userId"`
+	JoinedAt    time.Time `json:"joinedAt"`
+	EarningsINT int64     `json:"earningsINT"`
+}
+
+// Summary is returned by GET /api/referrals/summary.
+type Summary struct {
+	ReferralCode string    `json:"referralCode"`
+	URL          string    `json:"url"`
+	Tier         string    `json:"tier"`
+	Percent      float64   `json:"percent"`
+	InvitedCount int       `json:"invitedCount"`
+	EarningsINT  int64     `json:"earningsINT"`
+	Invited      []Invitee `json:"invited"`
+}
+
+// BonusKey is the ledger idempotency key of the bonus paid for a top-up.
+func BonusKey(paymentID string) string {
+	return "referral:" + paymentID
+}
+
+// ReversalKey is the ledger idempotency key of the debit that takes back the
+// bonus of a refunded top-up.
+func ReversalKey(paymentID string) string {
+	return "referral-reversal:" + paymentID
+}
@@ -0,0 +1,213 @@
+package referrals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const payoutColumns = `id, inviter_user_id, invitee_user_id, payment_id, amount_int, status, risk_score, risk_signals, reviewed_by, reason, ledger_entry_id, created_at, updated_at`
+
+// PostgresRepository persists referral payouts in PostgreSQL.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, payout Payout) error {
+	signals, err := encodeSignals(payout.RiskSignals)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO referral_payouts (` + payoutColumns + `)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (id) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query,
+		payout.ID,
+		payout.InviterUserID,
+		payout.InviteeUserID,
+		payout.PaymentID,
+		payout.AmountINT,
+		payout.Status,
+		payout.RiskScore,
+		signals,
+		payout.ReviewedBy,
+		payout.Reason,
+		payout.LedgerEntryID,
+		payout.CreatedAt,
+		payout.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("insert referral payout: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, id string) (Payout, error) {
+	payout, err := scanPayout(r.db.QueryRowContext(ctx, `SELECT `+payoutColumns+` FROM referral_payouts WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Payout{}, ErrNotFound
+		}
+		return Payout{}, fmt.Errorf("select referral payout: %w", err)
+	}
+	return payout, nil
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, payout Payout, fromStatus string) error {
+	const query = `
+		UPDATE referral_payouts
+		SET status = $2,
+		    reviewed_by = $3,
+		    reason = $4,
+		    ledger_entry_id = $5,
+		    updated_at = $6
+		WHERE id = $1 AND status = $7
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		payout.ID,
+		payout.Status,
+		payout.ReviewedBy,
+		payout.Reason,
+		payout.LedgerEntryID,
+		payout.UpdatedAt,
+		fromStatus,
+	)
+	if err != nil {
+		return fmt.Errorf("update referral payout: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.Get(ctx, payout.ID); err != nil {
+			return err
+		}
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListByInviter(ctx context.Context, inviterID string) ([]Payout, error) {
+	return r.list(ctx, `SELECT `+payoutColumns+` FROM referral_payouts WHERE inviter_user_id = $1 ORDER BY created_at DESC, id DESC`, inviterID)
+}
+
+func (r *PostgresRepository) List(ctx context.Context, status string) ([]Payout, error) {
+	if status == "" {
+		return r.list(ctx, `SELECT `+payoutColumns+` FROM referral_payouts ORDER BY created_at, id`)
+	}
+	return r.list(ctx, `SELECT `+payoutColumns+` FROM referral_payouts WHERE status = $1 ORDER BY created_at, id`, status)
+}
+
+func (r *PostgresRepository) list(ctx context.Context, query string, args ...any) ([]Payout, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select referral payouts: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Payout, 0)
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan referral payout: %w", err)
+		}
+		result = append(result, payout)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate referral payouts: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresRepository) RecordFingerprint(ctx context.Context, fingerprint Fingerprint) error {
+	const query = `
+INSERT INTO login_fingerprints (user_id, ip_hash, device_hash, first_seen_at, last_seen_at)
+VALUES ($1, $2, $3, $4, $4)
+ON CONFLICT (user_id, ip_hash, device_hash) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at`
+	if _, err := r.db.ExecContext(ctx, query, fingerprint.UserID, fingerprint.IPHash, fingerprint.DeviceHash, fingerprint.SeenAt); err != nil {
+		return fmt.Errorf("upsert login fingerprint: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) SharedFingerprintUsers(ctx context.Context, userID string) ([]string, error) {
+	const query = `
+SELECT DISTINCT other.user_id
+FROM login_fingerprints mine
+JOIN login_fingerprints other
+  ON other.user_id <> mine.user_id
+ AND ((mine.ip_hash <> '' AND other.ip_hash = mine.ip_hash) OR (mine.device_hash <> '' AND other.device_hash = mine.device_hash))
+WHERE mine.user_id = $1
+ORDER BY other.user_id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select shared fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]string, 0)
+	for rows.Next() {
+		var other string
+		if err := rows.Scan(&other); err != nil {
+			return nil, fmt.Errorf("scan shared fingerprint: %w", err)
+		}
+		result = append(result, other)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate shared fingerprints: %w", err)
+	}
+	return result, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayout(row rowScanner) (Payout, error) {
+	var (
+		payout  Payout
+		signals []byte
+	)
+	if err := row.Scan(
+		&payout.ID,
+		&payout.InviterUserID,
+		&payout.InviteeUserID,
+		&payout.PaymentID,
+		&payout.AmountINT,
+		&payout.Status,
+		&payout.RiskScore,
+		&signals,
+		&payout.ReviewedBy,
+		&payout.Reason,
+		&payout.LedgerEntryID,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	); err != nil {
+		return Payout{}, err
+	}
+	if len(signals) > 0 {
+		if err := json.Unmarshal(signals, &payout.RiskSignals); err != nil {
+			return Payout{}, fmt.Errorf("decode risk signals: %w", err)
+		}
+	}
+	payout.CreatedAt = payout.CreatedAt.UTC()
+	payout.UpdatedAt = payout.UpdatedAt.UTC()
+	return payout, nil
+}
+
+func encodeSignals(signals []string) ([]byte, error) {
+	if signals == nil {
+		signals = []string{}
+	}
+	encoded, err := json.Marshal(signals)
+	if err != nil {
+		return nil, fmt.Errorf("encode risk signals: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,100 @@
+package referrals
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var payoutRowColumns = []string{"id", "inviter_user_id", "invitee_user_id", "payment_id", "amount_int", "status", "risk_score", "risk_signals", "reviewed_by", "reason", "ledger_entry_id", "created_at", "updated_at"}
+
+func TestPostgresRepository_CreateAndList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	payout := Payout{ID: "rp-1", InviterUserID: "tg_1", InviteeUserID: "tg_2", PaymentID: "pay-1", AmountINT: 25, Status: StatusHeld, RiskScore: 60, RiskSignals: []string{SignalSharedWithInviter}, CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO referral_payouts ("+payoutColumns+")")).
+		WithArgs("rp-1", "tg_1", "tg_2", "pay-1", int64(25), StatusHeld, 60, []byte(`["fingerprint_shared_with_inviter"]`), "", "", "", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.Create(context.Background(), payout); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + payoutColumns + " FROM referral_payouts WHERE status = $1 ORDER BY created_at, id")).
+		WithArgs(StatusHeld).
+		WillReturnRows(sqlmock.NewRows(payoutRowColumns).AddRow("rp-1", "tg_1", "tg_2", "pay-1", int64(25), StatusHeld, 60, []byte(`["fingerprint_shared_with_inviter"]`), "", "", "", now, now))
+	payouts, err := repo.List(context.Background(), StatusHeld)
+	if err != nil || len(payouts) != 1 || payouts[0].RiskScore != 60 || len(payouts[0].RiskSignals) != 1 || payouts[0].RiskSignals[0] != SignalSharedWithInviter {
+		t.Fatalf("unexpected payouts %+v (%v)", payouts, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	payout := Payout{ID: "rp-1", Status: StatusPaid, ReviewedBy: "admin-1", LedgerEntryID: "wle_1", UpdatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE referral_payouts")).
+		WithArgs("rp-1", StatusPaid, "admin-1", "", "wle_1", now, StatusHeld).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + payoutColumns + " FROM referral_payouts WHERE id = $1")).
+		WithArgs("rp-1").
+		WillReturnRows(sqlmock.NewRows(payoutRowColumns).AddRow("rp-1", "tg_1", "tg_2", "pay-1", int64(25), StatusRejected, 60, []byte(`[]`), "admin-2", "farm", "", now, now))
+	if err := repo.Update(context.Background(), payout, StatusHeld); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_Fingerprints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO login_fingerprints (user_id, ip_hash, device_hash, first_seen_at, last_seen_at)")).
+		WithArgs("tg_2", "ip-hash", "device-hash", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.RecordFingerprint(context.Background(), Fingerprint{UserID: "tg_2", IPHash: "ip-hash", DeviceHash: "device-hash", SeenAt: now}); err != nil {
+		t.Fatalf("RecordFingerprint() error = %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT other.user_id")).
+		WithArgs("tg_2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("tg_1"))
+	shared, err := repo.SharedFingerprintUsers(context.Background(), "tg_2")
+	if err != nil || len(shared) != 1 || shared[0] != "tg_1" {
+		t.Fatalf("unexpected shared users %v (%v)", shared, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
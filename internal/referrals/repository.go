@@ -0,0 +1,25 @@
+package referrals
+
+import "context"
+
+// Repository persists referral payouts and login fingerprints.
+type Repository interface {
+	// Create stores a payout; creating an existing ID is a no-op.
+	Create(ctx context.Context, payout Payout) error
+	Get(ctx context.Context, id string) (Payout, error)
+	// Update persists payout if it is still in fromStatus and returns
+	// ErrInvalidTransition when another writer changed it first.
+	Update(ctx context.Context, payout Payout, fromStatus string) error
+	// ListByInviter returns the payouts of inviterID, newest first.
+	ListByInviter(ctx context.Context, inviterID string) ([]Payout, error)
+	// List returns payouts in status, or all when status is empty, oldest
+	// first.
+	List(ctx context.Context, status string) ([]Payout, error)
+
+	// RecordFingerprint stores a login fingerprint, refreshing SeenAt when
+	// the user was already seen with it.
+	RecordFingerprint(ctx context.Context, fingerprint Fingerprint) error
+	// SharedFingerprintUsers returns the other users seen with an IP or
+	// device hash of userID.
+	SharedFingerprintUsers(ctx context.Context, userID string) ([]string, error)
+}
@@ -0,0 +1,184 @@
+package referrals
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/users"
+)
+
+// Risk signals raised against a referral, with the score each adds.
+const (
+	SignalSharedWithInviter = "fingerprint_shared_with_inviter"
+	SignalSharedWithInvitee = "fingerprint_shared_with_invitee"
+	SignalSignupBurst       = "signup_burst"
+	SignalIdleInvitees      = "idle_invitees"
+)
+
+var signalScores = map[string]int{
+	SignalSharedWithInviter: 60,
+	SignalSharedWithInvitee: 40,
+	SignalSignupBurst:       30,
+	SignalIdleInvitees:      30,
+}
+
+// An inviter looks like a farm of idle accounts once idleMinInvitees other
+// invitees exist and at least idleShare of them never topped up.
+const (
+	idleMinInvitees = 5
+	idleShare       = 0.8
+)
+
+// TopupHistory reports which users completed a Stars top-up.
+type TopupHistory interface {
+	ToppedUp(ctx context.Context, userIDs []string) (map[string]bool, error)
+}
+
+// RiskPolicy tunes when referral bonuses are held for review.
+type RiskPolicy struct {
+	// HoldScore is the score at which a bonus is held; <= 0 pays everything.
+	HoldScore int
+	// BurstSize invitees of one inviter joining within BurstWindow of each
+	// other count as a signup burst; zero values disable the signal.
+	BurstWindow time.Duration
+	BurstSize   int
+}
+
+// DefaultRiskPolicy holds a bonus on a fingerprint shared with the inviter
+// or on any two weaker signals.
+func DefaultRiskPolicy() RiskPolicy {
+	return RiskPolicy{HoldScore: 50, BurstWindow: time.Hour, BurstSize: 5}
+}
+
+// Assessment is the abuse score of one referral.
+type Assessment struct {
+	Score   int
+	Signals []string
+}
+
+// Assess scores the referral of invitee, who must have an inviter.
+func (s *Service) Assess(ctx context.Context, invitee users.Profile) (Assessment, error) {
+	invitees, err := s.users.ListInvitees(ctx, invitee.InviterUserID)
+	if err != nil {
+		return Assessment{}, fmt.Errorf("list invitees: %w", err)
+	}
+	shared, err := s.repo.SharedFingerprintUsers(ctx, invitee.ID)
+	if err != nil {
+		return Assessment{}, fmt.Errorf("load shared fingerprints: %w", err)
+	}
+
+	var assessment Assessment
+	raise := func(signal string) {
+		assessment.Signals = append(assessment.Signals, signal)
+		assessment.Score += signalScores[signal]
+	}
+
+	siblings := make(map[string]bool, len(invitees))
+	for _, other := range invitees {
+		if other.ID != invitee.ID {
+			siblings[other.ID] = true
+		}
+	}
+	sharedWithInviter, sharedWithSibling := false, false
+	for _, userID := range shared {
+		switch {
+		case userID == invitee.InviterUserID:
+			sharedWithInviter = true
+		case siblings[userID]:
+			sharedWithSibling = true
+		}
+	}
+	if sharedWithInviter {
+		raise(SignalSharedWithInviter)
+	}
+	if sharedWithSibling {
+		raise(SignalSharedWithInvitee)
+	}
+
+	if s.policy.BurstWindow > 0 && s.policy.BurstSize > 1 {
+		joined := 0
+		for _, other := range invitees {
+			gap := other.CreatedAt.Sub(invitee.CreatedAt)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap <= s.policy.BurstWindow {
+				joined++
+			}
+		}
+		if joined >= s.policy.BurstSize {
+			raise(SignalSignupBurst)
+		}
+	}
+
+	if s.topups != nil && len(siblings) >= idleMinInvitees {
+		ids := make([]string, 0, len(siblings))
+		for userID := range siblings {
+			ids = append(ids, userID)
+		}
+		toppedUp, err := s.topups.ToppedUp(ctx, ids)
+		if err != nil {
+			return Assessment{}, fmt.Errorf("load invitee top-ups: %w", err)
+		}
+		idle := 0
+		for _, userID := range ids {
+			if !toppedUp[userID] {
+				idle++
+			}
+		}
+		if float64(idle) >= idleShare*float64(len(siblings)) {
+			raise(SignalIdleInvitees)
+		}
+	}
+	return assessment, nil
+}
+
+func (s *Service) holds(assessment Assessment) bool {
+	return s.policy.HoldScore > 0 && assessment.Score >= s.policy.HoldScore
+}
+
+// RecordLogin stores hashed fingerprints of a login. IPv4 addresses are
+// reduced to their /24 and IPv6 to their /64 so neighbouring addresses
+// match.
+func (s *Service) RecordLogin(ctx context.Context, userID, ip, device string) error {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return ErrUserIDRequired
+	}
+	fingerprint := Fingerprint{
+		UserID:     userID,
+		IPHash:     hashFingerprint(ipRange(ip)),
+		DeviceHash: hashFingerprint(strings.TrimSpace(device)),
+		SeenAt:     s.nowFn(),
+	}
+	if fingerprint.IPHash == "" && fingerprint.DeviceHash == "" {
+		return nil
+	}
+	return s.repo.RecordFingerprint(ctx, fingerprint)
+}
+
+func ipRange(raw string) string {
+	raw = strings.TrimSpace(raw)
+	ip := net.ParseIP(raw)
+	switch {
+	case ip == nil:
+		return raw
+	case ip.To4() != nil:
+		return ip.Mask(net.CIDRMask(24, 32)).String()
+	default:
+		return ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+}
+
+func hashFingerprint(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("funpot:fingerprint:" + value))
+	return hex.EncodeToString(sum[:])
+}
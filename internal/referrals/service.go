@@ -0,0 +1,312 @@
+package referrals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/users"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+type Service struct {
+	repo     Repository
+	users    *users.Service
+	ledger   *wallet.Service
+	percent  float64
+	linkBase string
+	policy   RiskPolicy
+	topups   TopupHistory
+	logger   *zap.Logger
+	nowFn    func() time.Time
+}
+
+// NewService pays inviters percent of their invitees' Stars top-ups.
+func NewService(repo Repository, userService *users.Service, ledger *wallet.Service, percent float64) *Service {
+	return &Service{
+		repo:    repo,
+		users:   userService,
+		ledger:  ledger,
+		percent: percent,
+		policy:  DefaultRiskPolicy(),
+		logger:  zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithLinkBase sets the Mini App link that "ref_<code>" is appended to when
+// building invite URLs, e.g. https://t.me/funpot_bot/app?startapp=.
+func (s *Service) WithLinkBase(base string) {
+	s.linkBase = strings.TrimSpace(base)
+}
+
+// WithRiskPolicy replaces DefaultRiskPolicy; held bonuses are logged.
+func (s *Service) WithRiskPolicy(policy RiskPolicy, logger *zap.Logger) {
+	s.policy = policy
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// WithTopupHistory enables the idle-invitees signal, which counts invitees
+// without a paid top-up.
+func (s *Service) WithTopupHistory(history TopupHistory) {
+	s.topups = history
+}
+
+// ObserveTopup owes the inviter of userID a referral_bonus for a paid
+// top-up. The bonus is credited right away unless the referral scores as
+// risky, in which case it is held for an admin. The payout ID and ledger
+// key derive from paymentID, so replays of the same payment pay once.
+func (s *Service) ObserveTopup(ctx context.Context, userID, paymentID string, amountINT int64) error {
+	invitee, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("load invitee: %w", err)
+	}
+	if invitee.InviterUserID == "" {
+		return nil
+	}
+	bonus := int64(math.Floor(float64(amountINT) * s.percent / 100))
+	if bonus <= 0 {
+		return nil
+	}
+
+	id := payoutID(paymentID)
+	payout, err := s.repo.Get(ctx, id)
+	switch {
+	case err == nil:
+		if payout.Status != StatusPending {
+			return nil
+		}
+		// An earlier delivery recorded the payout but did not credit it.
+		return s.settle(ctx, payout)
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+
+	assessment, err := s.Assess(ctx, invitee)
+	if err != nil {
+		return fmt.Errorf("assess referral: %w", err)
+	}
+	now := s.nowFn()
+	payout = Payout{
+		ID:            id,
+		InviterUserID: invitee.InviterUserID,
+		InviteeUserID: invitee.ID,
+		PaymentID:     paymentID,
+		AmountINT:     bonus,
+		Status:        StatusPending,
+		RiskScore:     assessment.Score,
+		RiskSignals:   assessment.Signals,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if s.holds(assessment) {
+		payout.Status = StatusHeld
+		s.logger.Info("referral bonus held for review",
+			zap.String("payout_id", id),
+			zap.String("inviter_id", payout.InviterUserID),
+			zap.String("invitee_id", payout.InviteeUserID),
+			zap.Int("risk_score", assessment.Score),
+			zap.Strings("risk_signals", assessment.Signals),
+		)
+		return s.repo.Create(ctx, payout)
+	}
+	if err := s.repo.Create(ctx, payout); err != nil {
+		return err
+	}
+	return s.settle(ctx, payout)
+}
+
+// topupReversedReason is recorded on payouts whose top-up was refunded.
+const topupReversedReason = "top-up refunded"
+
+// ObserveTopupReversal takes back the referral bonus of a refunded or
+// reversed top-up: an unpaid payout is rejected and a paid one is debited
+// from the inviter. When the inviter already spent the bonus the payout stays
+// paid with a reason for an admin instead of driving the balance negative.
+// Replays are no-ops.
+func (s *Service) ObserveTopupReversal(ctx context.Context, paymentID string) error {
+	payout, err := s.repo.Get(ctx, payoutID(paymentID))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	from := payout.Status
+	switch payout.Status {
+	case StatusPending, StatusHeld:
+		payout.Status = StatusRejected
+		payout.Reason = topupReversedReason
+	case StatusPaid:
+		_, err := s.ledger.Debit(ctx, payout.InviterUserID, payout.AmountINT, wallet.ReasonReferralBonus, payout.PaymentID, ReversalKey(payout.PaymentID))
+		switch {
+		case errors.Is(err, wallet.ErrInsufficientFunds):
+			s.logger.Warn("referral bonus of a refunded top-up needs manual review",
+				zap.String("payout_id", payout.ID),
+				zap.String("inviter_id", payout.InviterUserID),
+				zap.Int64("amount_int", payout.AmountINT),
+			)
+			payout.Reason = topupReversedReason + "; inviter balance too low to reverse bonus"
+		case err != nil:
+			return fmt.Errorf("reverse referral bonus: %w", err)
+		default:
+			payout.Status = StatusReversed
+			payout.Reason = topupReversedReason
+		}
+	default:
+		return nil
+	}
+	payout.UpdatedAt = s.nowFn()
+	return s.repo.Update(ctx, payout, from)
+}
+
+func payoutID(paymentID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(BonusKey(paymentID))).String()
+}
+
+func (s *Service) settle(ctx context.Context, payout Payout) error {
+	_, err := s.pay(ctx, payout, "")
+	if errors.Is(err, ErrInvalidTransition) {
+		// A concurrent delivery paid it first.
+		return nil
+	}
+	return err
+}
+
+// pay credits the bonus and marks the payout paid.
+func (s *Service) pay(ctx context.Context, payout Payout, adminID string) (Payout, error) {
+	if !canTransition(payout.Status, StatusPaid) {
+		return Payout{}, ErrInvalidTransition
+	}
+	result, err := s.ledger.Credit(ctx, payout.InviterUserID, payout.AmountINT, wallet.ReasonReferralBonus, payout.PaymentID, BonusKey(payout.PaymentID))
+	if err != nil {
+		return Payout{}, fmt.Errorf("credit referral bonus: %w", err)
+	}
+	from := payout.Status
+	payout.Status = StatusPaid
+	payout.LedgerEntryID = result.Entry.ID
+	if adminID != "" {
+		payout.ReviewedBy = adminID
+	}
+	payout.UpdatedAt = s.nowFn()
+	if err := s.repo.Update(ctx, payout, from); err != nil {
+		return Payout{}, err
+	}
+	return payout, nil
+}
+
+// List returns payouts in status, or all of them when status is empty.
+func (s *Service) List(ctx context.Context, status string) ([]Payout, error) {
+	return s.repo.List(ctx, status)
+}
+
+// Approve credits a held payout.
+func (s *Service) Approve(ctx context.Context, id, adminID string) (Payout, error) {
+	payout, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Payout{}, err
+	}
+	if payout.Status != StatusHeld {
+		return Payout{}, ErrInvalidTransition
+	}
+	return s.pay(ctx, payout, adminID)
+}
+
+// Reject declines a held payout; nothing is credited.
+func (s *Service) Reject(ctx context.Context, id, adminID, reason string) (Payout, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return Payout{}, ErrReasonRequired
+	}
+	payout, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Payout{}, err
+	}
+	if payout.Status != StatusHeld {
+		return Payout{}, ErrInvalidTransition
+	}
+	from := payout.Status
+	payout.Status = StatusRejected
+	payout.ReviewedBy = adminID
+	payout.Reason = reason
+	payout.UpdatedAt = s.nowFn()
+	if err := s.repo.Update(ctx, payout, from); err != nil {
+		return Payout{}, err
+	}
+	return payout, nil
+}
+
+// Summary reports userID's referral link, invitees and paid earnings.
+func (s *Service) Summary(ctx context.Context, userID string) (Summary, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return Summary{}, ErrUserIDRequired
+	}
+	profile, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return Summary{}, err
+	}
+	invitees, err := s.users.ListInvitees(ctx, userID)
+	if err != nil {
+		return Summary{}, fmt.Errorf("list invitees: %w", err)
+	}
+	payouts, err := s.repo.ListByInviter(ctx, userID)
+	if err != nil {
+		return Summary{}, fmt.Errorf("list referral payouts: %w", err)
+	}
+
+	summary := Summary{
+		ReferralCode: profile.ReferralCode,
+		URL:          s.inviteURL(profile.ReferralCode),
+		Tier:         TierSingle,
+		Percent:      s.percent,
+		InvitedCount: len(invitees),
+		Invited:      make([]Invitee, 0, len(invitees)),
+	}
+	earnings := make(map[string]int64, len(invitees))
+	for _, payout := range payouts {
+		if payout.Status != StatusPaid {
+			continue
+		}
+		earnings[payout.InviteeUserID] += payout.AmountINT
+		summary.EarningsINT += payout.AmountINT
+	}
+	for _, invitee := range invitees {
+		summary.Invited = append(summary.Invited, Invitee{
+			UserID:      invitee.ID,
+			JoinedAt:    invitee.CreatedAt,
+			EarningsINT: earnings[invitee.ID],
+		})
+	}
+	return summary, nil
+}
+
+// Payouts returns the referral bonuses owed to userID, newest first.
+func (s *Service) Payouts(ctx context.Context, userID string) ([]Payout, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, ErrUserIDRequired
+	}
+	return s.repo.ListByInviter(ctx, userID)
+}
+
+func (s *Service) inviteURL(code string) string {
+	if s.linkBase == "" || code == "" {
+		return ""
+	}
+	return s.linkBase + "ref_" + code
+}
@@ -0,0 +1,245 @@
+package referrals
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/funpot/funpot-go-core/internal/users"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func newFixture(t *testing.T) (*Service, *wallet.Service, users.Profile, users.Profile) {
+	t.Helper()
+	ctx := context.Background()
+	userService := users.NewService(users.NewInMemoryRepository())
+	inviter, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 1})
+	if err != nil {
+		t.Fatalf("sync inviter: %v", err)
+	}
+	invitee, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 2, StartParam: "ref_" + inviter.ReferralCode})
+	if err != nil {
+		t.Fatalf("sync invitee: %v", err)
+	}
+	ledger := wallet.NewService(wallet.NewInMemoryRepository())
+	svc := NewService(NewInMemoryRepository(), userService, ledger, 10)
+	svc.WithLinkBase("https://t.me/funpot_bot/app?startapp=")
+	return svc, ledger, inviter, invitee
+}
+
+func TestObserveTopupPaysInviterOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, inviter, invitee := newFixture(t)
+
+	for i := 0; i < 2; i++ {
+		if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 250); err != nil {
+			t.Fatalf("ObserveTopup() error = %v", err)
+		}
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 25 {
+		t.Fatalf("expected inviter balance 25, got %d (%v)", balance, err)
+	}
+
+	payouts, err := svc.Payouts(ctx, inviter.ID)
+	if err != nil || len(payouts) != 1 {
+		t.Fatalf("expected one payout, got %+v (%v)", payouts, err)
+	}
+	if payouts[0].AmountINT != 25 || payouts[0].InviteeUserID != invitee.ID || payouts[0].LedgerEntryID == "" {
+		t.Fatalf("unexpected payout %+v", payouts[0])
+	}
+}
+
+func TestObserveTopupReversalTakesBonusBack(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, inviter, invitee := newFixture(t)
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 250); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.ObserveTopupReversal(ctx, "pay-1"); err != nil {
+			t.Fatalf("ObserveTopupReversal() error = %v", err)
+		}
+	}
+	if balance, _ := ledger.Balance(ctx, inviter.ID); balance != 0 {
+		t.Fatalf("expected bonus debited back once, got balance %d", balance)
+	}
+	payouts, _ := svc.Payouts(ctx, inviter.ID)
+	if len(payouts) != 1 || payouts[0].Status != StatusReversed {
+		t.Fatalf("expected a reversed payout, got %+v", payouts)
+	}
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 250); err != nil {
+		t.Fatalf("replayed ObserveTopup() error = %v", err)
+	}
+	if balance, _ := ledger.Balance(ctx, inviter.ID); balance != 0 {
+		t.Fatalf("expected a replayed top-up not to pay again, got balance %d", balance)
+	}
+
+	// A held bonus of a refunded top-up is rejected without touching the ledger.
+	if err := svc.repo.Create(ctx, Payout{ID: payoutID("pay-2"), InviterUserID: inviter.ID, InviteeUserID: invitee.ID, PaymentID: "pay-2", AmountINT: 10, Status: StatusHeld}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := svc.ObserveTopupReversal(ctx, "pay-2"); err != nil {
+		t.Fatalf("ObserveTopupReversal() error = %v", err)
+	}
+	held, _ := svc.List(ctx, StatusRejected)
+	if len(held) != 1 || held[0].PaymentID != "pay-2" || held[0].Reason == "" {
+		t.Fatalf("expected the held payout rejected, got %+v", held)
+	}
+	if err := svc.ObserveTopupReversal(ctx, "pay-unknown"); err != nil {
+		t.Fatalf("expected unknown payments to be ignored, got %v", err)
+	}
+}
+
+func TestObserveTopupSkipsUninvitedUsers(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, inviter, _ := newFixture(t)
+
+	if err := svc.ObserveTopup(ctx, inviter.ID, "pay-2", 250); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	if err := svc.ObserveTopup(ctx, "tg_missing", "pay-3", 250); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 0 {
+		t.Fatalf("expected no bonus, got %d (%v)", balance, err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	ctx := context.Background()
+	svc, _, inviter, invitee := newFixture(t)
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 100); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-2", 50); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+
+	summary, err := svc.Summary(ctx, inviter.ID)
+	if err != nil {
+		t.Fatalf("Summary() error = %v", err)
+	}
+	if summary.ReferralCode != inviter.ReferralCode || summary.URL != "https://t.me/funpot_bot/app?startapp=ref_"+inviter.ReferralCode {
+		t.Fatalf("unexpected link %+v", summary)
+	}
+	if summary.Tier != TierSingle || summary.Percent != 10 || summary.InvitedCount != 1 || summary.EarningsINT != 15 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if len(summary.Invited) != 1 || summary.Invited[0].UserID != invitee.ID || summary.Invited[0].EarningsINT != 15 {
+		t.Fatalf("unexpected invitees %+v", summary.Invited)
+	}
+}
+
+func TestObserveTopupHoldsSharedFingerprint(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger, inviter, invitee := newFixture(t)
+	if err := svc.RecordLogin(ctx, inviter.ID, "203.0.113.7", "device-a"); err != nil {
+		t.Fatalf("RecordLogin() error = %v", err)
+	}
+	if err := svc.RecordLogin(ctx, invitee.ID, "203.0.113.99", "device-b"); err != nil {
+		t.Fatalf("RecordLogin() error = %v", err)
+	}
+
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 250); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 0 {
+		t.Fatalf("expected the bonus held, got balance %d (%v)", balance, err)
+	}
+	held, err := svc.List(ctx, StatusHeld)
+	if err != nil || len(held) != 1 || held[0].RiskScore < svc.policy.HoldScore || held[0].RiskSignals[0] != SignalSharedWithInviter {
+		t.Fatalf("expected one held payout, got %+v (%v)", held, err)
+	}
+
+	if _, err := svc.Reject(ctx, held[0].ID, "admin-1", ""); !errors.Is(err, ErrReasonRequired) {
+		t.Fatalf("expected ErrReasonRequired, got %v", err)
+	}
+	paid, err := svc.Approve(ctx, held[0].ID, "admin-1")
+	if err != nil || paid.Status != StatusPaid || paid.ReviewedBy != "admin-1" || paid.LedgerEntryID == "" {
+		t.Fatalf("unexpected approval %+v (%v)", paid, err)
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 25 {
+		t.Fatalf("expected inviter balance 25, got %d (%v)", balance, err)
+	}
+	if _, err := svc.Reject(ctx, held[0].ID, "admin-1", "farm"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	// A replay of the payment must not pay again.
+	if err := svc.ObserveTopup(ctx, invitee.ID, "pay-1", 250); err != nil {
+		t.Fatalf("ObserveTopup() replay error = %v", err)
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 25 {
+		t.Fatalf("expected inviter balance 25 after replay, got %d (%v)", balance, err)
+	}
+}
+
+// fakeTopups reports the users mapped to true as topped up.
+type fakeTopups map[string]bool
+
+func (f fakeTopups) ToppedUp(_ context.Context, userIDs []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		result[userID] = f[userID]
+	}
+	return result, nil
+}
+
+func TestAssessBurstOfIdleInvitees(t *testing.T) {
+	ctx := context.Background()
+	userService := users.NewService(users.NewInMemoryRepository())
+	inviter, err := userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: 1})
+	if err != nil {
+		t.Fatalf("sync inviter: %v", err)
+	}
+	var last users.Profile
+	for id := int64(2); id <= 8; id++ {
+		if last, err = userService.SyncTelegramProfile(ctx, users.TelegramProfile{ID: id, StartParam: inviter.ReferralCode}); err != nil {
+			t.Fatalf("sync invitee %d: %v", id, err)
+		}
+	}
+	ledger := wallet.NewService(wallet.NewInMemoryRepository())
+	svc := NewService(NewInMemoryRepository(), userService, ledger, 10)
+	invitees, err := userService.ListInvitees(ctx, inviter.ID)
+	if err != nil {
+		t.Fatalf("ListInvitees() error = %v", err)
+	}
+	var siblings []string
+	for _, invitee := range invitees {
+		if invitee.ID != last.ID {
+			siblings = append(siblings, invitee.ID)
+		}
+	}
+	topups := fakeTopups{siblings[0]: true}
+	svc.WithTopupHistory(topups)
+
+	assessment, err := svc.Assess(ctx, last)
+	if err != nil {
+		t.Fatalf("Assess() error = %v", err)
+	}
+	if assessment.Score != 60 || len(assessment.Signals) != 2 || assessment.Signals[0] != SignalSignupBurst || assessment.Signals[1] != SignalIdleInvitees {
+		t.Fatalf("unexpected assessment %+v", assessment)
+	}
+	// Invitees who paid for their top-ups are not idle.
+	topups[siblings[1]] = true
+	if assessment, err := svc.Assess(ctx, last); err != nil || len(assessment.Signals) != 1 || assessment.Signals[0] != SignalSignupBurst {
+		t.Fatalf("expected only the burst signal, got %+v (%v)", assessment, err)
+	}
+	delete(topups, siblings[1])
+
+	if err := svc.ObserveTopup(ctx, last.ID, "pay-9", 100); err != nil {
+		t.Fatalf("ObserveTopup() error = %v", err)
+	}
+	held, err := svc.List(ctx, StatusHeld)
+	if err != nil || len(held) != 1 {
+		t.Fatalf("expected one held payout, got %+v (%v)", held, err)
+	}
+	rejected, err := svc.Reject(ctx, held[0].ID, "admin-1", "signup farm")
+	if err != nil || rejected.Status != StatusRejected || rejected.Reason != "signup farm" {
+		t.Fatalf("unexpected rejection %+v (%v)", rejected, err)
+	}
+	if balance, err := ledger.Balance(ctx, inviter.ID); err != nil || balance != 0 {
+		t.Fatalf("expected nothing credited, got %d (%v)", balance, err)
+	}
+}
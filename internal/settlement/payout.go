@@ -0,0 +1,103 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/funpot/funpot-go-core/internal/votes"
+)
+
+// Payout models.
+const (
+	// ModelParimutuel splits the pot of every vote cost, minus the house
+	// rake, among winners in proportion to their stake.
+	ModelParimutuel = "parimutuel"
+	// ModelFixedOdds pays each winner their stake times FixedOdds.
+	ModelFixedOdds = "fixed_odds"
+)
+
+var ErrUnknownModel = errors.New("payout model must be parimutuel or fixed_odds")
+
+// PayoutConfig selects how winners are paid.
+type PayoutConfig struct {
+	Model string
+	// RakeBPS is the house share of a parimutuel pot in basis points.
+	RakeBPS int64
+	// FixedOdds is the multiplier applied to winning stakes.
+	FixedOdds float64
+}
+
+// Validate reports configuration errors.
+func (c PayoutConfig) Validate() error {
+	switch c.Model {
+	case ModelParimutuel:
+		if c.RakeBPS < 0 || c.RakeBPS > 10000 {
+			return fmt.Errorf("rake must be between 0 and 10000 bps, got %d", c.RakeBPS)
+		}
+	case ModelFixedOdds:
+		if c.FixedOdds < 1 {
+			return fmt.Errorf("fixed odds must be >= 1, got %v", c.FixedOdds)
+		}
+	default:
+		return ErrUnknownModel
+	}
+	return nil
+}
+
+// Payout is the reward owed to one winning voter.
+type Payout struct {
+	UserID string
+	VoteID string
+	Stake  int64
+	Amount int64
+}
+
+// ComputePayouts returns the payouts for the votes of an event resolved to
+// winningOption. Amounts are rounded down; parimutuel rounding dust stays
+// with the house. Free votes and zero amounts are skipped.
+func ComputePayouts(cfg PayoutConfig, winningOption string, items []votes.Vote) ([]Payout, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var pot int64
+	for _, vote := range items {
+		pot += vote.Cost
+	}
+	winningStake := winningStake(winningOption, items)
+	if winningStake == 0 {
+		return nil, nil
+	}
+	distributable := pot - pot*cfg.RakeBPS/10000
+
+	payouts := make([]Payout, 0)
+	for _, vote := range items {
+		if vote.OptionID != winningOption || vote.Cost <= 0 {
+			continue
+		}
+		var amount int64
+		switch cfg.Model {
+		case ModelParimutuel:
+			amount = distributable * vote.Cost / winningStake
+		case ModelFixedOdds:
+			amount = int64(math.Floor(float64(vote.Cost) * cfg.FixedOdds))
+		}
+		if amount <= 0 {
+			continue
+		}
+		payouts = append(payouts, Payout{UserID: vote.UserID, VoteID: vote.ID, Stake: vote.Cost, Amount: amount})
+	}
+	return payouts, nil
+}
+
+// winningStake sums the vote costs placed on winningOption.
+func winningStake(winningOption string, items []votes.Vote) int64 {
+	var stake int64
+	for _, vote := range items {
+		if vote.OptionID == winningOption {
+			stake += vote.Cost
+		}
+	}
+	return stake
+}
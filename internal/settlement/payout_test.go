@@ -0,0 +1,49 @@
+package settlement
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/funpot/funpot-go-core/internal/votes"
+)
+
+func TestComputePayouts(t *testing.T) {
+	items := []votes.Vote{
+		{ID: "v-1", UserID: "u-1", OptionID: "yes", Cost: 10},
+		{ID: "v-2", UserID: "u-2", OptionID: "yes", Cost: 30},
+		{ID: "v-3", UserID: "u-3", OptionID: "no", Cost: 60},
+		{ID: "v-4", UserID: "u-4", OptionID: "yes", Cost: 0},
+	}
+
+	tests := []struct {
+		name   string
+		cfg    PayoutConfig
+		option string
+		want   map[string]int64
+	}{
+		{name: "parimutuel with rake", cfg: PayoutConfig{Model: ModelParimutuel, RakeBPS: 500}, option: "yes", want: map[string]int64{"u-1": 23, "u-2": 71}},
+		{name: "parimutuel without rake", cfg: PayoutConfig{Model: ModelParimutuel}, option: "no", want: map[string]int64{"u-3": 100}},
+		{name: "fixed odds", cfg: PayoutConfig{Model: ModelFixedOdds, FixedOdds: 1.5}, option: "yes", want: map[string]int64{"u-1": 15, "u-2": 45}},
+		{name: "no winning stake", cfg: PayoutConfig{Model: ModelParimutuel, RakeBPS: 500}, option: "maybe", want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payouts, err := ComputePayouts(tt.cfg, tt.option, items)
+			if err != nil {
+				t.Fatalf("ComputePayouts() error = %v", err)
+			}
+			if len(payouts) != len(tt.want) {
+				t.Fatalf("expected %d payouts, got %+v", len(tt.want), payouts)
+			}
+			for _, payout := range payouts {
+				if payout.Amount != tt.want[payout.UserID] {
+					t.Fatalf("payout for %s = %d, want %d", payout.UserID, payout.Amount, tt.want[payout.UserID])
+				}
+			}
+		})
+	}
+
+	if _, err := ComputePayouts(PayoutConfig{Model: "lottery"}, "yes", items); !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("expected ErrUnknownModel, got %v", err)
+	}
+}
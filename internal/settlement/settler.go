@@ -0,0 +1,206 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+type SettlerConfig struct {
+	Interval time.Duration
+	LeaseTTL time.Duration
+	Payouts  PayoutConfig
+}
+
+// Settler pays winners of resolved events and refunds the votes of cancelled
+// ones. Every posting has a key derived from the event and user or vote, so a
+// settlement interrupted half-way is finished on the next pass without paying
+// anyone twice; the event is marked settled only after all postings succeed.
+type Settler struct {
+	events   *events.Service
+	votes    *votes.Service
+	ledger   *wallet.Service
+	lease    events.Lease
+	logger   *zap.Logger
+	interval time.Duration
+	leaseTTL time.Duration
+	payouts  PayoutConfig
+}
+
+func NewSettler(eventsService *events.Service, votesService *votes.Service, ledger *wallet.Service, lease events.Lease, logger *zap.Logger, cfg SettlerConfig) *Settler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 2 * time.Second
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 30 * time.Second
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Settler{
+		events:   eventsService,
+		votes:    votesService,
+		ledger:   ledger,
+		lease:    lease,
+		logger:   logger,
+		interval: cfg.Interval,
+		leaseTTL: cfg.LeaseTTL,
+		payouts:  cfg.Payouts,
+	}
+}
+
+// Run settles resolved events every interval until ctx is cancelled.
+func (s *Settler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce settles or refunds every unsettled event this replica wins the lease for and
+// returns how many it settled.
+func (s *Settler) RunOnce(ctx context.Context) int {
+	pending, err := s.events.ListUnsettled(ctx)
+	if err != nil {
+		s.logger.Warn("failed to list unsettled events", zap.Error(err))
+		return 0
+	}
+	settled := 0
+	for _, event := range pending {
+		key := "events:settle:" + event.ID
+		if !s.lease.TryLock(key, s.leaseTTL) {
+			continue
+		}
+		err := s.Settle(ctx, event)
+		s.lease.Unlock(key)
+		if err != nil {
+			s.logger.Warn("failed to settle event", zap.String("event_id", event.ID), zap.Error(err))
+			continue
+		}
+		settled++
+	}
+	return settled
+}
+
+// Settle posts the payouts of a resolved event, or the refunds of a
+// cancelled one, and marks it settled.
+func (s *Settler) Settle(ctx context.Context, event events.LiveEvent) error {
+	if !event.NeedsSettlement() {
+		return nil
+	}
+	items, err := s.votes.ListByEvent(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("list votes: %w", err)
+	}
+	if event.State == events.StateCancelled {
+		err = s.refundVotes(ctx, items)
+	} else {
+		err = s.payWinners(ctx, event, items)
+	}
+	if errors.Is(err, wallet.ErrIdempotencyConflict) {
+		// A payout or refund key already holds a different posting; retrying
+		// cannot succeed, so park the event for an admin instead.
+		s.logger.Error("settlement needs admin review", zap.String("event_id", event.ID), zap.Error(err))
+		if flagErr := s.events.FlagSettlement(ctx, event.ID, err.Error()); flagErr != nil {
+			return errors.Join(err, fmt.Errorf("flag settlement: %w", flagErr))
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.events.MarkSettled(ctx, event.ID); err != nil {
+		return fmt.Errorf("mark settled: %w", err)
+	}
+	s.logger.Info("event settled", zap.String("event_id", event.ID), zap.String("state", event.State), zap.Int("votes", len(items)))
+	return nil
+}
+
+// Refund cancels an event that has no final result, recording reason, and
+// returns every vote cost. Refunding an already refunded event is a no-op.
+func (s *Settler) Refund(ctx context.Context, eventID, reason string) (events.LiveEvent, error) {
+	event, err := s.events.Get(ctx, eventID)
+	if err != nil {
+		return events.LiveEvent{}, err
+	}
+	if event.State != events.StateCancelled {
+		if event, err = s.events.Cancel(ctx, event.ID, reason); err != nil {
+			return events.LiveEvent{}, err
+		}
+	}
+	if err := s.Settle(ctx, event); err != nil {
+		return events.LiveEvent{}, err
+	}
+	return s.events.Get(ctx, event.ID)
+}
+
+// Retry clears an event's settlement flag once an admin resolved the
+// conflicting postings and settles it again.
+func (s *Settler) Retry(ctx context.Context, eventID string) (events.LiveEvent, error) {
+	if err := s.events.FlagSettlement(ctx, eventID, ""); err != nil {
+		return events.LiveEvent{}, err
+	}
+	event, err := s.events.Get(ctx, eventID)
+	if err != nil {
+		return events.LiveEvent{}, err
+	}
+	if err := s.Settle(ctx, event); err != nil {
+		return events.LiveEvent{}, err
+	}
+	return s.events.Get(ctx, event.ID)
+}
+
+func (s *Settler) payWinners(ctx context.Context, event events.LiveEvent, items []votes.Vote) error {
+	if s.payouts.Model == ModelParimutuel && winningStake(event.Result.OptionID, items) == 0 {
+		// Nobody backed the winning option, so there is no one to split the
+		// pot with: hand every stake back rather than keep it.
+		s.logger.Info("no winning votes; refunding stakes", zap.String("event_id", event.ID))
+		return s.refundVotes(ctx, items)
+	}
+	payouts, err := ComputePayouts(s.payouts, event.Result.OptionID, items)
+	if err != nil {
+		return err
+	}
+	for _, payout := range payouts {
+		if _, err := s.ledger.Credit(ctx, payout.UserID, payout.Amount, wallet.ReasonReward, event.ID, PayoutKey(event.ID, payout.UserID)); err != nil {
+			return fmt.Errorf("credit payout to %s: %w", payout.UserID, err)
+		}
+	}
+	return nil
+}
+
+// refundVotes reverses each vote_cost debit with a vote_cost credit.
+func (s *Settler) refundVotes(ctx context.Context, items []votes.Vote) error {
+	for _, vote := range items {
+		if vote.Cost <= 0 {
+			continue
+		}
+		if _, err := s.ledger.Credit(ctx, vote.UserID, vote.Cost, wallet.ReasonVoteCost, vote.ID, RefundKey(vote.ID)); err != nil {
+			return fmt.Errorf("refund vote %s: %w", vote.ID, err)
+		}
+	}
+	return nil
+}
+
+// PayoutKey is the ledger idempotency key of a winner's reward for an event.
+func PayoutKey(eventID, userID string) string {
+	return "payout:" + eventID + ":" + userID
+}
+
+// RefundKey is the ledger idempotency key of a vote's refund.
+func RefundKey(voteID string) string {
+	return "refund:" + voteID
+}
@@ -0,0 +1,173 @@
+package settlement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/media"
+	"github.com/funpot/funpot-go-core/internal/realtime"
+	"github.com/funpot/funpot-go-core/internal/votes"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// newVotedEvent creates a paid event on which u-1 picked yes and u-2 and u-3
+// picked no, each paying 10 out of a balance of 10. Nobody picked draw.
+func newVotedEvent(t *testing.T) (*events.Service, *votes.Service, *wallet.Service, events.LiveEvent) {
+	t.Helper()
+	ctx := context.Background()
+	eventsService := events.NewService(nil)
+	event, err := eventsService.Create(ctx, events.CreateRequest{
+		StreamerID:  "s-1",
+		Title:       "Will it happen?",
+		Options:     []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}, {ID: "draw", Label: "Draw"}},
+		ClosesAt:    time.Now().Add(time.Minute),
+		CostPerVote: 10,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	ledgerRepo := wallet.NewInMemoryRepository()
+	ledger := wallet.NewService(ledgerRepo)
+	votesRepo := votes.NewInMemoryRepository()
+	votesRepo.WithLedger(ledgerRepo)
+	votesService := votes.NewService(votesRepo, eventsService)
+	votesService.WithWallet(votes.NewLedgerWallet(ledger))
+	for _, pick := range []struct{ user, option string }{{"u-1", "yes"}, {"u-2", "no"}, {"u-3", "no"}} {
+		if _, err := ledger.Credit(ctx, pick.user, 10, wallet.ReasonStarsTopup, "", "topup:"+pick.user); err != nil {
+			t.Fatalf("Credit() error = %v", err)
+		}
+		if _, err := votesService.Cast(ctx, votes.CastRequest{UserID: pick.user, EventID: event.ID, OptionID: pick.option}); err != nil {
+			t.Fatalf("Cast() error = %v", err)
+		}
+	}
+	return eventsService, votesService, ledger, event
+}
+
+func assertBalances(t *testing.T, ledger *wallet.Service, want map[string]int64) {
+	t.Helper()
+	for user, expected := range want {
+		balance, err := ledger.Balance(context.Background(), user)
+		if err != nil {
+			t.Fatalf("Balance() error = %v", err)
+		}
+		if balance != expected {
+			t.Fatalf("balance of %s = %d, want %d", user, balance, expected)
+		}
+	}
+}
+
+func TestSettlerPaysWinnersOnce(t *testing.T) {
+	ctx := context.Background()
+	eventsService, votesService, ledger, event := newVotedEvent(t)
+	publisher := realtime.NewInMemoryPublisher()
+	ledger.WithPublisher(publisher, nil)
+
+	settler := NewSettler(eventsService, votesService, ledger, media.NewInMemoryLocker(), nil, SettlerConfig{
+		Payouts: PayoutConfig{Model: ModelParimutuel, RakeBPS: 1000},
+	})
+	if settled := settler.RunOnce(ctx); settled != 0 {
+		t.Fatalf("expected nothing to settle before a result, got %d", settled)
+	}
+	if _, err := eventsService.RecordResult(ctx, event.ID, events.Result{OptionID: "no", Outcome: "no"}); err != nil {
+		t.Fatalf("RecordResult() error = %v", err)
+	}
+	if settled := settler.RunOnce(ctx); settled != 1 {
+		t.Fatalf("expected one settled event, got %d", settled)
+	}
+	if settled := settler.RunOnce(ctx); settled != 0 {
+		t.Fatalf("expected settled event to be skipped, got %d", settled)
+	}
+
+	assertBalances(t, ledger, map[string]int64{"u-1": 0, "u-2": 13, "u-3": 13})
+	messages := publisher.Messages()
+	if len(messages) != 2 || messages[0].Message.Type != realtime.TypeBalanceUpdated {
+		t.Fatalf("expected BALANCE_UPDATED for both winners, got %+v", messages)
+	}
+	stored, err := eventsService.Get(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.SettledAt == nil {
+		t.Fatal("expected event to be marked settled")
+	}
+}
+
+func TestSettlerRefundsCancelledEvents(t *testing.T) {
+	ctx := context.Background()
+	eventsService, votesService, ledger, event := newVotedEvent(t)
+	settler := NewSettler(eventsService, votesService, ledger, media.NewInMemoryLocker(), nil, SettlerConfig{
+		Payouts: PayoutConfig{Model: ModelParimutuel},
+	})
+
+	refunded, err := settler.Refund(ctx, event.ID, "stream ended")
+	if err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if refunded.State != events.StateCancelled || refunded.CancelReason != "stream ended" || refunded.SettledAt == nil {
+		t.Fatalf("unexpected refunded event %+v", refunded)
+	}
+	if _, err := settler.Refund(ctx, event.ID, "again"); err != nil {
+		t.Fatalf("repeated Refund() error = %v", err)
+	}
+	assertBalances(t, ledger, map[string]int64{"u-1": 10, "u-2": 10, "u-3": 10})
+
+	// Events cancelled elsewhere, e.g. by the automator, are refunded by the sweep.
+	other, otherVotes, otherLedger, otherEvent := newVotedEvent(t)
+	if _, err := other.Cancel(ctx, otherEvent.ID, "match outcome unknown"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	sweeper := NewSettler(other, otherVotes, otherLedger, media.NewInMemoryLocker(), nil, SettlerConfig{
+		Payouts: PayoutConfig{Model: ModelParimutuel},
+	})
+	if settled := sweeper.RunOnce(ctx); settled != 1 {
+		t.Fatalf("expected cancelled event to be swept, got %d", settled)
+	}
+	assertBalances(t, otherLedger, map[string]int64{"u-1": 10, "u-2": 10, "u-3": 10})
+}
+
+func TestSettlerRefundsPotWithoutWinners(t *testing.T) {
+	ctx := context.Background()
+	eventsService, votesService, ledger, event := newVotedEvent(t)
+	settler := NewSettler(eventsService, votesService, ledger, media.NewInMemoryLocker(), nil, SettlerConfig{
+		Payouts: PayoutConfig{Model: ModelParimutuel, RakeBPS: 1000},
+	})
+	if _, err := eventsService.RecordResult(ctx, event.ID, events.Result{OptionID: "draw", Outcome: "draw"}); err != nil {
+		t.Fatalf("RecordResult() error = %v", err)
+	}
+	if settled := settler.RunOnce(ctx); settled != 1 {
+		t.Fatalf("expected one settled event, got %d", settled)
+	}
+	assertBalances(t, ledger, map[string]int64{"u-1": 10, "u-2": 10, "u-3": 10})
+}
+
+func TestSettlerFlagsPayoutKeyConflicts(t *testing.T) {
+	ctx := context.Background()
+	eventsService, votesService, ledger, event := newVotedEvent(t)
+	settler := NewSettler(eventsService, votesService, ledger, media.NewInMemoryLocker(), nil, SettlerConfig{
+		Payouts: PayoutConfig{Model: ModelParimutuel},
+	})
+	// A stale posting already holds u-2's payout key with another amount.
+	if _, err := ledger.Credit(ctx, "u-2", 1, wallet.ReasonReward, event.ID, PayoutKey(event.ID, "u-2")); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	if _, err := eventsService.RecordResult(ctx, event.ID, events.Result{OptionID: "no", Outcome: "no"}); err != nil {
+		t.Fatalf("RecordResult() error = %v", err)
+	}
+	if settled := settler.RunOnce(ctx); settled != 0 {
+		t.Fatalf("expected conflicting settlement to fail, got %d", settled)
+	}
+
+	flagged, err := eventsService.ListSettlementFlagged(ctx)
+	if err != nil {
+		t.Fatalf("ListSettlementFlagged() error = %v", err)
+	}
+	if len(flagged) != 1 || flagged[0].ID != event.ID || flagged[0].SettlementError == "" {
+		t.Fatalf("expected event to be flagged for admin review, got %+v", flagged)
+	}
+	if pending, _ := eventsService.ListUnsettled(ctx); len(pending) != 0 {
+		t.Fatalf("expected flagged event to leave the settlement sweep, got %+v", pending)
+	}
+}
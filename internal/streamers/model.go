@@ -18,19 +18,25 @@ type Submission struct {
 }
 
 type LLMDecision struct {
-	ID         string  `json:"id"`
-	RunID      string  `json:"runId"`
-	StreamerID string  `json:"streamerId"`
-	Stage      string  `json:"stage"`
-	Label      string  `json:"label"`
-	Confidence float64 `json:"confidence"`
-	CreatedAt  string  `json:"createdAt"`
+	ID           string  `json:"id"`
+	RunID        string  `json:"runId"`
+	StreamerID   string  `json:"streamerId"`
+	Stage        string  `json:"stage"`
+	Label        string  `json:"label"`
+	Confidence   float64 `json:"confidence"`
+	Model        string  `json:"model,omitempty"`
+	ErrorCode    string  `json:"errorCode,omitempty"`
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
 }
 
 type RecordDecisionRequest struct {
-	RunID      string
-	StreamerID string
-	Stage      string
-	Label      string
-	Confidence float64
+	RunID        string
+	StreamerID   string
+	Stage        string
+	Label        string
+	Confidence   float64
+	Model        string
+	ErrorCode    string
+	ErrorMessage string
 }
@@ -8,6 +8,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
 )
 
 var (
@@ -15,6 +19,7 @@ var (
 	ErrInvalidStatus     = errors.New("status filter is invalid")
 	ErrRateLimited       = errors.New("submission rate limit exceeded")
 	ErrTwitchUnavailable = errors.New("failed to validate twitch username")
+	ErrNotFound          = errors.New("streamer not found")
 )
 
 var twitchUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{4,25}$`)
@@ -32,21 +37,25 @@ func (v noopTwitchValidator) ValidateUsername(_ context.Context, username string
 	return strings.ToLower(username), nil
 }
 
-type submissionLimit struct {
-	count      int
-	windowEnds time.Time
+// DecisionObserver is notified after every recorded LLM decision.
+type DecisionObserver interface {
+	ObserveDecision(ctx context.Context, decision LLMDecision)
 }
 
+// submissionLimit caps how many streamers a user can submit.
+var submissionLimit = ratelimit.PerWindow(3, time.Minute)
+
 type Service struct {
-	mu             sync.RWMutex
-	items          []Streamer
-	decisions      map[string][]LLMDecision
-	validator      TwitchValidator
-	rateLimitMu    sync.Mutex
-	rateLimitByKey map[string]submissionLimit
-	nowFn          func() time.Time
-	counterMu      sync.Mutex
-	counter        int64
+	mu        sync.RWMutex
+	items     []Streamer
+	decisions map[string][]LLMDecision
+	validator TwitchValidator
+	observer  DecisionObserver
+	limiter   ratelimit.Limiter
+	logger    *zap.Logger
+	nowFn     func() time.Time
+	counterMu sync.Mutex
+	counter   int64
 }
 
 func NewService() *Service {
@@ -57,15 +66,33 @@ func NewServiceWithValidator(validator TwitchValidator) *Service {
 	if validator == nil {
 		validator = noopTwitchValidator{}
 	}
-	return &Service{
-		items:          []Streamer{},
-		decisions:      make(map[string][]LLMDecision),
-		validator:      validator,
-		rateLimitByKey: make(map[string]submissionLimit),
+	s := &Service{
+		items:     []Streamer{},
+		decisions: make(map[string][]LLMDecision),
+		validator: validator,
+		logger:    zap.NewNop(),
 		nowFn: func() time.Time {
 			return time.Now().UTC()
 		},
 	}
+	s.limiter = ratelimit.NewInMemoryLimiter().WithClock(func() time.Time { return s.nowFn() })
+	return s
+}
+
+// WithRateLimiter shares submission limits across nodes, e.g. through Redis.
+// Limiter failures are logged and let the submission through.
+func (s *Service) WithRateLimiter(limiter ratelimit.Limiter, logger *zap.Logger) {
+	if limiter != nil {
+		s.limiter = limiter
+	}
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// WithDecisionObserver registers a hook that reacts to recorded decisions.
+func (s *Service) WithDecisionObserver(observer DecisionObserver) {
+	s.observer = observer
 }
 
 func (s *Service) List(_ context.Context, query, status string, page int) []Streamer {
@@ -102,6 +129,34 @@ func (s *Service) List(_ context.Context, query, status string, page int) []Stre
 	return result
 }
 
+// ListByStatus returns every streamer with the given status without pagination.
+func (s *Service) ListByStatus(_ context.Context, status string) []Streamer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statusFilter := strings.ToLower(strings.TrimSpace(status))
+	result := make([]Streamer, 0)
+	for _, item := range s.items {
+		if strings.ToLower(item.Status) == statusFilter {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Get returns the streamer with the given ID.
+func (s *Service) Get(_ context.Context, id string) (Streamer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return Streamer{}, ErrNotFound
+}
+
 func (s *Service) Submit(ctx context.Context, twitchUsername, addedBy string) (Submission, error) {
 	username := strings.TrimSpace(twitchUsername)
 	if username == "" {
@@ -111,7 +166,7 @@ func (s *Service) Submit(ctx context.Context, twitchUsername, addedBy string) (S
 		return Submission{}, ErrInvalidStatus
 	}
 
-	if !s.allowSubmission(addedBy) {
+	if !s.allowSubmission(ctx, addedBy) {
 		return Submission{}, ErrRateLimited
 	}
 
@@ -140,7 +195,7 @@ func (s *Service) Submit(ctx context.Context, twitchUsername, addedBy string) (S
 	return Submission{ID: id, Status: "pending", Reason: nil}, nil
 }
 
-func (s *Service) RecordLLMDecision(_ context.Context, req RecordDecisionRequest) (LLMDecision, error) {
+func (s *Service) RecordLLMDecision(ctx context.Context, req RecordDecisionRequest) (LLMDecision, error) {
 	streamerID := strings.TrimSpace(req.StreamerID)
 	if streamerID == "" {
 		return LLMDecision{}, errors.New("streamerId is required")
@@ -154,7 +209,8 @@ func (s *Service) RecordLLMDecision(_ context.Context, req RecordDecisionRequest
 		return LLMDecision{}, errors.New("stage must be one of: stage_a, stage_b, stage_c, stage_d")
 	}
 	label := strings.TrimSpace(req.Label)
-	if label == "" {
+	errorCode := strings.TrimSpace(req.ErrorCode)
+	if label == "" && errorCode == "" {
 		return LLMDecision{}, errors.New("label is required")
 	}
 	if req.Confidence < 0 || req.Confidence > 1 {
@@ -167,19 +223,25 @@ func (s *Service) RecordLLMDecision(_ context.Context, req RecordDecisionRequest
 	s.counterMu.Unlock()
 
 	item := LLMDecision{
-		ID:         id,
-		RunID:      runID,
-		StreamerID: streamerID,
-		Stage:      stage,
-		Label:      label,
-		Confidence: req.Confidence,
-		CreatedAt:  s.nowFn().UTC().Format(time.RFC3339Nano),
+		ID:           id,
+		RunID:        runID,
+		StreamerID:   streamerID,
+		Stage:        stage,
+		Label:        label,
+		Confidence:   req.Confidence,
+		Model:        strings.TrimSpace(req.Model),
+		ErrorCode:    errorCode,
+		ErrorMessage: strings.TrimSpace(req.ErrorMessage),
+		CreatedAt:    s.nowFn().UTC().Format(time.RFC3339Nano),
 	}
 
 	s.mu.Lock()
 	s.decisions[streamerID] = append(s.decisions[streamerID], item)
 	s.mu.Unlock()
 
+	if s.observer != nil {
+		s.observer.ObserveDecision(ctx, item)
+	}
 	return item, nil
 }
 
@@ -211,6 +273,20 @@ func (s *Service) ListLLMDecisions(_ context.Context, streamerID string, limit i
 	return out
 }
 
+// LatestDecisionsByStage returns the most recent decision recorded for each stage.
+func (s *Service) LatestDecisionsByStage(_ context.Context, streamerID string) map[string]LLMDecision {
+	key := strings.TrimSpace(streamerID)
+	result := make(map[string]LLMDecision)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.decisions[key] {
+		result[item.Stage] = item
+	}
+	return result
+}
+
 func isSupportedStage(stage string) bool {
 	switch stage {
 	case "stage_a", "stage_b", "stage_c", "stage_d":
@@ -229,28 +305,15 @@ func IsSupportedStatus(status string) bool {
 	}
 }
 
-func (s *Service) allowSubmission(userID string) bool {
-	const maxSubmissionsPerMinute = 3
-	const window = time.Minute
-
+func (s *Service) allowSubmission(ctx context.Context, userID string) bool {
 	key := strings.TrimSpace(userID)
 	if key == "" {
 		key = "anonymous"
 	}
-
-	s.rateLimitMu.Lock()
-	defer s.rateLimitMu.Unlock()
-
-	now := s.nowFn()
-	state := s.rateLimitByKey[key]
-	if state.windowEnds.IsZero() || now.After(state.windowEnds) {
-		state = submissionLimit{count: 0, windowEnds: now.Add(window)}
-	}
-	if state.count >= maxSubmissionsPerMinute {
-		s.rateLimitByKey[key] = state
-		return false
+	result, err := s.limiter.Allow(ctx, "streamers:"+key, submissionLimit)
+	if err != nil {
+		s.logger.Warn("submission rate limiter unavailable", zap.String("user_id", key), zap.Error(err))
+		return true
 	}
-	state.count++
-	s.rateLimitByKey[key] = state
-	return true
+	return result.Allowed
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
 )
 
 type validatorStub struct {
@@ -55,6 +57,12 @@ func TestServiceSubmitValidationAndListing(t *testing.T) {
 			if items[0].Username != "best_streamer" {
 				t.Fatalf("unexpected username: %s", items[0].Username)
 			}
+			if got, err := svc.Get(context.Background(), sub.ID); err != nil || got.Username != "best_streamer" {
+				t.Fatalf("Get() = %+v, %v", got, err)
+			}
+			if _, err := svc.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
 		})
 	}
 }
@@ -73,13 +81,34 @@ func TestServiceSubmitRateLimit(t *testing.T) {
 	if _, err := svc.Submit(context.Background(), "streamername", "user-1"); !errors.Is(err, ErrRateLimited) {
 		t.Fatalf("expected rate limit error, got %v", err)
 	}
+	clock = clock.Add(30 * time.Second)
+	if _, err := svc.Submit(context.Background(), "streamername", "user-1"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected the cap to hold for the whole minute, got %v", err)
+	}
 
-	clock = clock.Add(time.Minute + time.Second)
+	clock = clock.Add(31 * time.Second)
 	if _, err := svc.Submit(context.Background(), "streamername", "user-1"); err != nil {
 		t.Fatalf("expected limiter reset after window, got %v", err)
 	}
 }
 
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis unavailable")
+}
+
+func TestServiceSubmitAllowsWhenLimiterFails(t *testing.T) {
+	svc := NewServiceWithValidator(validatorStub{displayName: "Display"})
+	svc.WithRateLimiter(failingLimiter{}, nil)
+
+	for i := 0; i < 5; i++ {
+		if _, err := svc.Submit(context.Background(), "streamername", "user-1"); err != nil {
+			t.Fatalf("submission %d: expected the limiter failure to be ignored, got %v", i+1, err)
+		}
+	}
+}
+
 func TestRecordAndListLLMDecisions(t *testing.T) {
 	svc := NewService()
 	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
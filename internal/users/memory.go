@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"sort"
 	"sync"
 )
 
@@ -29,6 +30,49 @@ func (r *InMemoryRepository) GetByTelegramID(_ context.Context, telegramID int64
 	return profile, nil
 }
 
+// GetByID returns a profile by its user ID.
+func (r *InMemoryRepository) GetByID(_ context.Context, id string) (Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, profile := range r.byTelegramID {
+		if profile.ID == id {
+			return profile, nil
+		}
+	}
+	return Profile{}, ErrNotFound
+}
+
+// ListByInviter returns the users attributed to inviterID, oldest first.
+func (r *InMemoryRepository) ListByInviter(_ context.Context, inviterID string) ([]Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Profile, 0)
+	for _, profile := range r.byTelegramID {
+		if profile.InviterUserID == inviterID {
+			result = append(result, profile)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// GetByReferralCode returns the profile owning a referral code.
+func (r *InMemoryRepository) GetByReferralCode(_ context.Context, code string) (Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, profile := range r.byTelegramID {
+		if profile.ReferralCode == code {
+			return profile, nil
+		}
+	}
+	return Profile{}, ErrNotFound
+}
+
 // Create stores a new profile.
 func (r *InMemoryRepository) Create(_ context.Context, profile Profile) error {
 	r.mu.Lock()
@@ -44,9 +88,11 @@ func (r *InMemoryRepository) Create(_ context.Context, profile Profile) error {
 func (r *InMemoryRepository) Update(_ context.Context, profile Profile) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, exists := r.byTelegramID[profile.TelegramID]; !exists {
+	existing, exists := r.byTelegramID[profile.TelegramID]
+	if !exists {
 		return ErrNotFound
 	}
+	profile.InviterUserID = existing.InviterUserID
 	r.byTelegramID[profile.TelegramID] = profile
 	return nil
 }
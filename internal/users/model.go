@@ -4,15 +4,18 @@ import "time"
 
 // Profile represents the public portion of a FunPot user record.
 type Profile struct {
-	ID           string    `json:"id"`
-	TelegramID   int64     `json:"telegramId"`
-	Username     string    `json:"username"`
-	FirstName    string    `json:"firstName"`
-	LastName     string    `json:"lastName"`
-	LanguageCode string    `json:"languageCode"`
-	ReferralCode string    `json:"referralCode"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           string `json:"id"`
+	TelegramID   int64  `json:"telegramId"`
+	Username     string `json:"username"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	LanguageCode string `json:"languageCode"`
+	ReferralCode string `json:"referralCode"`
+	// InviterUserID is the user whose referral code brought this user in.
+	// It is set when the user is created and never changes.
+	InviterUserID string    `json:"inviterUserId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
 // TelegramProfile carries the subset of Telegram fields required for syncing users.
@@ -22,4 +25,7 @@ type TelegramProfile struct {
 	FirstName    string
 	LastName     string
 	LanguageCode string
+	// StartParam is the Mini App start_param, which may carry a referral
+	// code as "ref_<code>" or the bare code.
+	StartParam string
 }
@@ -17,22 +17,11 @@ func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
+const userColumns = `id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at`
+
 // GetByTelegramID returns a profile identified by the Telegram ID.
 func (r *PostgresRepository) GetByTelegramID(ctx context.Context, telegramID int64) (Profile, error) {
-	const query = `SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, created_at, updated_at FROM users WHERE telegram_id = $1`
-
-	var profile Profile
-	err := r.db.QueryRowContext(ctx, query, telegramID).Scan(
-		&profile.ID,
-		&profile.TelegramID,
-		&profile.Username,
-		&profile.FirstName,
-		&profile.LastName,
-		&profile.LanguageCode,
-		&profile.ReferralCode,
-		&profile.CreatedAt,
-		&profile.UpdatedAt,
-	)
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE telegram_id = $1`, telegramID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Profile{}, ErrNotFound
@@ -42,11 +31,57 @@ func (r *PostgresRepository) GetByTelegramID(ctx context.Context, telegramID int
 	return profile, nil
 }
 
+// GetByID returns a profile by its user ID.
+func (r *PostgresRepository) GetByID(ctx context.Context, id string) (Profile, error) {
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, ErrNotFound
+		}
+		return Profile{}, fmt.Errorf("select user by id: %w", err)
+	}
+	return profile, nil
+}
+
+// ListByInviter returns the users attributed to inviterID, oldest first.
+func (r *PostgresRepository) ListByInviter(ctx context.Context, inviterID string) ([]Profile, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE inviter_user_id = $1 ORDER BY created_at, id`, inviterID)
+	if err != nil {
+		return nil, fmt.Errorf("select invited users: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Profile, 0)
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan invited user: %w", err)
+		}
+		result = append(result, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate invited users: %w", err)
+	}
+	return result, nil
+}
+
+// GetByReferralCode returns the profile owning a referral code.
+func (r *PostgresRepository) GetByReferralCode(ctx context.Context, code string) (Profile, error) {
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE referral_code = $1`, code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, ErrNotFound
+		}
+		return Profile{}, fmt.Errorf("select user by referral code: %w", err)
+	}
+	return profile, nil
+}
+
 // Create inserts a new profile. Existing records are left untouched.
 func (r *PostgresRepository) Create(ctx context.Context, profile Profile) error {
 	const query = `
-INSERT INTO users (id, telegram_id, username, first_name, last_name, language_code, referral_code, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+INSERT INTO users (id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 ON CONFLICT (telegram_id) DO NOTHING`
 
 	if _, err := r.db.ExecContext(ctx, query,
@@ -57,6 +92,7 @@ ON CONFLICT (telegram_id) DO NOTHING`
 		profile.LastName,
 		profile.LanguageCode,
 		profile.ReferralCode,
+		sql.NullString{String: profile.InviterUserID, Valid: profile.InviterUserID != ""},
 		profile.CreatedAt,
 		profile.UpdatedAt,
 	); err != nil {
@@ -100,3 +136,30 @@ func (r *PostgresRepository) Update(ctx context.Context, profile Profile) error
 	}
 	return nil
 }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProfile(row rowScanner) (Profile, error) {
+	var (
+		profile Profile
+		inviter sql.NullString
+	)
+	if err := row.Scan(
+		&profile.ID,
+		&profile.TelegramID,
+		&profile.Username,
+		&profile.FirstName,
+		&profile.LastName,
+		&profile.LanguageCode,
+		&profile.ReferralCode,
+		&inviter,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	); err != nil {
+		return Profile{}, err
+	}
+	profile.InviterUserID = inviter.String
+	return profile, nil
+}
@@ -21,10 +21,10 @@ func TestPostgresRepository_GetByTelegramID(t *testing.T) {
 	repo := NewPostgresRepository(db)
 	now := time.Now().UTC()
 
-	rows := sqlmock.NewRows([]string{"id", "telegram_id", "username", "first_name", "last_name", "language_code", "referral_code", "created_at", "updated_at"}).
-		AddRow("tg_1", int64(1), "user", "First", "Last", "en", "ABC123", now, now)
+	rows := sqlmock.NewRows([]string{"id", "telegram_id", "username", "first_name", "last_name", "language_code", "referral_code", "inviter_user_id", "created_at", "updated_at"}).
+		AddRow("tg_1", int64(1), "user", "First", "Last", "en", "ABC123", nil, now, now)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, created_at, updated_at FROM users WHERE telegram_id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at FROM users WHERE telegram_id = $1")).
 		WithArgs(int64(1)).
 		WillReturnRows(rows)
 
@@ -50,7 +50,7 @@ func TestPostgresRepository_GetByTelegramID_NotFound(t *testing.T) {
 
 	repo := NewPostgresRepository(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, created_at, updated_at FROM users WHERE telegram_id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at FROM users WHERE telegram_id = $1")).
 		WithArgs(int64(99)).
 		WillReturnError(sql.ErrNoRows)
 
@@ -64,6 +64,36 @@ func TestPostgresRepository_GetByTelegramID_NotFound(t *testing.T) {
 	}
 }
 
+func TestPostgresRepository_GetByReferralCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	rows := sqlmock.NewRows([]string{"id", "telegram_id", "username", "first_name", "last_name", "language_code", "referral_code", "inviter_user_id", "created_at", "updated_at"}).
+		AddRow("tg_2", int64(2), "invitee", "First", "Last", "en", "XYZ789", "tg_1", now, now)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at FROM users WHERE referral_code = $1")).
+		WithArgs("XYZ789").
+		WillReturnRows(rows)
+
+	profile, err := repo.GetByReferralCode(context.Background(), "XYZ789")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.ID != "tg_2" || profile.InviterUserID != "tg_1" {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestPostgresRepository_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -85,7 +115,7 @@ func TestPostgresRepository_Create(t *testing.T) {
 		UpdatedAt:    now,
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (id, telegram_id, username, first_name, last_name, language_code, referral_code, created_at, updated_at)\nVALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)\nON CONFLICT (telegram_id) DO NOTHING")).
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (id, telegram_id, username, first_name, last_name, language_code, referral_code, inviter_user_id, created_at, updated_at)\nVALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)\nON CONFLICT (telegram_id) DO NOTHING")).
 		WithArgs(
 			profile.ID,
 			profile.TelegramID,
@@ -94,6 +124,7 @@ func TestPostgresRepository_Create(t *testing.T) {
 			profile.LastName,
 			profile.LanguageCode,
 			profile.ReferralCode,
+			sql.NullString{},
 			profile.CreatedAt,
 			profile.UpdatedAt,
 		).
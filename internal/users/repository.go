@@ -11,6 +11,11 @@ var ErrNotFound = errors.New("user not found")
 // Repository abstracts user persistence operations.
 type Repository interface {
 	GetByTelegramID(ctx context.Context, telegramID int64) (Profile, error)
+	GetByID(ctx context.Context, id string) (Profile, error)
+	GetByReferralCode(ctx context.Context, code string) (Profile, error)
+	// ListByInviter returns the users attributed to inviterID, oldest first.
+	ListByInviter(ctx context.Context, inviterID string) ([]Profile, error)
 	Create(ctx context.Context, profile Profile) error
+	// Update persists profile fields; InviterUserID is never changed.
 	Update(ctx context.Context, profile Profile) error
 }
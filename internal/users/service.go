@@ -36,6 +36,11 @@ func (s *Service) SyncTelegramProfile(ctx context.Context, profile TelegramProfi
 	if err != nil {
 		if err == ErrNotFound {
 			created := s.newProfile(profile)
+			inviterID, err := s.resolveInviter(ctx, profile)
+			if err != nil {
+				return Profile{}, err
+			}
+			created.InviterUserID = inviterID
 			if err := s.repo.Create(ctx, created); err != nil {
 				return Profile{}, err
 			}
@@ -73,6 +78,32 @@ func (s *Service) newProfile(profile TelegramProfile) Profile {
 	}
 }
 
+// resolveInviter maps a start_param referral code to the inviting user.
+// Unknown codes and self-referrals are ignored rather than failing login.
+func (s *Service) resolveInviter(ctx context.Context, profile TelegramProfile) (string, error) {
+	code := referralCodeFromStartParam(profile.StartParam)
+	if code == "" || code == generateReferralCode(profile.ID) {
+		return "", nil
+	}
+	inviter, err := s.repo.GetByReferralCode(ctx, code)
+	if err != nil {
+		if err == ErrNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	if inviter.TelegramID == profile.ID {
+		return "", nil
+	}
+	return inviter.ID, nil
+}
+
+func referralCodeFromStartParam(startParam string) string {
+	code := strings.TrimSpace(startParam)
+	code = strings.TrimPrefix(code, "ref_")
+	return strings.ToUpper(code)
+}
+
 func generateReferralCode(telegramID int64) string {
 	hasher := sha256.New()
 	hasher.Write([]byte(fmt.Sprintf("funpot:%d", telegramID)))
@@ -89,3 +120,13 @@ func generateReferralCode(telegramID int64) string {
 func (s *Service) GetByTelegramID(ctx context.Context, telegramID int64) (Profile, error) {
 	return s.repo.GetByTelegramID(ctx, telegramID)
 }
+
+// GetByID fetches a user profile by its user ID.
+func (s *Service) GetByID(ctx context.Context, id string) (Profile, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListInvitees returns the users attributed to inviterID, oldest first.
+func (s *Service) ListInvitees(ctx context.Context, inviterID string) ([]Profile, error) {
+	return s.repo.ListByInviter(ctx, inviterID)
+}
@@ -0,0 +1,57 @@
+package users
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncTelegramProfile_AttributesReferral(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewInMemoryRepository())
+
+	inviter, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 1, Username: "inviter"})
+	if err != nil {
+		t.Fatalf("sync inviter: %v", err)
+	}
+
+	invitee, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 2, StartParam: "ref_" + inviter.ReferralCode})
+	if err != nil {
+		t.Fatalf("sync invitee: %v", err)
+	}
+	if invitee.InviterUserID != inviter.ID {
+		t.Fatalf("expected inviter %s, got %q", inviter.ID, invitee.InviterUserID)
+	}
+
+	other, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 3})
+	if err != nil {
+		t.Fatalf("sync other: %v", err)
+	}
+	again, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 2, StartParam: other.ReferralCode})
+	if err != nil {
+		t.Fatalf("resync invitee: %v", err)
+	}
+	if again.InviterUserID != inviter.ID {
+		t.Fatalf("attribution changed to %q", again.InviterUserID)
+	}
+}
+
+func TestSyncTelegramProfile_IgnoresInvalidReferral(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewInMemoryRepository())
+
+	self, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 7, StartParam: "ref_" + generateReferralCode(7)})
+	if err != nil {
+		t.Fatalf("sync self-referral: %v", err)
+	}
+	if self.InviterUserID != "" {
+		t.Fatalf("self-referral attributed to %q", self.InviterUserID)
+	}
+
+	unknown, err := svc.SyncTelegramProfile(ctx, TelegramProfile{ID: 8, StartParam: "ref_NOPE"})
+	if err != nil {
+		t.Fatalf("sync unknown code: %v", err)
+	}
+	if unknown.InviterUserID != "" {
+		t.Fatalf("unknown code attributed to %q", unknown.InviterUserID)
+	}
+}
@@ -0,0 +1,31 @@
+package votes
+
+import (
+	"context"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// LedgerWallet charges vote costs to the wallet ledger as vote_cost debits.
+type LedgerWallet struct {
+	ledger *wallet.Service
+}
+
+func NewLedgerWallet(ledger *wallet.Service) *LedgerWallet {
+	return &LedgerWallet{ledger: ledger}
+}
+
+func (w *LedgerWallet) VoteDebit(userID string, amount int64, refID, idempotencyKey string) (wallet.Entry, error) {
+	return w.ledger.Prepare(wallet.Posting{
+		UserID:         userID,
+		Type:           wallet.TypeDebit,
+		Amount:         amount,
+		Reason:         wallet.ReasonVoteCost,
+		RefID:          refID,
+		IdempotencyKey: idempotencyKey,
+	})
+}
+
+func (w *LedgerWallet) Debited(ctx context.Context, userID string, balance int64) {
+	w.ledger.PublishBalance(ctx, userID, balance)
+}
@@ -0,0 +1,122 @@
+package votes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// InMemoryRepository stores votes in memory for development and tests.
+type InMemoryRepository struct {
+	mu      sync.RWMutex
+	items   []Vote
+	counter int64
+	ledger  wallet.Repository
+}
+
+// NewInMemoryRepository constructs an empty in-memory repository.
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{}
+}
+
+// WithLedger lets CreatePaid post vote debits to ledger; wire the same
+// repository the wallet service uses.
+func (r *InMemoryRepository) WithLedger(ledger wallet.Repository) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ledger = ledger
+}
+
+func (r *InMemoryRepository) Create(_ context.Context, vote Vote) (Vote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insert(vote)
+}
+
+// CreatePaid holds the repository lock across the ledger posting so no reader
+// sees the vote before its debit succeeded.
+func (r *InMemoryRepository) CreatePaid(ctx context.Context, vote Vote, debit wallet.Entry) (Vote, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ledger == nil {
+		return Vote{}, 0, ErrWalletUnavailable
+	}
+	if r.hasVote(vote.UserID, vote.EventID) {
+		return Vote{}, 0, ErrAlreadyVoted
+	}
+	_, balance, replayed, err := r.ledger.Post(ctx, debit)
+	if err != nil {
+		return Vote{}, 0, err
+	}
+	if replayed {
+		return Vote{}, 0, ErrAlreadyVoted
+	}
+	vote, err = r.insert(vote)
+	if err != nil {
+		return Vote{}, 0, err
+	}
+	return vote, balance, nil
+}
+
+func (r *InMemoryRepository) insert(vote Vote) (Vote, error) {
+	if r.hasVote(vote.UserID, vote.EventID) {
+		return Vote{}, ErrAlreadyVoted
+	}
+	if vote.ID == "" {
+		r.counter++
+		vote.ID = fmt.Sprintf("vote_%d", r.counter)
+	}
+	r.items = append(r.items, vote)
+	return vote, nil
+}
+
+func (r *InMemoryRepository) hasVote(userID, eventID string) bool {
+	for _, existing := range r.items {
+		if existing.UserID == userID && existing.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryRepository) CountByOption(_ context.Context, eventID string) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]int)
+	for _, vote := range r.items {
+		if vote.EventID == eventID {
+			counts[vote.OptionID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *InMemoryRepository) ListByUser(_ context.Context, userID string, eventIDs []string) ([]Vote, error) {
+	wanted := make(map[string]struct{}, len(eventIDs))
+	for _, id := range eventIDs {
+		wanted[id] = struct{}{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Vote, 0)
+	for _, vote := range r.items {
+		if _, ok := wanted[vote.EventID]; ok && vote.UserID == userID {
+			result = append(result, vote)
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryRepository) ListByEvent(_ context.Context, eventID string) ([]Vote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Vote, 0)
+	for _, vote := range r.items {
+		if vote.EventID == eventID {
+			result = append(result, vote)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package votes
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEventIDRequired    = errors.New("eventId is required")
+	ErrOptionIDRequired   = errors.New("optionId is required")
+	ErrUserIDRequired     = errors.New("userId is required")
+	ErrEventNotLive       = errors.New("event is not accepting votes")
+	ErrCostMismatch       = errors.New("cost does not match the event cost per vote")
+	ErrAlreadyVoted       = errors.New("user already voted on this event")
+	ErrInsufficientFunds  = errors.New("insufficient balance")
+	ErrWalletUnavailable  = errors.New("wallet is not available for paid votes")
+	ErrPaidVotingDisabled = errors.New("paid voting is temporarily disabled")
+	ErrNotFound           = errors.New("vote not found")
+	ErrRateLimited        = errors.New("vote rate limit exceeded")
+)
+
+// RateLimitError reports a vote refused by the per-streamer rate limit.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Vote is a single user's pick on an event. A user votes at most once per event.
+type Vote struct {
+	ID             string    `json:"id"`
+	EventID        string    `json:"eventId"`
+	UserID         string    `json:"userId"`
+	OptionID       string    `json:"optionId"`
+	Cost           int64     `json:"cost"`
+	IdempotencyKey string    `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// CastRequest is a vote submission. Cost, when set, must equal the event's
+// current cost per vote so clients never pay a price they did not see.
+type CastRequest struct {
+	UserID         string
+	EventID        string
+	OptionID       string
+	Cost           *int64
+	IdempotencyKey string
+}
+
+// CastResult is the accepted vote and the caller's balance after the debit.
+// NewBalance is nil when no wallet is configured and the vote was free.
+type CastResult struct {
+	VoteID     string `json:"voteId"`
+	EventID    string `json:"eventId"`
+	OptionID   string `json:"optionId"`
+	NewBalance *int64 `json:"newBalance,omitempty"`
+}
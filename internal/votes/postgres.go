@@ -0,0 +1,183 @@
+package votes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+const voteColumns = `id, event_id, user_id, option_id, cost_int, idempotency_key, created_at`
+
+// PostgresRepository persists votes in PostgreSQL.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository constructs a repository backed by PostgreSQL.
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Create inserts a vote; the (user_id, event_id) unique constraint rejects
+// a second vote on the same event and ErrEventNotLive is returned once the
+// event stopped taking votes.
+func (r *PostgresRepository) Create(ctx context.Context, vote Vote) (Vote, error) {
+	return insertVote(ctx, r.db, vote)
+}
+
+// CreatePaid inserts the vote and posts its debit in one transaction, so a
+// vote is never stored unpaid and a debit never outlives a rejected vote.
+func (r *PostgresRepository) CreatePaid(ctx context.Context, vote Vote, debit wallet.Entry) (Vote, int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Vote{}, 0, fmt.Errorf("begin paid vote: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	vote, err = insertVote(ctx, tx, vote)
+	if err != nil {
+		return Vote{}, 0, err
+	}
+	_, balance, replayed, err := wallet.PostTx(ctx, tx, debit)
+	if err != nil {
+		return Vote{}, 0, err
+	}
+	if replayed {
+		// The debit key is derived from (user, event), so a replay means
+		// this vote was already paid for.
+		return Vote{}, 0, ErrAlreadyVoted
+	}
+	if err := tx.Commit(); err != nil {
+		return Vote{}, 0, fmt.Errorf("commit paid vote: %w", err)
+	}
+	return vote, balance, nil
+}
+
+type queryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertVote stores vote only while its event is live. The event row is
+// locked FOR SHARE, so closing the event waits for this transaction and the
+// settler never lists votes before a concurrent vote commits.
+func insertVote(ctx context.Context, db queryExecer, vote Vote) (Vote, error) {
+	if vote.ID == "" {
+		vote.ID = uuid.NewString()
+	}
+
+	const query = `
+INSERT INTO votes (id, event_id, user_id, option_id, cost_int, idempotency_key, created_at)
+SELECT $1::text, $2::text, $3::text, $4::text, $5::bigint, $6::text, $7::timestamptz
+WHERE EXISTS (
+    SELECT 1 FROM events
+    WHERE id = $2 AND state = 'live' AND (closes_at IS NULL OR closes_at > $7)
+    FOR SHARE
+)
+ON CONFLICT (user_id, event_id) DO NOTHING`
+
+	result, err := db.ExecContext(ctx, query,
+		vote.ID,
+		vote.EventID,
+		vote.UserID,
+		vote.OptionID,
+		vote.Cost,
+		vote.IdempotencyKey,
+		vote.CreatedAt,
+	)
+	if err != nil {
+		return Vote{}, fmt.Errorf("insert vote: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Vote{}, err
+	}
+	if rowsAffected == 0 {
+		var voted bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM votes WHERE user_id = $1 AND event_id = $2)`, vote.UserID, vote.EventID).Scan(&voted); err != nil {
+			return Vote{}, fmt.Errorf("select existing vote: %w", err)
+		}
+		if voted {
+			return Vote{}, ErrAlreadyVoted
+		}
+		return Vote{}, ErrEventNotLive
+	}
+	return vote, nil
+}
+
+// ListByEvent returns the votes of an event, oldest first.
+func (r *PostgresRepository) ListByEvent(ctx context.Context, eventID string) ([]Vote, error) {
+	query := `SELECT ` + voteColumns + ` FROM votes WHERE event_id = $1 ORDER BY created_at, id`
+	return r.list(ctx, query, eventID)
+}
+
+// ListByUser returns the user's votes on the given events; the
+// (user_id, event_id) unique index serves the lookup.
+func (r *PostgresRepository) ListByUser(ctx context.Context, userID string, eventIDs []string) ([]Vote, error) {
+	if len(eventIDs) == 0 {
+		return []Vote{}, nil
+	}
+	args := make([]any, 0, len(eventIDs)+1)
+	args = append(args, userID)
+	placeholders := make([]string, 0, len(eventIDs))
+	for _, id := range eventIDs {
+		args = append(args, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	query := `SELECT ` + voteColumns + ` FROM votes WHERE user_id = $1 AND event_id IN (` + strings.Join(placeholders, ", ") + `)`
+	return r.list(ctx, query, args...)
+}
+
+func (r *PostgresRepository) list(ctx context.Context, query string, args ...any) ([]Vote, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select votes: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Vote, 0)
+	for rows.Next() {
+		var vote Vote
+		if err := rows.Scan(&vote.ID, &vote.EventID, &vote.UserID, &vote.OptionID, &vote.Cost, &vote.IdempotencyKey, &vote.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan vote: %w", err)
+		}
+		vote.CreatedAt = vote.CreatedAt.UTC()
+		result = append(result, vote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate votes: %w", err)
+	}
+	return result, nil
+}
+
+// CountByOption recomputes an event's totals from its votes.
+func (r *PostgresRepository) CountByOption(ctx context.Context, eventID string) (map[string]int, error) {
+	const query = `SELECT option_id, COUNT(*) FROM votes WHERE event_id = $1 GROUP BY option_id`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("count votes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			optionID string
+			count    int
+		)
+		if err := rows.Scan(&optionID, &count); err != nil {
+			return nil, fmt.Errorf("scan vote count: %w", err)
+		}
+		counts[optionID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate vote counts: %w", err)
+	}
+	return counts, nil
+}
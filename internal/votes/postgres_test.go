@@ -0,0 +1,158 @@
+package votes
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func TestPostgresRepository_CreateDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	vote := Vote{ID: "vote-1", EventID: "evt-1", UserID: "u-1", OptionID: "yes", Cost: 10, IdempotencyKey: "k-1", CreatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO votes (id, event_id, user_id, option_id, cost_int, idempotency_key, created_at)\nSELECT $1::text")).
+		WithArgs(vote.ID, vote.EventID, vote.UserID, vote.OptionID, vote.Cost, vote.IdempotencyKey, vote.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM votes WHERE user_id = $1 AND event_id = $2)")).
+		WithArgs("u-1", "evt-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	if _, err := repo.Create(context.Background(), vote); !errors.Is(err, ErrAlreadyVoted) {
+		t.Fatalf("expected ErrAlreadyVoted, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_CreateRequiresLiveEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	vote := Vote{ID: "vote-1", EventID: "evt-1", UserID: "u-1", OptionID: "yes", CreatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = $2 AND state = 'live' AND (closes_at IS NULL OR closes_at > $7)\n    FOR SHARE")).
+		WithArgs(vote.ID, vote.EventID, vote.UserID, vote.OptionID, vote.Cost, vote.IdempotencyKey, vote.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM votes WHERE user_id = $1 AND event_id = $2)")).
+		WithArgs("u-1", "evt-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if _, err := repo.Create(context.Background(), vote); !errors.Is(err, ErrEventNotLive) {
+		t.Fatalf("expected ErrEventNotLive, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_CreatePaidRollsBackUnpaidVote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	vote := Vote{ID: "vote-1", EventID: "evt-1", UserID: "u-1", OptionID: "yes", Cost: 10, CreatedAt: now}
+	debit := wallet.Entry{UserID: "u-1", Type: wallet.TypeDebit, Amount: 10, Currency: wallet.DefaultCurrency, Reason: wallet.ReasonVoteCost, RefID: "vote-1", IdempotencyKey: "vote:evt-1:u-1", CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO votes")).
+		WithArgs(vote.ID, vote.EventID, vote.UserID, vote.OptionID, vote.Cost, vote.IdempotencyKey, vote.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_accounts")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance_int FROM wallet_accounts WHERE user_id = $1 FOR UPDATE")).
+		WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_int"}).AddRow(int64(5)))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM wallet_ledger WHERE idempotency_key = $1")).
+		WithArgs("vote:evt-1:u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	if _, _, err := repo.CreatePaid(context.Background(), vote, debit); !errors.Is(err, wallet.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_ListByEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, event_id, user_id, option_id, cost_int, idempotency_key, created_at FROM votes WHERE event_id = $1 ORDER BY created_at, id")).
+		WithArgs("evt-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "user_id", "option_id", "cost_int", "idempotency_key", "created_at"}).
+			AddRow("vote-1", "evt-1", "u-1", "yes", int64(10), "k-1", now))
+
+	items, err := repo.ListByEvent(context.Background(), "evt-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].UserID != "u-1" || items[0].Cost != 10 {
+		t.Fatalf("unexpected votes: %+v", items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, event_id, user_id, option_id, cost_int, idempotency_key, created_at FROM votes WHERE user_id = $1 AND event_id IN ($2, $3)")).
+		WithArgs("u-1", "evt-1", "evt-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "user_id", "option_id", "cost_int", "idempotency_key", "created_at"}).
+			AddRow("vote-1", "evt-2", "u-1", "no", int64(10), "k-1", now))
+
+	items, err := repo.ListByUser(context.Background(), "u-1", []string{"evt-1", "evt-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].EventID != "evt-2" || items[0].OptionID != "no" {
+		t.Fatalf("unexpected votes: %+v", items)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
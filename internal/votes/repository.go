@@ -0,0 +1,24 @@
+package votes
+
+import (
+	"context"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+// Repository abstracts vote persistence.
+type Repository interface {
+	// Create stores a vote, assigning its ID when empty. It returns
+	// ErrAlreadyVoted when the user already voted on the event and, where the
+	// store can check it atomically, ErrEventNotLive once the event closed.
+	Create(ctx context.Context, vote Vote) (Vote, error)
+	// CreatePaid stores vote and posts its debit in one atomic step; neither
+	// is kept unless both succeed. It returns ErrAlreadyVoted like Create and
+	// wallet.ErrInsufficientFunds when the balance is too low.
+	CreatePaid(ctx context.Context, vote Vote, debit wallet.Entry) (Vote, int64, error)
+	ListByEvent(ctx context.Context, eventID string) ([]Vote, error)
+	// ListByUser returns the user's votes among eventIDs in one lookup.
+	ListByUser(ctx context.Context, userID string, eventIDs []string) ([]Vote, error)
+	// CountByOption returns the number of votes per option of an event.
+	CountByOption(ctx context.Context, eventID string) (map[string]int, error)
+}
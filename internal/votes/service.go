@@ -0,0 +1,283 @@
+package votes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/realtime"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
+)
+
+// rateLimitNoticeCode tags the SYSTEM_NOTICE sent when a vote is throttled.
+const rateLimitNoticeCode = "VOTE_RATE_LIMITED"
+
+// EventSource loads the event a vote targets.
+type EventSource interface {
+	Get(ctx context.Context, id string) (events.LiveEvent, error)
+}
+
+// Wallet prices vote debits. The repository posts the returned entry in the
+// same transaction as the vote row; Debited runs once both are committed.
+type Wallet interface {
+	VoteDebit(userID string, amount int64, refID, idempotencyKey string) (wallet.Entry, error)
+	Debited(ctx context.Context, userID string, balance int64)
+}
+
+// TotalsTarget stores reconciled vote totals; implemented by events.Service.
+type TotalsTarget interface {
+	ReconcileTotals(ctx context.Context, eventID string, totals map[string]int) error
+}
+
+// StaleTotalsRecorder remembers events whose tally missed a vote so
+// reconcile-totals recounts them; implemented by events.Service.
+type StaleTotalsRecorder interface {
+	MarkTotalsStale(ctx context.Context, eventID string) error
+}
+
+type Service struct {
+	repo         Repository
+	events       EventSource
+	wallet       Wallet
+	tally        events.Tally
+	requireTally bool
+	stale        StaleTotalsRecorder
+	limiter      ratelimit.Limiter
+	limit        ratelimit.Limit
+	publisher    realtime.Publisher
+	logger       *zap.Logger
+	nowFn        func() time.Time
+}
+
+func NewService(repo Repository, events EventSource) *Service {
+	return &Service{
+		repo:   repo,
+		events: events,
+		logger: zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithStaleTotals records events whose tally missed a vote after retrying.
+func (s *Service) WithStaleTotals(recorder StaleTotalsRecorder) {
+	s.stale = recorder
+}
+
+// WithWallet enables paid votes. Without a wallet only free events accept votes.
+func (s *Service) WithWallet(wallet Wallet) {
+	s.wallet = wallet
+}
+
+// WithTally counts accepted votes in tally. With requireForPaid, paid votes
+// are refused while the tally is unreachable so debits never outrun totals;
+// free votes are still stored and recovered by reconciling from the votes table.
+func (s *Service) WithTally(tally events.Tally, requireForPaid bool, logger *zap.Logger) {
+	s.tally = tally
+	s.requireTally = requireForPaid
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// WithRateLimiter caps votes per user per streamer. A zero limit disables it.
+func (s *Service) WithRateLimiter(limiter ratelimit.Limiter, limit ratelimit.Limit) {
+	s.limiter = limiter
+	s.limit = limit
+}
+
+// WithPublisher notifies users on their channel when a vote is throttled.
+func (s *Service) WithPublisher(publisher realtime.Publisher) {
+	s.publisher = publisher
+}
+
+// Cast records the caller's vote on a live event and debits its cost. The
+// vote row and the debit are written in one transaction. Both the vote ID
+// and the debit key are derived from (user, event), so a retry after an
+// ambiguous failure can never charge twice.
+func (s *Service) Cast(ctx context.Context, req CastRequest) (CastResult, error) {
+	userID := strings.TrimSpace(req.UserID)
+	if userID == "" {
+		return CastResult{}, ErrUserIDRequired
+	}
+	eventID := strings.TrimSpace(req.EventID)
+	if eventID == "" {
+		return CastResult{}, ErrEventIDRequired
+	}
+	optionID := strings.TrimSpace(req.OptionID)
+	if optionID == "" {
+		return CastResult{}, ErrOptionIDRequired
+	}
+
+	event, err := s.events.Get(ctx, eventID)
+	if err != nil {
+		return CastResult{}, err
+	}
+	now := s.nowFn()
+	if !event.IsOpen(now) {
+		return CastResult{}, ErrEventNotLive
+	}
+	if !hasOption(event.Options, optionID) {
+		return CastResult{}, events.ErrUnknownOption
+	}
+	cost := int64(event.CostPerVote)
+	if req.Cost != nil && *req.Cost != cost {
+		return CastResult{}, ErrCostMismatch
+	}
+	if cost > 0 && s.wallet == nil {
+		return CastResult{}, ErrWalletUnavailable
+	}
+	if err := s.checkRateLimit(ctx, userID, event.StreamerID); err != nil {
+		return CastResult{}, err
+	}
+	if cost > 0 && s.requireTally && s.tally != nil {
+		if err := s.tally.Ping(ctx); err != nil {
+			s.logger.Warn("vote tally unavailable; refusing paid vote", zap.String("event_id", event.ID), zap.Error(err))
+			return CastResult{}, ErrPaidVotingDisabled
+		}
+	}
+
+	vote := Vote{
+		ID:             VoteID(event.ID, userID),
+		EventID:        event.ID,
+		UserID:         userID,
+		OptionID:       optionID,
+		Cost:           cost,
+		IdempotencyKey: strings.TrimSpace(req.IdempotencyKey),
+		CreatedAt:      now,
+	}
+	var balance *int64
+	if cost > 0 {
+		debit, err := s.wallet.VoteDebit(userID, cost, vote.ID, DebitKey(event.ID, userID))
+		if err != nil {
+			return CastResult{}, err
+		}
+		var newBalance int64
+		vote, newBalance, err = s.repo.CreatePaid(ctx, vote, debit)
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			return CastResult{}, fmt.Errorf("%w: %w", ErrInsufficientFunds, err)
+		}
+		if err != nil {
+			return CastResult{}, err
+		}
+		s.wallet.Debited(ctx, userID, newBalance)
+		balance = &newBalance
+	} else {
+		vote, err = s.repo.Create(ctx, vote)
+		if err != nil {
+			return CastResult{}, err
+		}
+	}
+
+	result := CastResult{VoteID: vote.ID, EventID: vote.EventID, OptionID: vote.OptionID, NewBalance: balance}
+	s.count(ctx, vote)
+	return result, nil
+}
+
+// count adds a stored vote to the tally, retrying once. A vote the tally
+// still missed marks the event's totals stale for reconcile-totals.
+func (s *Service) count(ctx context.Context, vote Vote) {
+	if s.tally == nil {
+		return
+	}
+	err := s.tally.Increment(ctx, vote.EventID, vote.OptionID)
+	if err != nil {
+		err = s.tally.Increment(ctx, vote.EventID, vote.OptionID)
+	}
+	if err == nil {
+		return
+	}
+	s.logger.Warn("failed to count vote", zap.String("event_id", vote.EventID), zap.String("vote_id", vote.ID), zap.Error(err))
+	if s.stale == nil {
+		return
+	}
+	if err := s.stale.MarkTotalsStale(context.WithoutCancel(ctx), vote.EventID); err != nil {
+		s.logger.Error("failed to mark event totals stale; run reconcile-totals", zap.String("event_id", vote.EventID), zap.Error(err))
+	}
+}
+
+// VoteID is the deterministic ID of a user's vote on an event.
+func VoteID(eventID, userID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("vote:"+eventID+":"+userID)).String()
+}
+
+// DebitKey is the ledger idempotency key of a user's vote debit on an event.
+func DebitKey(eventID, userID string) string {
+	return "vote:" + eventID + ":" + userID
+}
+
+// ListByEvent returns every vote cast on an event, oldest first.
+func (s *Service) ListByEvent(ctx context.Context, eventID string) ([]Vote, error) {
+	return s.repo.ListByEvent(ctx, strings.TrimSpace(eventID))
+}
+
+// UserVotes returns the caller's pick per event among eventIDs.
+func (s *Service) UserVotes(ctx context.Context, userID string, eventIDs []string) (map[string]events.UserVote, error) {
+	items, err := s.repo.ListByUser(ctx, strings.TrimSpace(userID), eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	picks := make(map[string]events.UserVote, len(items))
+	for _, vote := range items {
+		picks[vote.EventID] = events.UserVote{OptionID: vote.OptionID}
+	}
+	return picks, nil
+}
+
+// ReconcileTotals recomputes an event's totals from its stored votes and
+// hands them to target, which overwrites the tally and the event snapshot.
+func (s *Service) ReconcileTotals(ctx context.Context, target TotalsTarget, eventID string) (map[string]int, error) {
+	counts, err := s.repo.CountByOption(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := target.ReconcileTotals(ctx, eventID, counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// checkRateLimit takes a token from the caller's bucket for the streamer.
+// Limiter outages let votes through; idempotency and the one-vote-per-event
+// constraint still bound the damage.
+func (s *Service) checkRateLimit(ctx context.Context, userID, streamerID string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	result, err := s.limiter.Allow(ctx, "votes:"+userID+":"+streamerID, s.limit)
+	if err != nil {
+		s.logger.Warn("vote rate limiter unavailable", zap.String("user_id", userID), zap.Error(err))
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+	if s.publisher != nil {
+		notice := realtime.Message{Type: realtime.TypeSystemNotice, Payload: map[string]any{
+			"code":         rateLimitNoticeCode,
+			"message":      "You are voting too fast. Try again shortly.",
+			"retryAfterMs": result.RetryAfter.Milliseconds(),
+		}}
+		if err := s.publisher.Publish(ctx, realtime.UserChannel(userID), notice); err != nil {
+			s.logger.Warn("failed to publish rate limit notice", zap.String("user_id", userID), zap.Error(err))
+		}
+	}
+	return &RateLimitError{RetryAfter: result.RetryAfter}
+}
+
+func hasOption(options []events.Option, id string) bool {
+	for _, option := range options {
+		if option.ID == id {
+			return true
+		}
+	}
+	return false
+}
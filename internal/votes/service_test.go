@@ -0,0 +1,226 @@
+package votes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/events"
+	"github.com/funpot/funpot-go-core/internal/realtime"
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
+)
+
+// withLedger funds balances on an in-memory ledger shared by svc's
+// repository and wallet.
+func withLedger(t *testing.T, svc *Service, balances map[string]int64) *wallet.Service {
+	t.Helper()
+	repo := wallet.NewInMemoryRepository()
+	ledger := wallet.NewService(repo)
+	for userID, amount := range balances {
+		if _, err := ledger.Credit(context.Background(), userID, amount, wallet.ReasonStarsTopup, "", "seed:"+userID); err != nil {
+			t.Fatalf("Credit() error = %v", err)
+		}
+	}
+	svc.repo.(*InMemoryRepository).WithLedger(repo)
+	svc.WithWallet(NewLedgerWallet(ledger))
+	return ledger
+}
+
+func newVotingFixture(t *testing.T, cost int) (*Service, *events.Service, events.LiveEvent) {
+	t.Helper()
+	eventsService := events.NewService(nil)
+	event, err := eventsService.Create(context.Background(), events.CreateRequest{
+		StreamerID:  "s-1",
+		Title:       "Will it happen?",
+		Options:     []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:    time.Now().Add(time.Minute),
+		CostPerVote: cost,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return NewService(NewInMemoryRepository(), eventsService), eventsService, event
+}
+
+func TestCastDebitsOncePerEvent(t *testing.T) {
+	ctx := context.Background()
+	svc, _, event := newVotingFixture(t, 10)
+	ledger := withLedger(t, svc, map[string]int64{"u-1": 25})
+
+	result, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes", IdempotencyKey: "k-1"})
+	if err != nil {
+		t.Fatalf("Cast() error = %v", err)
+	}
+	if result.VoteID == "" || result.NewBalance == nil || *result.NewBalance != 15 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "no"}); !errors.Is(err, ErrAlreadyVoted) {
+		t.Fatalf("expected ErrAlreadyVoted, got %v", err)
+	}
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes", IdempotencyKey: "k-2"}); !errors.Is(err, ErrAlreadyVoted) {
+		t.Fatalf("expected retry to be rejected, got %v", err)
+	}
+	if balance, _ := ledger.Balance(ctx, "u-1"); balance != 15 {
+		t.Fatalf("expected a single debit, balance = %d", balance)
+	}
+	if result.VoteID != VoteID(event.ID, "u-1") {
+		t.Fatalf("expected deterministic vote id, got %s", result.VoteID)
+	}
+}
+
+func TestCastRejectsInvalidVotes(t *testing.T) {
+	ctx := context.Background()
+	svc, eventsService, event := newVotingFixture(t, 10)
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); !errors.Is(err, ErrWalletUnavailable) {
+		t.Fatalf("expected ErrWalletUnavailable, got %v", err)
+	}
+	ledger := withLedger(t, svc, map[string]int64{"u-1": 5})
+
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "maybe"}); !errors.Is(err, events.ErrUnknownOption) {
+		t.Fatalf("expected ErrUnknownOption, got %v", err)
+	}
+	stale := int64(5)
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes", Cost: &stale}); !errors.Is(err, ErrCostMismatch) {
+		t.Fatalf("expected ErrCostMismatch, got %v", err)
+	}
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if votes, _ := svc.repo.ListByEvent(ctx, event.ID); len(votes) != 0 {
+		t.Fatalf("expected no unpaid vote to be stored, got %+v", votes)
+	}
+
+	if _, err := eventsService.Close(ctx, event.ID); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := ledger.Credit(ctx, "u-1", 95, wallet.ReasonStarsTopup, "", "seed:u-1:more"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); !errors.Is(err, ErrEventNotLive) {
+		t.Fatalf("expected ErrEventNotLive, got %v", err)
+	}
+}
+
+type downTally struct {
+	*events.InMemoryTally
+}
+
+func (downTally) Ping(context.Context) error {
+	return errors.New("redis: connection refused")
+}
+
+func TestCastCountsVotesAndGuardsPaidVoting(t *testing.T) {
+	ctx := context.Background()
+	svc, eventsService, event := newVotingFixture(t, 10)
+	withLedger(t, svc, map[string]int64{"u-1": 100, "u-2": 100})
+	tally := events.NewInMemoryTally()
+	eventsService.WithTally(tally)
+	svc.WithTally(tally, true, zap.NewNop())
+
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); err != nil {
+		t.Fatalf("Cast() error = %v", err)
+	}
+	if totals, _ := tally.Totals(ctx, event.ID); totals["yes"] != 1 {
+		t.Fatalf("expected vote to be tallied, got %v", totals)
+	}
+
+	svc.WithTally(downTally{tally}, true, zap.NewNop())
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-2", EventID: event.ID, OptionID: "no"}); !errors.Is(err, ErrPaidVotingDisabled) {
+		t.Fatalf("expected ErrPaidVotingDisabled, got %v", err)
+	}
+
+	counts, err := svc.ReconcileTotals(ctx, eventsService, event.ID)
+	if err != nil || counts["yes"] != 1 {
+		t.Fatalf("unexpected reconcile result %v, %v", counts, err)
+	}
+}
+
+// failingTally accepts pings but fails the first increments.
+type failingTally struct {
+	*events.InMemoryTally
+	failures int
+}
+
+func (f *failingTally) Increment(ctx context.Context, eventID, optionID string) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("redis: i/o timeout")
+	}
+	return f.InMemoryTally.Increment(ctx, eventID, optionID)
+}
+
+func TestCastRetriesAndRecordsMissedIncrements(t *testing.T) {
+	ctx := context.Background()
+	svc, eventsService, event := newVotingFixture(t, 10)
+	withLedger(t, svc, map[string]int64{"u-1": 100, "u-2": 100})
+	tally := &failingTally{InMemoryTally: events.NewInMemoryTally(), failures: 1}
+	eventsService.WithTally(tally)
+	svc.WithTally(tally, true, zap.NewNop())
+	svc.WithStaleTotals(eventsService)
+
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); err != nil {
+		t.Fatalf("Cast() error = %v", err)
+	}
+	if totals, _ := tally.Totals(ctx, event.ID); totals["yes"] != 1 {
+		t.Fatalf("expected the retry to count the vote, got %v", totals)
+	}
+	if stale, _ := eventsService.ListTotalsStale(ctx); len(stale) != 0 {
+		t.Fatalf("expected no stale events after a successful retry, got %v", stale)
+	}
+
+	tally.failures = 2
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-2", EventID: event.ID, OptionID: "no"}); err != nil {
+		t.Fatalf("Cast() error = %v", err)
+	}
+	if stale, _ := eventsService.ListTotalsStale(ctx); len(stale) != 1 || stale[0] != event.ID {
+		t.Fatalf("expected the event marked stale, got %v", stale)
+	}
+
+	counts, err := svc.ReconcileTotals(ctx, eventsService, event.ID)
+	if err != nil || counts["no"] != 1 {
+		t.Fatalf("unexpected reconcile result %v, %v", counts, err)
+	}
+	if stale, _ := eventsService.ListTotalsStale(ctx); len(stale) != 0 {
+		t.Fatalf("expected reconcile to clear the stale mark, got %v", stale)
+	}
+}
+
+func TestCastRateLimitsPerStreamer(t *testing.T) {
+	ctx := context.Background()
+	svc, eventsService, event := newVotingFixture(t, 0)
+	now := time.Now().UTC()
+	svc.WithRateLimiter(ratelimit.NewInMemoryLimiter().WithClock(func() time.Time { return now }), ratelimit.PerMinute(1))
+	publisher := realtime.NewInMemoryPublisher()
+	svc.WithPublisher(publisher)
+
+	second, err := eventsService.Create(ctx, events.CreateRequest{
+		StreamerID: "s-1",
+		Title:      "Next round?",
+		Options:    []events.Option{{ID: "yes", Label: "Yes"}, {ID: "no", Label: "No"}},
+		ClosesAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: event.ID, OptionID: "yes"}); err != nil {
+		t.Fatalf("Cast() error = %v", err)
+	}
+	_, err = svc.Cast(ctx, CastRequest{UserID: "u-1", EventID: second.ID, OptionID: "yes"})
+	var limited *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limited) || limited.RetryAfter != time.Minute {
+		t.Fatalf("expected rate limit with 1m retry, got %v", err)
+	}
+	messages := publisher.Messages()
+	if len(messages) != 1 || messages[0].Channel != realtime.UserChannel("u-1") || messages[0].Message.Type != realtime.TypeSystemNotice {
+		t.Fatalf("expected one system notice for u-1, got %+v", messages)
+	}
+	if _, err := svc.Cast(ctx, CastRequest{UserID: "u-2", EventID: second.ID, OptionID: "no"}); err != nil {
+		t.Fatalf("expected other users unaffected, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// InMemoryRepository keeps the ledger in process memory; used in tests and
+// local runs without PostgreSQL.
+type InMemoryRepository struct {
+	mu       sync.Mutex
+	entries  map[string][]Entry
+	byKey    map[string]Entry
+	balances map[string]int64
+	counter  int64
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		entries:  make(map[string][]Entry),
+		byKey:    make(map[string]Entry),
+		balances: make(map[string]int64),
+	}
+}
+
+func (r *InMemoryRepository) Post(_ context.Context, entry Entry) (Entry, int64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.byKey[entry.IdempotencyKey]; ok {
+		return existing, r.balances[existing.UserID], true, nil
+	}
+	balance := r.balances[entry.UserID] + entry.signed()
+	if balance < 0 && !IsSystemAccount(entry.UserID) {
+		return Entry{}, 0, false, ErrInsufficientFunds
+	}
+	entry.ID = r.nextID()
+	entry.PostingID = entry.ID
+	counter := entry.counter()
+	counter.ID = r.nextID()
+	r.append(entry)
+	r.append(counter)
+	return entry, balance, false, nil
+}
+
+func (r *InMemoryRepository) nextID() string {
+	r.counter++
+	return fmt.Sprintf("wle_%d", r.counter)
+}
+
+func (r *InMemoryRepository) append(entry Entry) {
+	r.entries[entry.UserID] = append(r.entries[entry.UserID], entry)
+	r.byKey[entry.IdempotencyKey] = entry
+	r.balances[entry.UserID] += entry.signed()
+}
+
+func (r *InMemoryRepository) GetByKey(_ context.Context, idempotencyKey string) (Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.byKey[idempotencyKey]
+	if !ok {
+		return Entry{}, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+func (r *InMemoryRepository) Balance(_ context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.balances[userID], nil
+}
+
+func (r *InMemoryRepository) History(_ context.Context, userID string, limit, offset int) ([]Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries[userID]
+	out := make([]Entry, 0, limit)
+	for i := len(entries) - 1 - offset; i >= 0 && len(out) < limit; i-- {
+		out = append(out, entries[i])
+	}
+	return out, nil
+}
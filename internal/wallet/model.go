@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUserIDRequired         = errors.New("userId is required")
+	ErrInvalidAmount          = errors.New("amount must be positive")
+	ErrInvalidType            = errors.New("entry type must be credit or debit")
+	ErrInvalidReason          = errors.New("reason is not supported")
+	ErrIdempotencyKeyRequired = errors.New("idempotency key is required")
+	ErrIdempotencyConflict    = errors.New("idempotency key was already used for a different posting")
+	ErrInsufficientFunds      = errors.New("insufficient balance")
+	ErrReservedAccount        = errors.New("account is reserved for the system ledger")
+	ErrEntryNotFound          = errors.New("ledger entry not found")
+)
+
+// DefaultCurrency is the internal currency every balance is kept in.
+const DefaultCurrency = "INT"
+
+const (
+	TypeCredit = "credit"
+	TypeDebit  = "debit"
+)
+
+// Posting reasons from docs/erd.md.
+const (
+	ReasonStarsTopup    = "stars_topup"
+	ReasonVoteCost      = "vote_cost"
+	ReasonReward        = "reward"
+	ReasonWithdraw      = "withdraw"
+	ReasonReferralBonus = "referral_bonus"
+)
+
+// System accounts hold the other side of every posting so that each posting
+// sums to zero across the ledger. Their balances may go negative.
+const (
+	systemAccountPrefix     = "system:"
+	AccountHouse            = systemAccountPrefix + "house"
+	AccountPot              = systemAccountPrefix + "pot"
+	AccountPaymentsClearing = systemAccountPrefix + "payments_clearing"
+	AccountReferral         = systemAccountPrefix + "referral"
+)
+
+// IsSystemAccount reports whether accountID is one of the ledger's system
+// accounts rather than a user wallet.
+func IsSystemAccount(accountID string) bool {
+	return strings.HasPrefix(accountID, systemAccountPrefix)
+}
+
+// CounterAccount returns the system account that takes the other side of a
+// posting with reason: stakes and rewards move through the pot, Stars top-ups
+// and withdrawals through payments clearing, and referral bonuses are funded
+// by the referral account.
+func CounterAccount(reason string) string {
+	switch reason {
+	case ReasonVoteCost, ReasonReward:
+		return AccountPot
+	case ReasonStarsTopup, ReasonWithdraw:
+		return AccountPaymentsClearing
+	case ReasonReferralBonus:
+		return AccountReferral
+	default:
+		return AccountHouse
+	}
+}
+
+// IsSupportedReason reports whether reason is a known posting reason.
+func IsSupportedReason(reason string) bool {
+	switch reason {
+	case ReasonStarsTopup, ReasonVoteCost, ReasonReward, ReasonWithdraw, ReasonReferralBonus:
+		return true
+	default:
+		return false
+	}
+}
+
+// Entry is a single immutable ledger line. Every posting writes two entries
+// sharing a PostingID: the user's line and its counter line on a system
+// account.
+type Entry struct {
+	ID             string    `json:"id"`
+	PostingID      string    `json:"-"`
+	UserID         string    `json:"-"`
+	Type           string    `json:"type"`
+	Amount         int64     `json:"amount"`
+	Currency       string    `json:"currency"`
+	Reason         string    `json:"reason"`
+	RefID          string    `json:"refId,omitempty"`
+	IdempotencyKey string    `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// signed returns the entry's effect on the balance.
+func (e Entry) signed() int64 {
+	if e.Type == TypeDebit {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// counter returns the balancing line of e on its system account.
+func (e Entry) counter() Entry {
+	counter := e
+	counter.ID = ""
+	counter.UserID = CounterAccount(e.Reason)
+	counter.Type = TypeCredit
+	if e.Type == TypeCredit {
+		counter.Type = TypeDebit
+	}
+	counter.IdempotencyKey = CounterKey(e.IdempotencyKey)
+	return counter
+}
+
+// CounterKey is the idempotency key of the counter line for a posting key.
+func CounterKey(key string) string {
+	return "contra:" + key
+}
+
+// sameAs reports whether e records the same posting as other, ignoring the
+// generated ID and timestamp.
+func (e Entry) sameAs(other Entry) bool {
+	return e.UserID == other.UserID && e.Type == other.Type && e.Amount == other.Amount &&
+		e.Reason == other.Reason && e.RefID == other.RefID
+}
+
+// Posting requests a credit or debit. IdempotencyKey is unique across the
+// ledger; reposting the same key returns the original entry.
+type Posting struct {
+	UserID         string
+	Type           string
+	Amount         int64
+	Reason         string
+	RefID          string
+	IdempotencyKey string
+}
+
+// PostResult is the ledger entry and the balance right after it.
+type PostResult struct {
+	Entry    Entry
+	Balance  int64
+	Replayed bool
+}
+
+// Wallet is the caller's balance and a page of ledger history, newest first.
+type Wallet struct {
+	Balance  int64   `json:"balance"`
+	Currency string  `json:"currency"`
+	History  []Entry `json:"history"`
+	Page     int     `json:"page"`
+	HasMore  bool    `json:"hasMore"`
+}
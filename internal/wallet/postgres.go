@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const entryColumns = `id, posting_id, user_id, type, amount_int, currency, reason, ref_id, idempotency_key, created_at`
+
+// PostgresRepository keeps the ledger in wallet_ledger and the balance
+// projection in wallet_accounts, including the system accounts.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Post locks the user's account row so concurrent debits are applied one at
+// a time against the latest balance.
+func (r *PostgresRepository) Post(ctx context.Context, entry Entry) (Entry, int64, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Entry{}, 0, false, fmt.Errorf("begin posting: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stored, balance, replayed, err := PostTx(ctx, tx, entry)
+	if err != nil || replayed {
+		return stored, balance, replayed, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Entry{}, 0, false, fmt.Errorf("commit posting: %w", err)
+	}
+	return stored, balance, false, nil
+}
+
+// PostTx applies entry and its counter line inside tx, leaving the commit to
+// the caller. Other repositories use it to write their own rows and the
+// ledger posting atomically.
+func PostTx(ctx context.Context, tx *sql.Tx, entry Entry) (Entry, int64, bool, error) {
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO wallet_accounts (user_id, balance_int, updated_at)
+VALUES ($1, 0, $2)
+ON CONFLICT (user_id) DO NOTHING`, entry.UserID, entry.CreatedAt); err != nil {
+		return Entry{}, 0, false, fmt.Errorf("ensure wallet account: %w", err)
+	}
+	var balance int64
+	if err := tx.QueryRowContext(ctx, `SELECT balance_int FROM wallet_accounts WHERE user_id = $1 FOR UPDATE`, entry.UserID).Scan(&balance); err != nil {
+		return Entry{}, 0, false, fmt.Errorf("lock wallet account: %w", err)
+	}
+
+	existing, err := scanEntry(tx.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM wallet_ledger WHERE idempotency_key = $1`, entry.IdempotencyKey))
+	switch {
+	case err == nil:
+		return existing, balance, true, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return Entry{}, 0, false, fmt.Errorf("select ledger entry by key: %w", err)
+	}
+
+	balance += entry.signed()
+	if balance < 0 {
+		return Entry{}, 0, false, ErrInsufficientFunds
+	}
+	entry.ID = uuid.NewString()
+	entry.PostingID = entry.ID
+	inserted, err := insertEntry(ctx, tx, entry)
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+	if !inserted {
+		// Another user's posting took the key between the lookup and the insert.
+		return Entry{}, 0, false, ErrIdempotencyConflict
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE wallet_accounts SET balance_int = $2, updated_at = $3 WHERE user_id = $1`, entry.UserID, balance, entry.CreatedAt); err != nil {
+		return Entry{}, 0, false, fmt.Errorf("update wallet balance: %w", err)
+	}
+
+	counter := entry.counter()
+	counter.ID = uuid.NewString()
+	if inserted, err := insertEntry(ctx, tx, counter); err != nil {
+		return Entry{}, 0, false, err
+	} else if !inserted {
+		return Entry{}, 0, false, ErrIdempotencyConflict
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO wallet_accounts (user_id, balance_int, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET balance_int = wallet_accounts.balance_int + EXCLUDED.balance_int, updated_at = EXCLUDED.updated_at`,
+		counter.UserID, counter.signed(), counter.CreatedAt); err != nil {
+		return Entry{}, 0, false, fmt.Errorf("update system account balance: %w", err)
+	}
+	return entry, balance, false, nil
+}
+
+func insertEntry(ctx context.Context, tx *sql.Tx, entry Entry) (bool, error) {
+	result, err := tx.ExecContext(ctx, `
+INSERT INTO wallet_ledger (`+entryColumns+`)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (idempotency_key) DO NOTHING`,
+		entry.ID, entry.PostingID, entry.UserID, entry.Type, entry.Amount, entry.Currency, entry.Reason, entry.RefID, entry.IdempotencyKey, entry.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("insert ledger entry: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, nil
+}
+
+func (r *PostgresRepository) GetByKey(ctx context.Context, idempotencyKey string) (Entry, error) {
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM wallet_ledger WHERE idempotency_key = $1`, idempotencyKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrEntryNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("select ledger entry by key: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *PostgresRepository) Balance(ctx context.Context, userID string) (int64, error) {
+	var balance int64
+	err := r.db.QueryRowContext(ctx, `SELECT balance_int FROM wallet_accounts WHERE user_id = $1`, userID).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("select wallet balance: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *PostgresRepository) History(ctx context.Context, userID string, limit, offset int) ([]Entry, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+entryColumns+` FROM wallet_ledger WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("select ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	items := []Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ledger entry: %w", err)
+		}
+		items = append(items, entry)
+	}
+	return items, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row rowScanner) (Entry, error) {
+	var entry Entry
+	err := row.Scan(&entry.ID, &entry.PostingID, &entry.UserID, &entry.Type, &entry.Amount, &entry.Currency, &entry.Reason, &entry.RefID, &entry.IdempotencyKey, &entry.CreatedAt)
+	return entry, err
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var entryRowColumns = []string{"id", "posting_id", "user_id", "type", "amount_int", "currency", "reason", "ref_id", "idempotency_key", "created_at"}
+
+func TestPostgresRepository_PostDebit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	entry := Entry{UserID: "u-1", Type: TypeDebit, Amount: 10, Currency: DefaultCurrency, Reason: ReasonVoteCost, RefID: "vote-1", IdempotencyKey: "vote:vote-1", CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_accounts (user_id, balance_int, updated_at) VALUES ($1, 0, $2) ON CONFLICT (user_id) DO NOTHING")).
+		WithArgs("u-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance_int FROM wallet_accounts WHERE user_id = $1 FOR UPDATE")).
+		WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_int"}).AddRow(int64(25)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, posting_id, user_id, type, amount_int, currency, reason, ref_id, idempotency_key, created_at FROM wallet_ledger WHERE idempotency_key = $1")).
+		WithArgs("vote:vote-1").
+		WillReturnRows(sqlmock.NewRows(entryRowColumns))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_ledger (id, posting_id, user_id, type, amount_int, currency, reason, ref_id, idempotency_key, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (idempotency_key) DO NOTHING")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "u-1", TypeDebit, int64(10), DefaultCurrency, ReasonVoteCost, "vote-1", "vote:vote-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE wallet_accounts SET balance_int = $2, updated_at = $3 WHERE user_id = $1")).
+		WithArgs("u-1", int64(15), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_ledger")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), AccountPot, TypeCredit, int64(10), DefaultCurrency, ReasonVoteCost, "vote-1", "contra:vote:vote-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_accounts (user_id, balance_int, updated_at) VALUES ($1, $2, $3) ON CONFLICT (user_id) DO UPDATE SET balance_int = wallet_accounts.balance_int + EXCLUDED.balance_int")).
+		WithArgs(AccountPot, int64(10), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	stored, balance, replayed, err := repo.Post(context.Background(), entry)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if stored.ID == "" || balance != 15 || replayed {
+		t.Fatalf("unexpected post result %+v balance=%d replayed=%v", stored, balance, replayed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_PostRejectsOverdraw(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	entry := Entry{UserID: "u-1", Type: TypeDebit, Amount: 30, Currency: DefaultCurrency, Reason: ReasonWithdraw, IdempotencyKey: "wd-1", CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_accounts")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance_int FROM wallet_accounts WHERE user_id = $1 FOR UPDATE")).
+		WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_int"}).AddRow(int64(25)))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM wallet_ledger WHERE idempotency_key = $1")).
+		WithArgs("wd-1").
+		WillReturnRows(sqlmock.NewRows(entryRowColumns))
+	mock.ExpectRollback()
+
+	if _, _, _, err := repo.Post(context.Background(), entry); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_GetByKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM wallet_ledger WHERE idempotency_key = $1")).
+		WithArgs("withdraw-hold:w-1").
+		WillReturnRows(sqlmock.NewRows(entryRowColumns).AddRow("wle-1", "wle-1", "u-1", TypeDebit, int64(30), DefaultCurrency, ReasonWithdraw, "w-1", "withdraw-hold:w-1", now))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM wallet_ledger WHERE idempotency_key = $1")).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(entryRowColumns))
+
+	entry, err := repo.GetByKey(context.Background(), "withdraw-hold:w-1")
+	if err != nil || entry.ID != "wle-1" || entry.Amount != 30 {
+		t.Fatalf("unexpected entry %+v (%v)", entry, err)
+	}
+	if _, err := repo.GetByKey(context.Background(), "missing"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("expected ErrEntryNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
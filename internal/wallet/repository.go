@@ -0,0 +1,18 @@
+package wallet
+
+import "context"
+
+// Repository stores ledger entries and the balance projection.
+type Repository interface {
+	// Post appends entry and moves the balance in one atomic step. It fails
+	// with ErrInsufficientFunds when a debit would make the balance negative.
+	// When entry.IdempotencyKey already exists it returns the stored entry,
+	// the current balance and replayed=true without changing anything.
+	Post(ctx context.Context, entry Entry) (stored Entry, balance int64, replayed bool, err error)
+	// GetByKey returns the entry posted with idempotencyKey or
+	// ErrEntryNotFound.
+	GetByKey(ctx context.Context, idempotencyKey string) (Entry, error)
+	Balance(ctx context.Context, userID string) (int64, error)
+	// History lists a user's entries newest first.
+	History(ctx context.Context, userID string, limit, offset int) ([]Entry, error)
+}
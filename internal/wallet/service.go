@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/realtime"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+type Service struct {
+	repo      Repository
+	publisher realtime.Publisher
+	logger    *zap.Logger
+	nowFn     func() time.Time
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo:   repo,
+		logger: zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithPublisher sends BALANCE_UPDATED to the user's channel after postings.
+func (s *Service) WithPublisher(publisher realtime.Publisher, logger *zap.Logger) {
+	s.publisher = publisher
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// Post validates and applies a posting. Reposting an idempotency key returns
+// the original entry; reusing it for a different posting fails with
+// ErrIdempotencyConflict.
+func (s *Service) Post(ctx context.Context, posting Posting) (PostResult, error) {
+	entry, err := s.Prepare(posting)
+	if err != nil {
+		return PostResult{}, err
+	}
+
+	stored, balance, replayed, err := s.repo.Post(ctx, entry)
+	if err != nil {
+		return PostResult{}, err
+	}
+	if replayed {
+		if !stored.sameAs(entry) {
+			return PostResult{}, ErrIdempotencyConflict
+		}
+		return PostResult{Entry: stored, Balance: balance, Replayed: true}, nil
+	}
+	s.PublishBalance(ctx, stored.UserID, balance)
+	return PostResult{Entry: stored, Balance: balance}, nil
+}
+
+// Prepare validates posting and returns the entry Post would write. Callers
+// that store their own rows in the same transaction as the posting apply it
+// with PostTx and then call PublishBalance.
+func (s *Service) Prepare(posting Posting) (Entry, error) {
+	entry := Entry{
+		UserID:         strings.TrimSpace(posting.UserID),
+		Type:           posting.Type,
+		Amount:         posting.Amount,
+		Currency:       DefaultCurrency,
+		Reason:         posting.Reason,
+		RefID:          strings.TrimSpace(posting.RefID),
+		IdempotencyKey: strings.TrimSpace(posting.IdempotencyKey),
+		CreatedAt:      s.nowFn(),
+	}
+	switch {
+	case entry.UserID == "":
+		return Entry{}, ErrUserIDRequired
+	case IsSystemAccount(entry.UserID):
+		return Entry{}, ErrReservedAccount
+	case entry.Type != TypeCredit && entry.Type != TypeDebit:
+		return Entry{}, ErrInvalidType
+	case entry.Amount <= 0:
+		return Entry{}, ErrInvalidAmount
+	case !IsSupportedReason(entry.Reason):
+		return Entry{}, ErrInvalidReason
+	case entry.IdempotencyKey == "":
+		return Entry{}, ErrIdempotencyKeyRequired
+	}
+	return entry, nil
+}
+
+// Credit adds amount to the user's balance.
+func (s *Service) Credit(ctx context.Context, userID string, amount int64, reason, refID, idempotencyKey string) (PostResult, error) {
+	return s.Post(ctx, Posting{UserID: userID, Type: TypeCredit, Amount: amount, Reason: reason, RefID: refID, IdempotencyKey: idempotencyKey})
+}
+
+// Debit takes amount from the user's balance, failing with
+// ErrInsufficientFunds rather than going negative.
+func (s *Service) Debit(ctx context.Context, userID string, amount int64, reason, refID, idempotencyKey string) (PostResult, error) {
+	return s.Post(ctx, Posting{UserID: userID, Type: TypeDebit, Amount: amount, Reason: reason, RefID: refID, IdempotencyKey: idempotencyKey})
+}
+
+// EntryByKey returns the entry posted with idempotencyKey or
+// ErrEntryNotFound.
+func (s *Service) EntryByKey(ctx context.Context, idempotencyKey string) (Entry, error) {
+	return s.repo.GetByKey(ctx, idempotencyKey)
+}
+
+// Balance returns the user's projected balance; unknown users have zero.
+func (s *Service) Balance(ctx context.Context, userID string) (int64, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return 0, ErrUserIDRequired
+	}
+	return s.repo.Balance(ctx, userID)
+}
+
+// Get returns the balance and one page of history. Pages start at 1; limit
+// defaults to 20 and is capped at 100.
+func (s *Service) Get(ctx context.Context, userID string, page, limit int) (Wallet, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return Wallet{}, ErrUserIDRequired
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	balance, err := s.repo.Balance(ctx, userID)
+	if err != nil {
+		return Wallet{}, err
+	}
+	history, err := s.repo.History(ctx, userID, limit+1, (page-1)*limit)
+	if err != nil {
+		return Wallet{}, err
+	}
+	hasMore := len(history) > limit
+	if hasMore {
+		history = history[:limit]
+	}
+	return Wallet{Balance: balance, Currency: DefaultCurrency, History: history, Page: page, HasMore: hasMore}, nil
+}
+
+// PublishBalance sends BALANCE_UPDATED for a posting applied outside Post.
+func (s *Service) PublishBalance(ctx context.Context, userID string, balance int64) {
+	if s.publisher == nil {
+		return
+	}
+	msg := realtime.Message{Type: realtime.TypeBalanceUpdated, Payload: map[string]int64{"balance": balance}}
+	if err := s.publisher.Publish(ctx, realtime.UserChannel(userID), msg); err != nil {
+		s.logger.Warn("failed to publish balance update", zap.String("user_id", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/funpot/funpot-go-core/internal/realtime"
+)
+
+func TestPostIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewInMemoryRepository())
+	publisher := realtime.NewInMemoryPublisher()
+	svc.WithPublisher(publisher, nil)
+
+	first, err := svc.Credit(ctx, "u-1", 100, ReasonStarsTopup, "pay-1", "topup:pay-1")
+	if err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	if first.Balance != 100 || first.Replayed {
+		t.Fatalf("unexpected result %+v", first)
+	}
+	again, err := svc.Credit(ctx, "u-1", 100, ReasonStarsTopup, "pay-1", "topup:pay-1")
+	if err != nil {
+		t.Fatalf("repeated Credit() error = %v", err)
+	}
+	if !again.Replayed || again.Entry.ID != first.Entry.ID || again.Balance != 100 {
+		t.Fatalf("expected replay of the first posting, got %+v", again)
+	}
+	if _, err := svc.Credit(ctx, "u-1", 50, ReasonStarsTopup, "pay-1", "topup:pay-1"); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Fatalf("expected ErrIdempotencyConflict, got %v", err)
+	}
+	if _, err := svc.Debit(ctx, "u-1", 101, ReasonVoteCost, "vote-1", "vote:vote-1"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if messages := publisher.Messages(); len(messages) != 1 || messages[0].Message.Type != realtime.TypeBalanceUpdated {
+		t.Fatalf("expected one balance update, got %+v", messages)
+	}
+}
+
+func TestPostingsBalanceAgainstSystemAccounts(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	svc := NewService(repo)
+
+	if _, err := svc.Credit(ctx, "u-1", 100, ReasonStarsTopup, "pay-1", "topup:pay-1"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	if _, err := svc.Debit(ctx, "u-1", 30, ReasonVoteCost, "vote-1", "vote:vote-1"); err != nil {
+		t.Fatalf("Debit() error = %v", err)
+	}
+	if _, err := svc.Credit(ctx, "u-2", 45, ReasonReward, "evt-1", "payout:evt-1:u-2"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	if _, err := svc.Credit(ctx, "u-2", 5, ReasonReferralBonus, "pay-1", "referral:pay-1"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+
+	want := map[string]int64{
+		"u-1":                   70,
+		"u-2":                   50,
+		AccountPaymentsClearing: -100,
+		AccountPot:              -15,
+		AccountReferral:         -5,
+	}
+	var sum int64
+	for account, expected := range want {
+		balance, err := repo.Balance(ctx, account)
+		if err != nil {
+			t.Fatalf("Balance(%s) error = %v", account, err)
+		}
+		if balance != expected {
+			t.Fatalf("Balance(%s) = %d, want %d", account, balance, expected)
+		}
+		sum += balance
+	}
+	if sum != 0 {
+		t.Fatalf("ledger does not balance: sum = %d", sum)
+	}
+
+	pot, err := repo.History(ctx, AccountPot, 10, 0)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(pot) != 2 || pot[1].Type != TypeCredit || pot[1].IdempotencyKey != CounterKey("vote:vote-1") || pot[1].PostingID == "" {
+		t.Fatalf("unexpected pot history %+v", pot)
+	}
+}
+
+func TestPostValidation(t *testing.T) {
+	svc := NewService(NewInMemoryRepository())
+	tests := []struct {
+		name    string
+		posting Posting
+		want    error
+	}{
+		{name: "user", posting: Posting{Type: TypeCredit, Amount: 1, Reason: ReasonReward, IdempotencyKey: "k"}, want: ErrUserIDRequired},
+		{name: "type", posting: Posting{UserID: "u-1", Type: "refund", Amount: 1, Reason: ReasonReward, IdempotencyKey: "k"}, want: ErrInvalidType},
+		{name: "amount", posting: Posting{UserID: "u-1", Type: TypeCredit, Reason: ReasonReward, IdempotencyKey: "k"}, want: ErrInvalidAmount},
+		{name: "reason", posting: Posting{UserID: "u-1", Type: TypeCredit, Amount: 1, Reason: "gift", IdempotencyKey: "k"}, want: ErrInvalidReason},
+		{name: "key", posting: Posting{UserID: "u-1", Type: TypeCredit, Amount: 1, Reason: ReasonReward}, want: ErrIdempotencyKeyRequired},
+		{name: "system", posting: Posting{UserID: AccountPot, Type: TypeCredit, Amount: 1, Reason: ReasonReward, IdempotencyKey: "k"}, want: ErrReservedAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Post(context.Background(), tt.posting); !errors.Is(err, tt.want) {
+				t.Fatalf("Post() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentDebitsNeverOverdraw(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewInMemoryRepository())
+	if _, err := svc.Credit(ctx, "u-1", 50, ReasonStarsTopup, "", "topup"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := svc.Debit(ctx, "u-1", 10, ReasonVoteCost, "", "vote:"+string(rune('a'+i))); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	balance, err := svc.Balance(ctx, "u-1")
+	if err != nil {
+		t.Fatalf("Balance() error = %v", err)
+	}
+	if accepted != 5 || balance != 0 {
+		t.Fatalf("expected 5 debits and zero balance, got %d debits and %d", accepted, balance)
+	}
+}
+
+func TestGetPaginatesHistory(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewInMemoryRepository())
+	for _, key := range []string{"k-1", "k-2", "k-3"} {
+		if _, err := svc.Credit(ctx, "u-1", 10, ReasonReward, "", key); err != nil {
+			t.Fatalf("Credit() error = %v", err)
+		}
+	}
+
+	first, err := svc.Get(ctx, "u-1", 1, 2)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first.Balance != 30 || len(first.History) != 2 || !first.HasMore || first.History[0].IdempotencyKey != "k-3" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	second, err := svc.Get(ctx, "u-1", 2, 2)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(second.History) != 1 || second.HasMore || second.History[0].IdempotencyKey != "k-1" {
+		t.Fatalf("unexpected second page %+v", second)
+	}
+}
@@ -0,0 +1,69 @@
+package withdrawals
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// InMemoryRepository keeps withdrawals in process memory; used in tests and
+// local runs without PostgreSQL.
+type InMemoryRepository struct {
+	mu    sync.RWMutex
+	items map[string]Withdrawal
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{items: make(map[string]Withdrawal)}
+}
+
+func (r *InMemoryRepository) Create(_ context.Context, withdrawal Withdrawal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[withdrawal.ID]; !ok {
+		r.items[withdrawal.ID] = withdrawal
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) Get(_ context.Context, id string) (Withdrawal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	withdrawal, ok := r.items[id]
+	if !ok {
+		return Withdrawal{}, ErrNotFound
+	}
+	return withdrawal, nil
+}
+
+func (r *InMemoryRepository) Update(_ context.Context, withdrawal Withdrawal, fromStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.items[withdrawal.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if current.Status != fromStatus {
+		return ErrInvalidTransition
+	}
+	r.items[withdrawal.ID] = withdrawal
+	return nil
+}
+
+func (r *InMemoryRepository) List(_ context.Context, status string) ([]Withdrawal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Withdrawal, 0)
+	for _, withdrawal := range r.items {
+		if status == "" || withdrawal.Status == status {
+			result = append(result, withdrawal)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
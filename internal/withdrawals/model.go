@@ -0,0 +1,88 @@
+package withdrawals
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserIDRequired         = errors.New("userId is required")
+	ErrInvalidAmount          = errors.New("amountINT must be positive")
+	ErrIdempotencyKeyRequired = errors.New("idempotency key is required")
+	ErrIdempotencyConflict    = errors.New("idempotency key was already used for a different amount")
+	ErrInsufficientFunds      = errors.New("insufficient balance")
+	ErrRateLimited            = errors.New("withdrawal rate limit exceeded")
+	ErrNotFound               = errors.New("withdrawal not found")
+	ErrInvalidTransition      = errors.New("withdrawal status transition is not allowed")
+	ErrReasonRequired         = errors.New("reason is required")
+)
+
+// Withdrawal statuses. A request starts pending; an admin approves or
+// rejects it and marks approved withdrawals paid once the payout was sent.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusPaid     = "paid"
+)
+
+// IsValidStatus reports whether status is a known withdrawal status.
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusApproved, StatusRejected, StatusPaid:
+		return true
+	default:
+		return false
+	}
+}
+
+// canTransition lists the allowed status changes; rejecting an approved
+// withdrawal covers payouts that could not be sent.
+func canTransition(from, to string) bool {
+	switch to {
+	case StatusApproved:
+		return from == StatusPending
+	case StatusRejected:
+		return from == StatusPending || from == StatusApproved
+	case StatusPaid:
+		return from == StatusApproved
+	default:
+		return false
+	}
+}
+
+// RateLimitError reports a request refused by the per-user limit.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Withdrawal is a request to pay out INT. The amount is held by a withdraw
+// debit when requested and released by a compensating credit on rejection.
+type Withdrawal struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"userId"`
+	AmountINT      int64     `json:"amountINT"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	ReviewedBy     string    `json:"reviewedBy,omitempty"`
+	HoldEntryID    string    `json:"holdEntryId,omitempty"`
+	ReleaseEntryID string    `json:"releaseEntryId,omitempty"`
+	IdempotencyKey string    `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Request asks to withdraw AmountINT for UserID.
+type Request struct {
+	UserID         string
+	AmountINT      int64
+	IdempotencyKey string
+}
@@ -0,0 +1,140 @@
+package withdrawals
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const withdrawalColumns = `id, user_id, amount_int, status, reason, reviewed_by, hold_entry_id, release_entry_id, idempotency_key, created_at, updated_at`
+
+// PostgresRepository persists withdrawals in PostgreSQL.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, withdrawal Withdrawal) error {
+	query := `INSERT INTO withdrawals (` + withdrawalColumns + `)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (id) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query,
+		withdrawal.ID,
+		withdrawal.UserID,
+		withdrawal.AmountINT,
+		withdrawal.Status,
+		withdrawal.Reason,
+		withdrawal.ReviewedBy,
+		withdrawal.HoldEntryID,
+		withdrawal.ReleaseEntryID,
+		withdrawal.IdempotencyKey,
+		withdrawal.CreatedAt,
+		withdrawal.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("insert withdrawal: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, id string) (Withdrawal, error) {
+	withdrawal, err := scanWithdrawal(r.db.QueryRowContext(ctx, `SELECT `+withdrawalColumns+` FROM withdrawals WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Withdrawal{}, ErrNotFound
+		}
+		return Withdrawal{}, fmt.Errorf("select withdrawal: %w", err)
+	}
+	return withdrawal, nil
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, withdrawal Withdrawal, fromStatus string) error {
+	const query = `
+		UPDATE withdrawals
+		SET status = $2,
+		    reason = $3,
+		    reviewed_by = $4,
+		    release_entry_id = $5,
+		    updated_at = $6
+		WHERE id = $1 AND status = $7
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		withdrawal.ID,
+		withdrawal.Status,
+		withdrawal.Reason,
+		withdrawal.ReviewedBy,
+		withdrawal.ReleaseEntryID,
+		withdrawal.UpdatedAt,
+		fromStatus,
+	)
+	if err != nil {
+		return fmt.Errorf("update withdrawal: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.Get(ctx, withdrawal.ID); err != nil {
+			return err
+		}
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+func (r *PostgresRepository) List(ctx context.Context, status string) ([]Withdrawal, error) {
+	query := `SELECT ` + withdrawalColumns + ` FROM withdrawals ORDER BY created_at, id`
+	args := []any{}
+	if status != "" {
+		query = `SELECT ` + withdrawalColumns + ` FROM withdrawals WHERE status = $1 ORDER BY created_at, id`
+		args = append(args, status)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select withdrawals: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Withdrawal, 0)
+	for rows.Next() {
+		withdrawal, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan withdrawal: %w", err)
+		}
+		result = append(result, withdrawal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate withdrawals: %w", err)
+	}
+	return result, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWithdrawal(row rowScanner) (Withdrawal, error) {
+	var withdrawal Withdrawal
+	if err := row.Scan(
+		&withdrawal.ID,
+		&withdrawal.UserID,
+		&withdrawal.AmountINT,
+		&withdrawal.Status,
+		&withdrawal.Reason,
+		&withdrawal.ReviewedBy,
+		&withdrawal.HoldEntryID,
+		&withdrawal.ReleaseEntryID,
+		&withdrawal.IdempotencyKey,
+		&withdrawal.CreatedAt,
+		&withdrawal.UpdatedAt,
+	); err != nil {
+		return Withdrawal{}, err
+	}
+	withdrawal.CreatedAt = withdrawal.CreatedAt.UTC()
+	withdrawal.UpdatedAt = withdrawal.UpdatedAt.UTC()
+	return withdrawal, nil
+}
@@ -0,0 +1,77 @@
+package withdrawals
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var withdrawalRowColumns = []string{"id", "user_id", "amount_int", "status", "reason", "reviewed_by", "hold_entry_id", "release_entry_id", "idempotency_key", "created_at", "updated_at"}
+
+func TestPostgresRepository_CreateAndGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	withdrawal := Withdrawal{ID: "w-1", UserID: "u-1", AmountINT: 40, Status: StatusPending, HoldEntryID: "wle_1", IdempotencyKey: "key-1", CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO withdrawals ("+withdrawalColumns+")")).
+		WithArgs("w-1", "u-1", int64(40), StatusPending, "", "", "wle_1", "", "key-1", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.Create(context.Background(), withdrawal); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + withdrawalColumns + " FROM withdrawals WHERE id = $1")).
+		WithArgs("w-1").
+		WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).AddRow("w-1", "u-1", int64(40), StatusPending, "", "", "wle_1", "", "key-1", now, now))
+	got, err := repo.Get(context.Background(), "w-1")
+	if err != nil || got.AmountINT != 40 || got.HoldEntryID != "wle_1" {
+		t.Fatalf("unexpected withdrawal %+v (%v)", got, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + withdrawalColumns + " FROM withdrawals WHERE id = $1")).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(withdrawalRowColumns))
+	if _, err := repo.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresRepository_UpdateConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewPostgresRepository(db)
+	now := time.Now().UTC()
+	withdrawal := Withdrawal{ID: "w-1", Status: StatusApproved, ReviewedBy: "admin-1", UpdatedAt: now}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE withdrawals")).
+		WithArgs("w-1", StatusApproved, "", "admin-1", "", now, StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + withdrawalColumns + " FROM withdrawals WHERE id = $1")).
+		WithArgs("w-1").
+		WillReturnRows(sqlmock.NewRows(withdrawalRowColumns).AddRow("w-1", "u-1", int64(40), StatusRejected, "no", "admin-2", "wle_1", "wle_2", "key-1", now, now))
+	if err := repo.Update(context.Background(), withdrawal, StatusPending); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
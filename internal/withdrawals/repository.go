@@ -0,0 +1,16 @@
+package withdrawals
+
+import "context"
+
+// Repository persists withdrawals.
+type Repository interface {
+	// Create stores a new withdrawal; creating an existing ID is a no-op.
+	Create(ctx context.Context, withdrawal Withdrawal) error
+	Get(ctx context.Context, id string) (Withdrawal, error)
+	// Update persists withdrawal if it is still in fromStatus and returns
+	// ErrInvalidTransition when another writer changed it first.
+	Update(ctx context.Context, withdrawal Withdrawal, fromStatus string) error
+	// List returns withdrawals in status, or all when status is empty,
+	// oldest first.
+	List(ctx context.Context, status string) ([]Withdrawal, error)
+}
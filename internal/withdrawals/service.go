@@ -0,0 +1,219 @@
+package withdrawals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+	"github.com/funpot/funpot-go-core/pkg/ratelimit"
+)
+
+// requestLimit caps how many withdrawals a user can request.
+var requestLimit = ratelimit.PerWindow(2, time.Hour)
+
+type Service struct {
+	repo    Repository
+	ledger  *wallet.Service
+	limiter ratelimit.Limiter
+	logger  *zap.Logger
+	nowFn   func() time.Time
+}
+
+func NewService(repo Repository, ledger *wallet.Service) *Service {
+	s := &Service{
+		repo:   repo,
+		ledger: ledger,
+		logger: zap.NewNop(),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+	s.limiter = ratelimit.NewInMemoryLimiter().WithClock(func() time.Time { return s.nowFn() })
+	return s
+}
+
+// WithRateLimiter shares request limits across nodes, e.g. through Redis.
+// Limiter errors are logged and let the request through.
+func (s *Service) WithRateLimiter(limiter ratelimit.Limiter, logger *zap.Logger) {
+	if limiter != nil {
+		s.limiter = limiter
+	}
+	if logger != nil {
+		s.logger = logger
+	}
+}
+
+// Request holds the amount and opens a pending withdrawal. The ID derives
+// from the user and idempotency key, so a retry whose hold was posted but
+// whose record was not picks up where it stopped.
+func (s *Service) Request(ctx context.Context, req Request) (Withdrawal, error) {
+	userID := strings.TrimSpace(req.UserID)
+	key := strings.TrimSpace(req.IdempotencyKey)
+	switch {
+	case userID == "":
+		return Withdrawal{}, ErrUserIDRequired
+	case req.AmountINT <= 0:
+		return Withdrawal{}, ErrInvalidAmount
+	case key == "":
+		return Withdrawal{}, ErrIdempotencyKeyRequired
+	}
+
+	id := uuid.NewSHA1(uuid.NameSpaceOID, []byte("withdrawal:"+userID+":"+key)).String()
+	existing, err := s.repo.Get(ctx, id)
+	switch {
+	case err == nil:
+		if existing.AmountINT != req.AmountINT {
+			return Withdrawal{}, ErrIdempotencyConflict
+		}
+		return existing, nil
+	case !errors.Is(err, ErrNotFound):
+		return Withdrawal{}, err
+	}
+
+	if err := s.allowRequest(ctx, userID, id); err != nil {
+		return Withdrawal{}, err
+	}
+
+	hold, err := s.ledger.Debit(ctx, userID, req.AmountINT, wallet.ReasonWithdraw, id, HoldKey(id))
+	switch {
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		return Withdrawal{}, ErrInsufficientFunds
+	case errors.Is(err, wallet.ErrIdempotencyConflict):
+		return Withdrawal{}, ErrIdempotencyConflict
+	case err != nil:
+		return Withdrawal{}, fmt.Errorf("hold withdrawal: %w", err)
+	}
+
+	now := s.nowFn()
+	withdrawal := Withdrawal{
+		ID:             id,
+		UserID:         userID,
+		AmountINT:      req.AmountINT,
+		Status:         StatusPending,
+		HoldEntryID:    hold.Entry.ID,
+		IdempotencyKey: key,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.Create(ctx, withdrawal); err != nil {
+		s.logger.Error("withdrawal hold posted without a record", zap.String("withdrawal_id", id), zap.String("user_id", userID), zap.Error(err))
+		return Withdrawal{}, err
+	}
+	return withdrawal, nil
+}
+
+// allowRequest takes a slot of the user's request limit unless the
+// withdrawal's hold was already posted by an earlier attempt whose record
+// was not stored; that retry is the same request.
+func (s *Service) allowRequest(ctx context.Context, userID, id string) error {
+	_, err := s.ledger.EntryByKey(ctx, HoldKey(id))
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, wallet.ErrEntryNotFound):
+		return fmt.Errorf("look up withdrawal hold: %w", err)
+	}
+	result, err := s.limiter.Allow(ctx, "withdrawals:"+userID, requestLimit)
+	if err != nil {
+		s.logger.Warn("withdrawal rate limiter unavailable", zap.String("user_id", userID), zap.Error(err))
+		return nil
+	}
+	if !result.Allowed {
+		return &RateLimitError{RetryAfter: result.RetryAfter}
+	}
+	return nil
+}
+
+// Get returns a withdrawal by ID.
+func (s *Service) Get(ctx context.Context, id string) (Withdrawal, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns withdrawals in status, or all of them when status is empty.
+func (s *Service) List(ctx context.Context, status string) ([]Withdrawal, error) {
+	return s.repo.List(ctx, status)
+}
+
+// Approve accepts a pending withdrawal for payout.
+func (s *Service) Approve(ctx context.Context, id, adminID string) (Withdrawal, error) {
+	return s.transition(ctx, id, adminID, StatusApproved, "")
+}
+
+// MarkPaid records that an approved withdrawal was paid out; the hold
+// becomes final.
+func (s *Service) MarkPaid(ctx context.Context, id, adminID string) (Withdrawal, error) {
+	return s.transition(ctx, id, adminID, StatusPaid, "")
+}
+
+// Reject declines a pending or approved withdrawal and releases its hold
+// with a compensating credit. Rejecting a rejected withdrawal retries a
+// release that failed earlier.
+func (s *Service) Reject(ctx context.Context, id, adminID, reason string) (Withdrawal, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return Withdrawal{}, ErrReasonRequired
+	}
+	withdrawal, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	if withdrawal.Status != StatusRejected {
+		// Flip the status before releasing so a concurrent MarkPaid cannot
+		// pay out funds that were handed back.
+		if withdrawal, err = s.transition(ctx, id, adminID, StatusRejected, reason); err != nil {
+			return Withdrawal{}, err
+		}
+	}
+	if withdrawal.ReleaseEntryID != "" {
+		return withdrawal, nil
+	}
+
+	release, err := s.ledger.Credit(ctx, withdrawal.UserID, withdrawal.AmountINT, wallet.ReasonWithdraw, withdrawal.ID, ReleaseKey(withdrawal.ID))
+	if err != nil {
+		return Withdrawal{}, fmt.Errorf("release withdrawal hold: %w", err)
+	}
+	withdrawal.ReleaseEntryID = release.Entry.ID
+	withdrawal.UpdatedAt = s.nowFn()
+	if err := s.repo.Update(ctx, withdrawal, StatusRejected); err != nil {
+		return Withdrawal{}, err
+	}
+	return withdrawal, nil
+}
+
+func (s *Service) transition(ctx context.Context, id, adminID, to, reason string) (Withdrawal, error) {
+	withdrawal, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	if !canTransition(withdrawal.Status, to) {
+		return Withdrawal{}, ErrInvalidTransition
+	}
+	from := withdrawal.Status
+	withdrawal.Status = to
+	withdrawal.ReviewedBy = adminID
+	if reason != "" {
+		withdrawal.Reason = reason
+	}
+	withdrawal.UpdatedAt = s.nowFn()
+	if err := s.repo.Update(ctx, withdrawal, from); err != nil {
+		return Withdrawal{}, err
+	}
+	return withdrawal, nil
+}
+
+// HoldKey is the ledger idempotency key of the debit holding a withdrawal.
+func HoldKey(id string) string {
+	return "withdraw-hold:" + id
+}
+
+// ReleaseKey is the ledger idempotency key of the credit releasing a
+// rejected withdrawal's hold.
+func ReleaseKey(id string) string {
+	return "withdraw-release:" + id
+}
@@ -0,0 +1,160 @@
+package withdrawals
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/funpot/funpot-go-core/internal/wallet"
+)
+
+func newFixture(t *testing.T, balance int64) (*Service, *wallet.Service) {
+	t.Helper()
+	ledger := wallet.NewService(wallet.NewInMemoryRepository())
+	if balance > 0 {
+		if _, err := ledger.Credit(context.Background(), "u-1", balance, wallet.ReasonStarsTopup, "pay-1", "topup:pay-1"); err != nil {
+			t.Fatalf("Credit() error = %v", err)
+		}
+	}
+	return NewService(NewInMemoryRepository(), ledger), ledger
+}
+
+func assertBalance(t *testing.T, ledger *wallet.Service, want int64) {
+	t.Helper()
+	if got, err := ledger.Balance(context.Background(), "u-1"); err != nil || got != want {
+		t.Fatalf("expected balance %d, got %d (%v)", want, got, err)
+	}
+}
+
+func TestRequestHoldsFunds(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger := newFixture(t, 100)
+
+	withdrawal, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 40, IdempotencyKey: "w-1"})
+	if err != nil || withdrawal.Status != StatusPending || withdrawal.HoldEntryID == "" {
+		t.Fatalf("unexpected withdrawal %+v (%v)", withdrawal, err)
+	}
+	assertBalance(t, ledger, 60)
+
+	again, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 40, IdempotencyKey: "w-1"})
+	if err != nil || again.ID != withdrawal.ID {
+		t.Fatalf("expected the same withdrawal, got %+v (%v)", again, err)
+	}
+	assertBalance(t, ledger, 60)
+
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 50, IdempotencyKey: "w-1"}); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Fatalf("expected ErrIdempotencyConflict, got %v", err)
+	}
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 80, IdempotencyKey: "w-2"}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 0, IdempotencyKey: "w-3"}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10}); !errors.Is(err, ErrIdempotencyKeyRequired) {
+		t.Fatalf("expected ErrIdempotencyKeyRequired, got %v", err)
+	}
+}
+
+func TestRequestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newFixture(t, 100)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+
+	for _, key := range []string{"w-1", "w-2"} {
+		if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: key}); err != nil {
+			t.Fatalf("%s: unexpected error %v", key, err)
+		}
+	}
+	// The cap holds for the whole hour rather than refilling gradually.
+	now = now.Add(45 * time.Minute)
+	_, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: "w-3"})
+	var limited *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limited) || limited.RetryAfter != 15*time.Minute {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: "w-1"}); err != nil {
+		t.Fatalf("expected a retry to bypass the limit, got %v", err)
+	}
+}
+
+type failOnceRepository struct {
+	Repository
+	failed bool
+}
+
+func (r *failOnceRepository) Create(ctx context.Context, withdrawal Withdrawal) error {
+	if !r.failed {
+		r.failed = true
+		return errors.New("database unavailable")
+	}
+	return r.Repository.Create(ctx, withdrawal)
+}
+
+func TestRequestRetryAfterFailedCreateKeepsRateLimitSlot(t *testing.T) {
+	ctx := context.Background()
+	ledger := wallet.NewService(wallet.NewInMemoryRepository())
+	if _, err := ledger.Credit(ctx, "u-1", 100, wallet.ReasonStarsTopup, "pay-1", "topup:pay-1"); err != nil {
+		t.Fatalf("Credit() error = %v", err)
+	}
+	svc := NewService(&failOnceRepository{Repository: NewInMemoryRepository()}, ledger)
+
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: "w-1"}); err == nil {
+		t.Fatal("expected the failed create to surface")
+	}
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: "w-1"}); err != nil {
+		t.Fatalf("expected the retry to store the withdrawal, got %v", err)
+	}
+	assertBalance(t, ledger, 90)
+	// The retry did not take a second slot, so another request still fits.
+	if _, err := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 10, IdempotencyKey: "w-2"}); err != nil {
+		t.Fatalf("expected a second request within the limit, got %v", err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc, ledger := newFixture(t, 100)
+	paid, _ := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 30, IdempotencyKey: "w-1"})
+	rejected, _ := svc.Request(ctx, Request{UserID: "u-1", AmountINT: 20, IdempotencyKey: "w-2"})
+	assertBalance(t, ledger, 50)
+
+	if _, err := svc.MarkPaid(ctx, paid.ID, "admin-1"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected pending withdrawals not to be payable, got %v", err)
+	}
+	if _, err := svc.Approve(ctx, paid.ID, "admin-1"); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	done, err := svc.MarkPaid(ctx, paid.ID, "admin-1")
+	if err != nil || done.Status != StatusPaid || done.ReviewedBy != "admin-1" {
+		t.Fatalf("unexpected paid withdrawal %+v (%v)", done, err)
+	}
+	if _, err := svc.Reject(ctx, paid.ID, "admin-1", "too late"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected paid withdrawals not to be rejectable, got %v", err)
+	}
+	assertBalance(t, ledger, 50)
+
+	if _, err := svc.Reject(ctx, rejected.ID, "admin-1", ""); !errors.Is(err, ErrReasonRequired) {
+		t.Fatalf("expected ErrReasonRequired, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		result, err := svc.Reject(ctx, rejected.ID, "admin-1", "wallet address invalid")
+		if err != nil || result.Status != StatusRejected || result.ReleaseEntryID == "" || result.Reason != "wallet address invalid" {
+			t.Fatalf("reject %d: unexpected withdrawal %+v (%v)", i+1, result, err)
+		}
+	}
+	assertBalance(t, ledger, 70)
+
+	pending, err := svc.List(ctx, StatusPending)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("expected no pending withdrawals, got %+v (%v)", pending, err)
+	}
+	if all, _ := svc.List(ctx, ""); len(all) != 2 {
+		t.Fatalf("expected two withdrawals, got %d", len(all))
+	}
+	if _, err := svc.Approve(ctx, "missing", "admin-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS events (
+    id TEXT PRIMARY KEY,
+    streamer_id TEXT NOT NULL,
+    game_id TEXT,
+    external_id TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL,
+    options_json JSONB NOT NULL,
+    state TEXT NOT NULL CHECK (state IN ('live', 'closed', 'cancelled')),
+    closes_at TIMESTAMPTZ,
+    cost_per_vote BIGINT NOT NULL DEFAULT 0 CHECK (cost_per_vote >= 0),
+    totals_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+    final_totals_json JSONB,
+    result_json JSONB,
+    cancel_reason TEXT NOT NULL DEFAULT '',
+    source_clip_id TEXT,
+    prompt_versions_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+    confidence NUMERIC(4, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_streamer_state ON events (streamer_id, state);
+CREATE INDEX IF NOT EXISTS idx_events_game_state ON events (game_id, state);
+CREATE INDEX IF NOT EXISTS idx_events_live_closes_at ON events (closes_at) WHERE state = 'live';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_streamer_external_id ON events (streamer_id, external_id) WHERE external_id <> '';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS votes (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id),
+    user_id TEXT NOT NULL,
+    option_id TEXT NOT NULL,
+    cost_int BIGINT NOT NULL DEFAULT 0 CHECK (cost_int >= 0),
+    idempotency_key TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_votes_event_id ON votes (event_id);
+CREATE INDEX IF NOT EXISTS idx_votes_idempotency_key ON votes (idempotency_key);
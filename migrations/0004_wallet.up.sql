@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS wallet_accounts (
+    user_id TEXT PRIMARY KEY,
+    balance_int BIGINT NOT NULL DEFAULT 0 CHECK (balance_int >= 0),
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS wallet_ledger (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount_int BIGINT NOT NULL CHECK (amount_int > 0),
+    currency TEXT NOT NULL DEFAULT 'INT',
+    reason TEXT NOT NULL CHECK (reason IN ('stars_topup', 'vote_cost', 'reward', 'withdraw', 'referral_bonus')),
+    ref_id TEXT NOT NULL DEFAULT '',
+    idempotency_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_ledger_user_created ON wallet_ledger (user_id, created_at);
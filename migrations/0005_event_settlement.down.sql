@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_unsettled;
+
+ALTER TABLE events DROP COLUMN IF EXISTS settled_at;
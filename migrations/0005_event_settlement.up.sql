@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_events_unsettled ON events (updated_at) WHERE state = 'closed' AND settled_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_unsettled;
+
+CREATE INDEX IF NOT EXISTS idx_events_unsettled ON events (updated_at) WHERE state IN ('closed', 'cancelled') AND settled_at IS NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    provider TEXT NOT NULL CHECK (provider = 'telegram_stars'),
+    invoice_id TEXT NOT NULL UNIQUE,
+    amount_int BIGINT NOT NULL CHECK (amount_int > 0),
+    stars BIGINT NOT NULL CHECK (stars > 0),
+    status TEXT NOT NULL CHECK (status IN ('pending', 'paid', 'failed')),
+    idempotency_key TEXT NOT NULL DEFAULT '',
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_user_created ON payments (user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_user_idempotency_key ON payments (user_id, idempotency_key) WHERE idempotency_key <> '';
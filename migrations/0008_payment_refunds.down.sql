@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payments_created;
+
+UPDATE payments SET status = 'failed' WHERE status = 'refunded';
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (status IN ('pending', 'paid', 'failed'));
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS withdrawals (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    amount_int BIGINT NOT NULL CHECK (amount_int > 0),
+    status TEXT NOT NULL CHECK (status IN ('pending', 'approved', 'rejected', 'paid')),
+    reason TEXT NOT NULL DEFAULT '',
+    reviewed_by TEXT NOT NULL DEFAULT '',
+    hold_entry_id TEXT NOT NULL,
+    release_entry_id TEXT NOT NULL DEFAULT '',
+    idempotency_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_withdrawals_status_created ON withdrawals (status, created_at);
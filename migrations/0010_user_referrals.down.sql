@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_inviter_immutable ON users;
+DROP FUNCTION IF EXISTS users_inviter_immutable();
+DROP INDEX IF EXISTS idx_users_inviter;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_no_self_referral;
+ALTER TABLE users DROP COLUMN IF EXISTS inviter_user_id;
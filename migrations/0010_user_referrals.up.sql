@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS inviter_user_id TEXT REFERENCES users (id);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_no_self_referral;
+ALTER TABLE users ADD CONSTRAINT users_no_self_referral CHECK (inviter_user_id IS NULL OR inviter_user_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_users_inviter ON users (inviter_user_id) WHERE inviter_user_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION users_inviter_immutable() RETURNS trigger AS $$
+BEGIN
+    IF OLD.inviter_user_id IS DISTINCT FROM NEW.inviter_user_id THEN
+        RAISE EXCEPTION 'users.inviter_user_id is immutable';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_inviter_immutable ON users;
+CREATE TRIGGER users_inviter_immutable
+    BEFORE UPDATE OF inviter_user_id ON users
+    FOR EACH ROW EXECUTE FUNCTION users_inviter_immutable();
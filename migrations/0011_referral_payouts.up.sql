@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS referral_payouts (
+    id TEXT PRIMARY KEY,
+    inviter_user_id TEXT NOT NULL REFERENCES users (id),
+    invitee_user_id TEXT NOT NULL REFERENCES users (id),
+    payment_id TEXT NOT NULL UNIQUE,
+    amount_int BIGINT NOT NULL CHECK (amount_int > 0),
+    ledger_entry_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_payouts_inviter_created ON referral_payouts (inviter_user_id, created_at);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS login_fingerprints;
+
+DROP INDEX IF EXISTS idx_referral_payouts_status_created;
+ALTER TABLE referral_payouts ALTER COLUMN ledger_entry_id DROP DEFAULT;
+ALTER TABLE referral_payouts
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS risk_signals,
+    DROP COLUMN IF EXISTS risk_score,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,25 @@
+ALTER TABLE referral_payouts
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'paid' CHECK (status IN ('pending', 'held', 'paid', 'rejected')),
+    ADD COLUMN IF NOT EXISTS risk_score INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS risk_signals JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS reviewed_by TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+UPDATE referral_payouts SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE referral_payouts ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE referral_payouts ALTER COLUMN ledger_entry_id SET DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_referral_payouts_status_created ON referral_payouts (status, created_at);
+
+CREATE TABLE IF NOT EXISTS login_fingerprints (
+    user_id TEXT NOT NULL REFERENCES users (id),
+    ip_hash TEXT NOT NULL,
+    device_hash TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, ip_hash, device_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_fingerprints_ip ON login_fingerprints (ip_hash) WHERE ip_hash <> '';
+CREATE INDEX IF NOT EXISTS idx_login_fingerprints_device ON login_fingerprints (device_hash) WHERE device_hash <> '';
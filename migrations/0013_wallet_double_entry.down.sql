@@ -0,0 +1,8 @@
+DELETE FROM wallet_ledger WHERE user_id LIKE 'system:%';
+DELETE FROM wallet_accounts WHERE user_id LIKE 'system:%';
+
+ALTER TABLE wallet_accounts DROP CONSTRAINT IF EXISTS wallet_accounts_balance_int_check;
+ALTER TABLE wallet_accounts ADD CONSTRAINT wallet_accounts_balance_int_check CHECK (balance_int >= 0);
+
+DROP INDEX IF EXISTS idx_wallet_ledger_posting;
+ALTER TABLE wallet_ledger DROP COLUMN IF EXISTS posting_id;
@@ -0,0 +1,36 @@
+ALTER TABLE wallet_ledger ADD COLUMN IF NOT EXISTS posting_id TEXT NOT NULL DEFAULT '';
+UPDATE wallet_ledger SET posting_id = id WHERE posting_id = '';
+ALTER TABLE wallet_ledger ALTER COLUMN posting_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_wallet_ledger_posting ON wallet_ledger (posting_id);
+
+-- System accounts carry the counter side of every posting and may go negative.
+ALTER TABLE wallet_accounts DROP CONSTRAINT IF EXISTS wallet_accounts_balance_int_check;
+ALTER TABLE wallet_accounts ADD CONSTRAINT wallet_accounts_balance_int_check CHECK (balance_int >= 0 OR user_id LIKE 'system:%');
+
+-- Backfill counter lines for postings written before double entry.
+INSERT INTO wallet_ledger (id, posting_id, user_id, type, amount_int, currency, reason, ref_id, idempotency_key, created_at)
+SELECT gen_random_uuid()::text,
+       l.id,
+       CASE l.reason
+           WHEN 'vote_cost' THEN 'system:pot'
+           WHEN 'reward' THEN 'system:pot'
+           WHEN 'stars_topup' THEN 'system:payments_clearing'
+           WHEN 'withdraw' THEN 'system:payments_clearing'
+           WHEN 'referral_bonus' THEN 'system:referral'
+           ELSE 'system:house'
+       END,
+       CASE l.type WHEN 'credit' THEN 'debit' ELSE 'credit' END,
+       l.amount_int, l.currency, l.reason, l.ref_id, 'contra:' || l.idempotency_key, l.created_at
+FROM wallet_ledger l
+WHERE l.user_id NOT LIKE 'system:%'
+ON CONFLICT (idempotency_key) DO NOTHING;
+
+INSERT INTO wallet_accounts (user_id, balance_int, updated_at)
+SELECT user_id,
+       SUM(CASE type WHEN 'credit' THEN amount_int ELSE -amount_int END),
+       NOW()
+FROM wallet_ledger
+WHERE user_id LIKE 'system:%'
+GROUP BY user_id
+ON CONFLICT (user_id) DO UPDATE SET balance_int = EXCLUDED.balance_int, updated_at = EXCLUDED.updated_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_settlement_flagged;
+ALTER TABLE events DROP COLUMN IF EXISTS settlement_error;
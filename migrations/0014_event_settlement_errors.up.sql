@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS settlement_error TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_events_settlement_flagged ON events (updated_at) WHERE settled_at IS NULL AND settlement_error <> '';
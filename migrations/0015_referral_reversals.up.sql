@@ -0,0 +1,2 @@
+ALTER TABLE referral_payouts DROP CONSTRAINT IF EXISTS referral_payouts_status_check;
+ALTER TABLE referral_payouts ADD CONSTRAINT referral_payouts_status_check CHECK (status IN ('pending', 'held', 'paid', 'rejected', 'reversed'));
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_payments_flagged ON payments (updated_at) WHERE payload ->> 'flag' <> '';
@@ -0,0 +1,2 @@
+ALTER TABLE events ALTER COLUMN confidence TYPE NUMERIC(4, 2);
+ALTER TABLE events DROP COLUMN IF EXISTS version;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_totals_stale;
+ALTER TABLE events DROP COLUMN IF EXISTS totals_stale;
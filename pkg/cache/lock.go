@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker provides best-effort distributed locks with SET NX PX. Unlock
+// only releases locks still owned by this locker instance.
+type RedisLocker struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	timeout   time.Duration
+	mu        sync.Mutex
+	tokens    map[string]string
+}
+
+func NewRedisLocker(client redis.UniversalClient, keyPrefix string) *RedisLocker {
+	if keyPrefix == "" {
+		keyPrefix = "funpot:lock"
+	}
+	return &RedisLocker{client: client, keyPrefix: keyPrefix, timeout: 2 * time.Second, tokens: make(map[string]string)}
+}
+
+func (l *RedisLocker) TryLock(key string, ttl time.Duration) bool {
+	token, err := lockToken()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	ok, err := l.client.SetNX(ctx, l.keyPrefix+":"+key, token, ttl).Result()
+	if err != nil || !ok {
+		return false
+	}
+	l.mu.Lock()
+	l.tokens[key] = token
+	l.mu.Unlock()
+	return true
+}
+
+func (l *RedisLocker) Unlock(key string) {
+	l.mu.Lock()
+	token, ok := l.tokens[key]
+	delete(l.tokens, key)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	_ = unlockScript.Run(ctx, l.client, []string{l.keyPrefix + ":" + key}, token).Err()
+}
+
+func lockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
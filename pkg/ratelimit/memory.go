@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// InMemoryLimiter keeps buckets in process memory; used in tests and
+// single-node setups without Redis.
+type InMemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	logs    map[string][]time.Time
+	nowFn   func() time.Time
+}
+
+func NewInMemoryLimiter() *InMemoryLimiter {
+	return &InMemoryLimiter{
+		buckets: make(map[string]bucket),
+		logs:    make(map[string][]time.Time),
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithClock overrides the time source used to refill buckets.
+func (l *InMemoryLimiter) WithClock(nowFn func() time.Time) *InMemoryLimiter {
+	if nowFn != nil {
+		l.nowFn = nowFn
+	}
+	return l
+}
+
+func (l *InMemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	if limit.disabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFn()
+	if limit.Sliding {
+		return l.allowSliding(key, limit, now), nil
+	}
+	capacity := float64(limit.Burst)
+	state, ok := l.buckets[key]
+	if !ok {
+		state = bucket{tokens: capacity, updated: now}
+	}
+	if elapsed := now.Sub(state.updated); elapsed > 0 {
+		state.tokens = math.Min(capacity, state.tokens+capacity*float64(elapsed)/float64(limit.Interval))
+		state.updated = now
+	}
+
+	result := Result{}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - state.tokens) * float64(limit.Interval) / capacity))
+	}
+	result.Remaining = int(state.tokens)
+	l.buckets[key] = state
+	return result, nil
+}
+
+func (l *InMemoryLimiter) allowSliding(key string, limit Limit, now time.Time) Result {
+	requests := l.logs[key]
+	for len(requests) > 0 && !now.Before(requests[0].Add(limit.Interval)) {
+		requests = requests[1:]
+	}
+	if len(requests) >= limit.Burst {
+		l.logs[key] = requests
+		return Result{RetryAfter: requests[0].Add(limit.Interval).Sub(now)}
+	}
+	requests = append(requests, now)
+	l.logs[key] = requests
+	return Result{Allowed: true, Remaining: limit.Burst - len(requests)}
+}
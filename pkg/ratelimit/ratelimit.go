@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens that refills Burst
+// tokens evenly over Interval. With Sliding set it instead keeps a log of
+// request times and allows at most Burst requests in any Interval. A zero
+// Burst disables limiting.
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+	Sliding  bool
+}
+
+// PerMinute allows n requests per rolling minute.
+func PerMinute(n int) Limit {
+	return Limit{Burst: n, Interval: time.Minute}
+}
+
+// PerWindow allows at most n requests in any window of interval, for caps
+// that must hold exactly rather than on average.
+func PerWindow(n int, interval time.Duration) Limit {
+	return Limit{Burst: n, Interval: interval, Sliding: true}
+}
+
+func (l Limit) disabled() bool {
+	return l.Burst <= 0 || l.Interval <= 0
+}
+
+// Result is the outcome of taking one token from a bucket.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter takes tokens from per-key buckets.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes one token atomically. Timestamps come
+// from the caller in milliseconds; a clock running behind the stored one
+// refills nothing rather than draining the bucket.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * capacity / interval)
+	ts = now
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * interval / capacity)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], interval)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// slidingLogScript keeps request times in a sorted set and records one more
+// only when fewer than the limit fall within the last interval. Members are
+// made unique by the log size, which only grows within one millisecond.
+var slidingLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - interval)
+local count = redis.call("ZCARD", KEYS[1])
+if count >= limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	return {0, 0, tonumber(oldest[2]) + interval - now}
+end
+redis.call("ZADD", KEYS[1], now, now .. ":" .. count)
+redis.call("PEXPIRE", KEYS[1], interval)
+return {1, limit - count - 1, 0}
+`)
+
+// RedisLimiter shares token buckets across nodes through Redis.
+type RedisLimiter struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	nowFn     func() time.Time
+}
+
+func NewRedisLimiter(client redis.UniversalClient, keyPrefix string) (*RedisLimiter, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = "funpot:ratelimit"
+	}
+	return &RedisLimiter{
+		client:    client,
+		keyPrefix: keyPrefix,
+		nowFn: func() time.Time {
+			return time.Now().UTC()
+		},
+	}, nil
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.disabled() {
+		return Result{Allowed: true}, nil
+	}
+	script, redisKey := tokenBucketScript, l.keyPrefix+":"+key
+	if limit.Sliding {
+		script, redisKey = slidingLogScript, l.keyPrefix+":log:"+key
+	}
+	values, err := script.Run(ctx, l.client, []string{redisKey},
+		limit.Burst, limit.Interval.Milliseconds(), l.nowFn().UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 3 {
+		return Result{}, errors.New("unexpected rate limit script reply")
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
@@ -10,6 +10,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric"
 	metricSdk "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
@@ -74,6 +75,12 @@ func (p *Provider) MetricsHandler() http.Handler {
 	return p.metricsHandler
 }
 
+// Meter returns a named meter for application metrics. It records nothing
+// while metrics are disabled.
+func (p *Provider) Meter(name string) metric.Meter {
+	return otel.Meter(name)
+}
+
 // Shutdown flushes exporters and releases telemetry resources.
 func (p *Provider) Shutdown(ctx context.Context) error {
 	if p == nil {